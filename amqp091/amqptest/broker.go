@@ -0,0 +1,420 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package amqptest
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+	"github.com/oarkflow/amqp/amqp091/internal/wire"
+)
+
+// message is a routed publishing as stored by the queues.
+type message struct {
+	exchange    string
+	routingKey  string
+	props       wire.Properties
+	body        []byte
+	redelivered bool
+	expires     time.Time // zero when not subject to a TTL
+}
+
+// binding links a source exchange to a destination queue or exchange.
+type binding struct {
+	destination string
+	toExchange  bool
+	key         string
+	args        amqp.Table
+}
+
+// exchange holds the routing rules.
+type exchange struct {
+	name       string
+	kind       string
+	durable    bool
+	autoDelete bool
+	internal   bool
+	args       amqp.Table
+	bindings   []binding
+}
+
+// consumer is a basic.consume registration over a queue.
+type consumer struct {
+	tag       string
+	channel   *serverChannel
+	noAck     bool
+	exclusive bool
+}
+
+// queue stores the messages till delivered and acknowledged.
+type queue struct {
+	name       string
+	durable    bool
+	autoDelete bool
+	args       amqp.Table
+	owner      *serverConn // exclusive queues
+	messages   []*message
+	consumers  []*consumer
+	unacked    int
+	next       int  // round-robin cursor over consumers
+	consumed   bool // had at least one consumer (auto-delete)
+}
+
+// predeclare creates the default and the standard "amq." exchanges.
+func (s *Server) predeclare() {
+	for name, kind := range map[string]string{
+		"":            amqp.ExchangeDirect,
+		"amq.direct":  amqp.ExchangeDirect,
+		"amq.fanout":  amqp.ExchangeFanout,
+		"amq.topic":   amqp.ExchangeTopic,
+		"amq.headers": amqp.ExchangeHeaders,
+		"amq.match":   amqp.ExchangeHeaders,
+	} {
+		s.exchanges[name] = &exchange{name: name, kind: kind, durable: true}
+	}
+}
+
+// validKind tests the supported exchange types.
+func validKind(kind string) bool {
+	switch kind {
+	case amqp.ExchangeDirect, amqp.ExchangeFanout, amqp.ExchangeTopic, amqp.ExchangeHeaders:
+		return true
+	}
+	return false
+}
+
+// sameArgs compares declaration arguments, treating nil and empty as equal.
+func sameArgs(a, b amqp.Table) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// route delivers the message to all matching queues (following exchange to
+// exchange bindings) and returns the number of queues reached.
+// Must be called with the server lock held.
+func (s *Server) route(exchangeName, key string, msg *message) int {
+	// default exchange binds implicitly every queue by its name
+	if exchangeName == "" {
+		q, ok := s.queues[key]
+		if !ok {
+			return 0
+		}
+		s.enqueue(q, msg)
+		return 1
+	}
+
+	queues := make(map[string]struct{})
+	s.collect(exchangeName, key, msg.props.Headers, queues, make(map[string]struct{}))
+
+	for name := range queues {
+		s.enqueue(s.queues[name], msg.copy())
+	}
+
+	return len(queues)
+}
+
+// collect walks the bindings of an exchange gathering the destination queues.
+func (s *Server) collect(exchangeName, key string, headers amqp.Table, queues, visited map[string]struct{}) {
+	if _, seen := visited[exchangeName]; seen {
+		return
+	}
+	visited[exchangeName] = struct{}{}
+
+	ex, ok := s.exchanges[exchangeName]
+	if !ok {
+		return
+	}
+
+	for _, b := range ex.bindings {
+		if !matches(ex.kind, b, key, headers) {
+			continue
+		}
+		if b.toExchange {
+			s.collect(b.destination, key, headers, queues, visited)
+		} else if _, ok := s.queues[b.destination]; ok {
+			queues[b.destination] = struct{}{}
+		}
+	}
+}
+
+// matches applies the exchange type specific routing rules.
+func matches(kind string, b binding, key string, headers amqp.Table) bool {
+	switch kind {
+	case amqp.ExchangeFanout:
+		return true
+	case amqp.ExchangeTopic:
+		return topicMatch(strings.Split(b.key, "."), strings.Split(key, "."))
+	case amqp.ExchangeHeaders:
+		return headersMatch(b.args, headers)
+	default:
+		return b.key == key
+	}
+}
+
+// topicMatch matches dot separated words where '*' stands for exactly
+// one word and '#' for zero or more words.
+func topicMatch(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if topicMatch(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && topicMatch(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && topicMatch(pattern[1:], words[1:])
+	}
+}
+
+// headersMatch implements the x-match (all|any) rule of headers exchanges.
+func headersMatch(args, headers amqp.Table) bool {
+	any := args["x-match"] == "any"
+	matched := 0
+	wanted := 0
+
+	for k, v := range args {
+		if strings.HasPrefix(k, "x-") {
+			continue
+		}
+		wanted++
+		if hv, ok := headers[k]; ok && (v == nil || reflect.DeepEqual(v, hv)) {
+			matched++
+			if any {
+				return true
+			}
+		}
+	}
+
+	return !any && matched == wanted
+}
+
+// copy duplicates the message, so that queues can independently flag redeliveries.
+func (m *message) copy() *message {
+	dup := *m
+	return &dup
+}
+
+// enqueue appends a message to the queue and attempts delivering.
+// Messages subject to a TTL (x-message-ttl queue argument or per message
+// expiration) are dead-lettered once expired while still waiting in the queue.
+func (s *Server) enqueue(q *queue, msg *message) {
+	if ttl, ok := messageTTL(q, msg); ok {
+		msg.expires = time.Now().Add(ttl)
+		time.AfterFunc(ttl, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.expire(q)
+		})
+	}
+
+	q.messages = append(q.messages, msg)
+	s.dispatch(q)
+}
+
+// messageTTL returns the shortest of the queue and the message time to live.
+func messageTTL(q *queue, msg *message) (time.Duration, bool) {
+	ttl, ok := time.Duration(0), false
+
+	var ms int64
+	switch v := q.args["x-message-ttl"].(type) {
+	case int16:
+		ms, ok = int64(v), true
+	case int32:
+		ms, ok = int64(v), true
+	case int64:
+		ms, ok = v, true
+	case int:
+		ms, ok = int64(v), true
+	}
+	if ok {
+		ttl = time.Duration(ms) * time.Millisecond
+	}
+
+	if exp, err := strconv.ParseInt(msg.props.Expiration, 10, 64); err == nil {
+		if d := time.Duration(exp) * time.Millisecond; !ok || d < ttl {
+			ttl, ok = d, true
+		}
+	}
+
+	return ttl, ok
+}
+
+// expire dead-letters the messages of the queue whose TTL elapsed.
+// Must be called with the server lock held.
+func (s *Server) expire(q *queue) {
+	if s.queues[q.name] != q {
+		return // deleted meanwhile
+	}
+
+	now := time.Now()
+	kept := q.messages[:0]
+	var expired []*message
+	for _, m := range q.messages {
+		if !m.expires.IsZero() && !m.expires.After(now) {
+			expired = append(expired, m)
+			continue
+		}
+		kept = append(kept, m)
+	}
+	q.messages = kept
+
+	for _, m := range expired {
+		m.expires = time.Time{}
+		s.deadLetter(q, m, "expired")
+	}
+}
+
+// requeue puts back messages at the head of the queue, flagging them redelivered.
+func (s *Server) requeue(q *queue, msgs []*message) {
+	for _, m := range msgs {
+		m.redelivered = true
+	}
+	q.messages = append(append([]*message{}, msgs...), q.messages...)
+	s.dispatch(q)
+}
+
+// deadLetter republishes a rejected message as instructed by the
+// x-dead-letter-exchange and x-dead-letter-routing-key queue arguments.
+func (s *Server) deadLetter(q *queue, msg *message, reason string) {
+	dlx, ok := q.args["x-dead-letter-exchange"].(string)
+	if !ok {
+		return
+	}
+	key := msg.routingKey
+	if dlk, ok := q.args["x-dead-letter-routing-key"].(string); ok {
+		key = dlk
+	}
+
+	dead := msg.copy()
+	dead.redelivered = false
+	dead.exchange = dlx
+	dead.routingKey = key
+	dead.expires = time.Time{}
+	dead.props.Expiration = "" // would expire again
+	headers := amqp.Table{}
+	for k, v := range msg.props.Headers {
+		headers[k] = v
+	}
+	headers["x-first-death-queue"] = q.name
+	headers["x-first-death-reason"] = reason
+	dead.props.Headers = headers
+
+	s.route(dlx, key, dead)
+}
+
+// dispatch pushes the ready messages to the consumers with spare capacity (round-robin).
+func (s *Server) dispatch(q *queue) {
+	for len(q.messages) != 0 && len(q.consumers) != 0 {
+		var target *consumer
+		for i := 0; i < len(q.consumers); i++ {
+			c := q.consumers[(q.next+i)%len(q.consumers)]
+			if c.channel.canDeliver() {
+				target = c
+				q.next = (q.next + i + 1) % len(q.consumers)
+				break
+			}
+		}
+		if target == nil {
+			return
+		}
+
+		msg := q.messages[0]
+		q.messages = q.messages[1:]
+		target.channel.deliver(target, q, msg)
+	}
+}
+
+// removeConsumer detaches a consumer, deleting auto-delete queues left without any.
+func (s *Server) removeConsumer(q *queue, tag string) {
+	for i, c := range q.consumers {
+		if c.tag == tag {
+			q.consumers = append(q.consumers[:i], q.consumers[i+1:]...)
+			break
+		}
+	}
+	if q.next >= len(q.consumers) {
+		q.next = 0
+	}
+
+	if q.autoDelete && q.consumed && len(q.consumers) == 0 {
+		s.deleteQueue(q)
+	}
+}
+
+// deleteQueue removes the queue along its bindings and notifies its consumers.
+func (s *Server) deleteQueue(q *queue) int {
+	delete(s.queues, q.name)
+
+	for _, ex := range s.exchanges {
+		s.unbind(ex, func(b binding) bool { return !b.toExchange && b.destination == q.name })
+	}
+	for _, c := range q.consumers {
+		c.channel.cancelled(c.tag)
+	}
+	q.consumers = nil
+
+	return len(q.messages)
+}
+
+// deleteExchange removes the exchange and all bindings pointing to it.
+func (s *Server) deleteExchange(ex *exchange) {
+	delete(s.exchanges, ex.name)
+
+	for _, other := range s.exchanges {
+		s.unbind(other, func(b binding) bool { return b.toExchange && b.destination == ex.name })
+	}
+}
+
+// unbind removes the bindings selected by the predicate, then deletes auto-delete
+// exchanges left without any.
+func (s *Server) unbind(ex *exchange, drop func(b binding) bool) bool {
+	removed := false
+	kept := ex.bindings[:0]
+
+	for _, b := range ex.bindings {
+		if drop(b) {
+			removed = true
+			continue
+		}
+		kept = append(kept, b)
+	}
+	ex.bindings = kept
+
+	if removed && ex.autoDelete && len(ex.bindings) == 0 {
+		s.deleteExchange(ex)
+	}
+
+	return removed
+}
+
+// bind adds (idempotently) a binding to the source exchange.
+func (ex *exchange) bind(b binding) {
+	for _, existing := range ex.bindings {
+		if existing.destination == b.destination && existing.toExchange == b.toExchange &&
+			existing.key == b.key && sameArgs(existing.args, b.args) {
+			return
+		}
+	}
+	ex.bindings = append(ex.bindings, b)
+}
+
+// sameBinding returns a predicate selecting exactly the described binding.
+func sameBinding(destination string, toExchange bool, key string, args amqp.Table) func(b binding) bool {
+	return func(b binding) bool {
+		return b.destination == destination && b.toExchange == toExchange &&
+			b.key == key && sameArgs(b.args, args)
+	}
+}
@@ -0,0 +1,765 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package amqptest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+	"github.com/oarkflow/amqp/amqp091/internal/wire"
+)
+
+// directReplyTo is the pseudo queue of the RabbitMQ direct reply-to feature.
+const directReplyTo = "amq.rabbitmq.reply-to"
+
+// delivery tracks a message handed to the client and not yet settled.
+type delivery struct {
+	tag   uint64
+	queue *queue
+	msg   *message
+}
+
+// serverChannel is the broker side of a client channel.
+// All its state is guarded by the server lock.
+type serverChannel struct {
+	id          uint16
+	conn        *serverConn
+	closing     bool // channel.close sent, awaiting close-ok
+	active      bool // client side flow control
+	confirm     bool // publisher confirms enabled
+	publishSeq  uint64
+	deliveryTag uint64
+	prefetch    int
+	unacked     []*delivery
+	consumers   map[string]*queue // consumer tag to consumed queue
+	lastQueue   string            // most recently declared queue, used for empty names
+	replyTag    string            // direct reply-to consumer tag
+	replyKey    string            // direct reply-to routing key of this channel
+
+	publishing *wire.BasicPublish // pending content assembly
+	props      wire.Properties
+	size       int
+	content    []byte
+}
+
+// newServerChannel opens a channel on the connection.
+func newServerChannel(conn *serverConn, id uint16) *serverChannel {
+	return &serverChannel{
+		id:        id,
+		conn:      conn,
+		active:    true,
+		consumers: make(map[string]*queue),
+	}
+}
+
+// fail closes the channel with a soft error.
+func (ch *serverChannel) fail(f *wire.MethodFrame, code uint16, format string, args ...interface{}) {
+	ch.closing = true
+	ch.release()
+	ch.conn.send(ch.id, &wire.ChannelClose{
+		ReplyCode: code,
+		ReplyText: fmt.Sprintf(format, args...),
+		ClassId:   f.ClassId,
+		MethodId:  f.MethodId,
+	})
+}
+
+// release cancels the consumers and requeues the unacknowledged messages.
+func (ch *serverChannel) release() {
+	srv := ch.conn.srv
+
+	ch.closing = true
+	for tag, q := range ch.consumers {
+		delete(ch.consumers, tag)
+		srv.removeConsumer(q, tag)
+	}
+	ch.requeue(ch.unacked)
+	ch.unacked = nil
+	ch.publishing = nil
+	ch.cancelReply()
+}
+
+// cancelReply removes the direct reply-to consumer, if any.
+func (ch *serverChannel) cancelReply() {
+	if ch.replyKey != "" {
+		delete(ch.conn.srv.replies, ch.replyKey)
+	}
+	ch.replyTag, ch.replyKey = "", ""
+}
+
+// requeue returns settled deliveries to their (still existing) queues, preserving order.
+func (ch *serverChannel) requeue(deliveries []*delivery) {
+	srv := ch.conn.srv
+	byQueue := make(map[*queue][]*message)
+	order := make([]*queue, 0)
+
+	for _, d := range deliveries {
+		d.queue.unacked--
+		if _, ok := byQueue[d.queue]; !ok {
+			order = append(order, d.queue)
+		}
+		byQueue[d.queue] = append(byQueue[d.queue], d.msg)
+	}
+
+	for _, q := range order {
+		if srv.queues[q.name] == q {
+			srv.requeue(q, byQueue[q])
+		}
+	}
+}
+
+// canDeliver tests if the channel accepts more consumer deliveries.
+func (ch *serverChannel) canDeliver() bool {
+	return !ch.closing && !ch.conn.closing && ch.active &&
+		(ch.prefetch == 0 || len(ch.unacked) < ch.prefetch)
+}
+
+// deliver pushes a message to a consumer of this channel.
+func (ch *serverChannel) deliver(c *consumer, q *queue, msg *message) {
+	ch.deliveryTag++
+	if !c.noAck {
+		ch.unacked = append(ch.unacked, &delivery{tag: ch.deliveryTag, queue: q, msg: msg})
+		q.unacked++
+	}
+
+	ch.conn.sendContent(ch.id, &wire.BasicDeliver{
+		ConsumerTag: c.tag,
+		DeliveryTag: ch.deliveryTag,
+		Redelivered: msg.redelivered,
+		Exchange:    msg.exchange,
+		RoutingKey:  msg.routingKey,
+	}, msg.props, msg.body)
+}
+
+// cancelled notifies the client that the broker has cancelled one of its consumers.
+func (ch *serverChannel) cancelled(tag string) {
+	if _, ok := ch.consumers[tag]; !ok {
+		return
+	}
+	delete(ch.consumers, tag)
+	ch.conn.send(ch.id, &wire.BasicCancel{ConsumerTag: tag, NoWait: true})
+}
+
+// redispatch offers the queues consumed on this channel any spare capacity.
+func (ch *serverChannel) redispatch() {
+	seen := make(map[*queue]struct{})
+	for _, q := range ch.consumers {
+		if _, ok := seen[q]; !ok {
+			seen[q] = struct{}{}
+			ch.conn.srv.dispatch(q)
+		}
+	}
+}
+
+// queueName resolves the empty queue name to the last declared one.
+func (ch *serverChannel) queueName(name string) string {
+	if name == "" {
+		return ch.lastQueue
+	}
+	return name
+}
+
+// lookupQueue finds a queue enforcing the exclusive ownership.
+func (ch *serverChannel) lookupQueue(f *wire.MethodFrame, name string) (*queue, bool) {
+	q, ok := ch.conn.srv.queues[name]
+	if !ok {
+		ch.fail(f, amqp.NotFound, "NOT_FOUND - no queue '%s' in vhost '/'", name)
+		return nil, false
+	}
+	if q.owner != nil && q.owner != ch.conn {
+		ch.fail(f, amqp.ResourceLocked, "RESOURCE_LOCKED - cannot obtain exclusive access to locked queue '%s' in vhost '/'", name)
+		return nil, false
+	}
+
+	return q, true
+}
+
+// lookupExchange finds an exchange.
+func (ch *serverChannel) lookupExchange(f *wire.MethodFrame, name string) (*exchange, bool) {
+	ex, ok := ch.conn.srv.exchanges[name]
+	if !ok {
+		ch.fail(f, amqp.NotFound, "NOT_FOUND - no exchange '%s' in vhost '/'", name)
+	}
+
+	return ex, ok
+}
+
+// handle serves a method addressed to this channel.
+func (ch *serverChannel) handle(f *wire.MethodFrame) {
+	switch f.Method.(type) {
+	case *wire.ChannelClose:
+		ch.release()
+		delete(ch.conn.channels, ch.id)
+		ch.conn.send(ch.id, &wire.ChannelCloseOk{})
+		return
+	case *wire.ChannelCloseOk:
+		delete(ch.conn.channels, ch.id)
+		return
+	}
+
+	if ch.closing {
+		// drop everything till the client acknowledges our channel.close
+		return
+	}
+	if ch.publishing != nil {
+		ch.conn.closeWithLocked(amqp.UnexpectedFrame, "UNEXPECTED_FRAME - expected content header", f.ClassId, f.MethodId)
+		return
+	}
+
+	switch m := f.Method.(type) {
+	case *wire.ChannelFlow:
+		ch.active = m.Active
+		ch.conn.send(ch.id, &wire.ChannelFlowOk{Active: m.Active})
+		if m.Active {
+			ch.redispatch()
+		}
+	case *wire.ChannelFlowOk:
+		// reply to a server initiated flow
+	case *wire.ExchangeDeclare:
+		ch.exchangeDeclare(f, m)
+	case *wire.ExchangeDelete:
+		ch.exchangeDelete(f, m)
+	case *wire.ExchangeBind:
+		ch.exchangeBind(f, m.Source, m.Destination, m.RoutingKey, m.Arguments, true, m.NoWait)
+	case *wire.ExchangeUnbind:
+		ch.exchangeBind(f, m.Source, m.Destination, m.RoutingKey, m.Arguments, false, m.NoWait)
+	case *wire.QueueDeclare:
+		ch.queueDeclare(f, m)
+	case *wire.QueueBind:
+		ch.queueBind(f, m.Queue, m.Exchange, m.RoutingKey, m.Arguments, true, m.NoWait)
+	case *wire.QueueUnbind:
+		ch.queueBind(f, m.Queue, m.Exchange, m.RoutingKey, m.Arguments, false, false)
+	case *wire.QueuePurge:
+		ch.queuePurge(f, m)
+	case *wire.QueueDelete:
+		ch.queueDelete(f, m)
+	case *wire.BasicQos:
+		ch.prefetch = int(m.PrefetchCount)
+		ch.conn.send(ch.id, &wire.BasicQosOk{})
+		ch.redispatch()
+	case *wire.BasicConsume:
+		ch.basicConsume(f, m)
+	case *wire.BasicCancel:
+		if m.ConsumerTag == ch.replyTag {
+			ch.cancelReply()
+		}
+		if q, ok := ch.consumers[m.ConsumerTag]; ok {
+			delete(ch.consumers, m.ConsumerTag)
+			ch.conn.srv.removeConsumer(q, m.ConsumerTag)
+		}
+		if !m.NoWait {
+			ch.conn.send(ch.id, &wire.BasicCancelOk{ConsumerTag: m.ConsumerTag})
+		}
+	case *wire.BasicPublish:
+		if m.Immediate {
+			ch.conn.closeWithLocked(amqp.NotImplemented, "NOT_IMPLEMENTED - immediate=true", f.ClassId, f.MethodId)
+			return
+		}
+		ch.publishing = m
+		ch.content = nil
+		ch.size = -1
+	case *wire.BasicGet:
+		ch.basicGet(f, m)
+	case *wire.BasicAck:
+		if settled, ok := ch.settle(f, m.DeliveryTag, m.Multiple); ok {
+			for _, d := range settled {
+				d.queue.unacked--
+			}
+			ch.redispatch()
+		}
+	case *wire.BasicNack:
+		ch.reject(f, m.DeliveryTag, m.Multiple, m.Requeue)
+	case *wire.BasicReject:
+		ch.reject(f, m.DeliveryTag, false, m.Requeue)
+	case *wire.BasicRecover:
+		ch.recoverAll()
+		ch.conn.send(ch.id, &wire.BasicRecoverOk{})
+	case *wire.BasicRecoverAsync:
+		ch.recoverAll()
+	case *wire.ConfirmSelect:
+		ch.confirm = true
+		if !m.Nowait {
+			ch.conn.send(ch.id, &wire.ConfirmSelectOk{})
+		}
+	case *wire.TxSelect, *wire.TxCommit, *wire.TxRollback:
+		ch.fail(f, amqp.NotImplemented, "NOT_IMPLEMENTED - transactions are not supported")
+	default:
+		ch.conn.closeWithLocked(amqp.CommandInvalid, "COMMAND_INVALID - unexpected method", f.ClassId, f.MethodId)
+	}
+}
+
+// exchangeDeclare creates or checks an exchange.
+func (ch *serverChannel) exchangeDeclare(f *wire.MethodFrame, m *wire.ExchangeDeclare) {
+	srv := ch.conn.srv
+	ex, exists := srv.exchanges[m.Exchange]
+
+	switch {
+	case m.Passive && !exists:
+		ch.fail(f, amqp.NotFound, "NOT_FOUND - no exchange '%s' in vhost '/'", m.Exchange)
+		return
+	case m.Passive:
+	case !validKind(m.Type):
+		ch.conn.closeWithLocked(amqp.CommandInvalid, fmt.Sprintf("COMMAND_INVALID - unknown exchange type '%s'", m.Type), f.ClassId, f.MethodId)
+		return
+	case exists:
+		if arg := ex.inequivalent(m); arg != "" {
+			ch.fail(f, amqp.PreconditionFailed, "PRECONDITION_FAILED - inequivalent arg '%s' for exchange '%s' in vhost '/'", arg, m.Exchange)
+			return
+		}
+	case m.Exchange == "" || strings.HasPrefix(m.Exchange, "amq."):
+		ch.fail(f, amqp.AccessRefused, "ACCESS_REFUSED - exchange name '%s' contains reserved prefix 'amq.*'", m.Exchange)
+		return
+	default:
+		srv.exchanges[m.Exchange] = &exchange{
+			name:       m.Exchange,
+			kind:       m.Type,
+			durable:    m.Durable,
+			autoDelete: m.AutoDelete,
+			internal:   m.Internal,
+			args:       m.Arguments,
+		}
+	}
+
+	if !m.NoWait {
+		ch.conn.send(ch.id, &wire.ExchangeDeclareOk{})
+	}
+}
+
+// inequivalent returns the first attribute differing from the existing exchange.
+func (ex *exchange) inequivalent(m *wire.ExchangeDeclare) string {
+	switch {
+	case ex.kind != m.Type:
+		return "type"
+	case ex.durable != m.Durable:
+		return "durable"
+	case ex.autoDelete != m.AutoDelete:
+		return "auto_delete"
+	case ex.internal != m.Internal:
+		return "internal"
+	case !sameArgs(ex.args, m.Arguments):
+		return "arguments"
+	}
+	return ""
+}
+
+// exchangeDelete removes an exchange.
+func (ch *serverChannel) exchangeDelete(f *wire.MethodFrame, m *wire.ExchangeDelete) {
+	srv := ch.conn.srv
+
+	if m.Exchange == "" || strings.HasPrefix(m.Exchange, "amq.") {
+		ch.fail(f, amqp.AccessRefused, "ACCESS_REFUSED - operation not permitted on exchange '%s'", m.Exchange)
+		return
+	}
+	if ex, ok := srv.exchanges[m.Exchange]; ok {
+		if m.IfUnused && len(ex.bindings) != 0 {
+			ch.fail(f, amqp.PreconditionFailed, "PRECONDITION_FAILED - exchange '%s' in vhost '/' in use", m.Exchange)
+			return
+		}
+		srv.deleteExchange(ex)
+	}
+
+	if !m.NoWait {
+		ch.conn.send(ch.id, &wire.ExchangeDeleteOk{})
+	}
+}
+
+// exchangeBind adds or removes an exchange to exchange binding.
+func (ch *serverChannel) exchangeBind(f *wire.MethodFrame, source, destination, key string, args amqp.Table, add, noWait bool) {
+	src, ok := ch.lookupExchange(f, source)
+	if !ok {
+		return
+	}
+	if _, ok := ch.lookupExchange(f, destination); !ok {
+		return
+	}
+
+	if add {
+		src.bind(binding{destination: destination, toExchange: true, key: key, args: args})
+		if !noWait {
+			ch.conn.send(ch.id, &wire.ExchangeBindOk{})
+		}
+		return
+	}
+
+	ch.conn.srv.unbind(src, sameBinding(destination, true, key, args))
+	if !noWait {
+		ch.conn.send(ch.id, &wire.ExchangeUnbindOk{})
+	}
+}
+
+// queueDeclare creates or checks a queue.
+func (ch *serverChannel) queueDeclare(f *wire.MethodFrame, m *wire.QueueDeclare) {
+	srv := ch.conn.srv
+	name := m.Queue
+
+	if name == "" && !m.Passive {
+		name = srv.nextName("amq.gen")
+	} else if name == "" {
+		name = ch.lastQueue
+	}
+
+	q, exists := srv.queues[name]
+
+	switch {
+	case exists && q.owner != nil && q.owner != ch.conn:
+		ch.fail(f, amqp.ResourceLocked, "RESOURCE_LOCKED - cannot obtain exclusive access to locked queue '%s' in vhost '/'", name)
+		return
+	case m.Passive && !exists:
+		ch.fail(f, amqp.NotFound, "NOT_FOUND - no queue '%s' in vhost '/'", name)
+		return
+	case m.Passive:
+	case exists:
+		if arg := q.inequivalent(m); arg != "" {
+			ch.fail(f, amqp.PreconditionFailed, "PRECONDITION_FAILED - inequivalent arg '%s' for queue '%s' in vhost '/'", arg, name)
+			return
+		}
+	case m.Queue != "" && strings.HasPrefix(m.Queue, "amq."):
+		ch.fail(f, amqp.AccessRefused, "ACCESS_REFUSED - queue name '%s' contains reserved prefix 'amq.*'", m.Queue)
+		return
+	default:
+		q = &queue{
+			name:       name,
+			durable:    m.Durable,
+			autoDelete: m.AutoDelete,
+			args:       m.Arguments,
+		}
+		if m.Exclusive {
+			q.owner = ch.conn
+		}
+		srv.queues[name] = q
+	}
+
+	ch.lastQueue = name
+	if !m.NoWait {
+		ch.conn.send(ch.id, &wire.QueueDeclareOk{
+			Queue:         name,
+			MessageCount:  uint32(len(q.messages)),
+			ConsumerCount: uint32(len(q.consumers)),
+		})
+	}
+}
+
+// inequivalent returns the first attribute differing from the existing queue.
+func (q *queue) inequivalent(m *wire.QueueDeclare) string {
+	switch {
+	case q.durable != m.Durable:
+		return "durable"
+	case q.autoDelete != m.AutoDelete:
+		return "auto_delete"
+	case (q.owner != nil) != m.Exclusive:
+		return "exclusive"
+	case !sameArgs(q.args, m.Arguments):
+		return "arguments"
+	}
+	return ""
+}
+
+// queueBind adds or removes an exchange to queue binding.
+func (ch *serverChannel) queueBind(f *wire.MethodFrame, name, source, key string, args amqp.Table, add, noWait bool) {
+	q, ok := ch.lookupQueue(f, ch.queueName(name))
+	if !ok {
+		return
+	}
+	ex, ok := ch.lookupExchange(f, source)
+	if !ok {
+		return
+	}
+	if ex.name == "" {
+		ch.fail(f, amqp.AccessRefused, "ACCESS_REFUSED - operation not permitted on the default exchange")
+		return
+	}
+
+	if add {
+		ex.bind(binding{destination: q.name, key: key, args: args})
+		if !noWait {
+			ch.conn.send(ch.id, &wire.QueueBindOk{})
+		}
+		return
+	}
+
+	ch.conn.srv.unbind(ex, sameBinding(q.name, false, key, args))
+	ch.conn.send(ch.id, &wire.QueueUnbindOk{})
+}
+
+// queuePurge drops the ready messages of a queue.
+func (ch *serverChannel) queuePurge(f *wire.MethodFrame, m *wire.QueuePurge) {
+	q, ok := ch.lookupQueue(f, ch.queueName(m.Queue))
+	if !ok {
+		return
+	}
+
+	count := len(q.messages)
+	q.messages = nil
+
+	if !m.NoWait {
+		ch.conn.send(ch.id, &wire.QueuePurgeOk{MessageCount: uint32(count)})
+	}
+}
+
+// queueDelete removes a queue.
+func (ch *serverChannel) queueDelete(f *wire.MethodFrame, m *wire.QueueDelete) {
+	srv := ch.conn.srv
+	count := 0
+
+	if _, exists := srv.queues[ch.queueName(m.Queue)]; exists {
+		q, ok := ch.lookupQueue(f, ch.queueName(m.Queue))
+		if !ok {
+			return
+		}
+		if m.IfUnused && len(q.consumers) != 0 {
+			ch.fail(f, amqp.PreconditionFailed, "PRECONDITION_FAILED - queue '%s' in vhost '/' in use", q.name)
+			return
+		}
+		if m.IfEmpty && len(q.messages) != 0 {
+			ch.fail(f, amqp.PreconditionFailed, "PRECONDITION_FAILED - queue '%s' in vhost '/' is not empty", q.name)
+			return
+		}
+		count = srv.deleteQueue(q)
+	}
+
+	if !m.NoWait {
+		ch.conn.send(ch.id, &wire.QueueDeleteOk{MessageCount: uint32(count)})
+	}
+}
+
+// basicConsume registers a consumer.
+func (ch *serverChannel) basicConsume(f *wire.MethodFrame, m *wire.BasicConsume) {
+	srv := ch.conn.srv
+
+	if m.Queue == directReplyTo {
+		ch.consumeReplies(f, m)
+		return
+	}
+
+	q, ok := ch.lookupQueue(f, ch.queueName(m.Queue))
+	if !ok {
+		return
+	}
+
+	tag := m.ConsumerTag
+	if tag == "" {
+		tag = srv.nextName("amq.ctag")
+	}
+	if _, dup := ch.consumers[tag]; dup {
+		ch.conn.closeWithLocked(amqp.NotAllowed, fmt.Sprintf("NOT_ALLOWED - attempt to reuse consumer tag '%s'", tag), f.ClassId, f.MethodId)
+		return
+	}
+	for _, c := range q.consumers {
+		if c.exclusive || m.Exclusive {
+			ch.fail(f, amqp.AccessRefused, "ACCESS_REFUSED - queue '%s' in vhost '/' in exclusive use", q.name)
+			return
+		}
+	}
+
+	ch.consumers[tag] = q
+	q.consumers = append(q.consumers, &consumer{tag: tag, channel: ch, noAck: m.NoAck, exclusive: m.Exclusive})
+	q.consumed = true
+
+	if !m.NoWait {
+		ch.conn.send(ch.id, &wire.BasicConsumeOk{ConsumerTag: tag})
+	}
+	srv.dispatch(q)
+}
+
+// consumeReplies registers the direct reply-to pseudo queue consumer.
+func (ch *serverChannel) consumeReplies(f *wire.MethodFrame, m *wire.BasicConsume) {
+	srv := ch.conn.srv
+
+	if !m.NoAck {
+		ch.fail(f, amqp.PreconditionFailed, "PRECONDITION_FAILED - reply consumer cannot acknowledge")
+		return
+	}
+	if ch.replyTag != "" {
+		ch.fail(f, amqp.PreconditionFailed, "PRECONDITION_FAILED - reply consumer already set")
+		return
+	}
+
+	tag := m.ConsumerTag
+	if tag == "" {
+		tag = srv.nextName("amq.ctag")
+	}
+	ch.replyTag = tag
+	ch.replyKey = directReplyTo + "." + srv.nextName("g")
+	srv.replies[ch.replyKey] = ch
+
+	if !m.NoWait {
+		ch.conn.send(ch.id, &wire.BasicConsumeOk{ConsumerTag: tag})
+	}
+}
+
+// basicGet synchronously fetches one message.
+func (ch *serverChannel) basicGet(f *wire.MethodFrame, m *wire.BasicGet) {
+	q, ok := ch.lookupQueue(f, ch.queueName(m.Queue))
+	if !ok {
+		return
+	}
+	if len(q.messages) == 0 {
+		ch.conn.send(ch.id, &wire.BasicGetEmpty{})
+		return
+	}
+
+	msg := q.messages[0]
+	q.messages = q.messages[1:]
+	ch.deliveryTag++
+	if !m.NoAck {
+		ch.unacked = append(ch.unacked, &delivery{tag: ch.deliveryTag, queue: q, msg: msg})
+		q.unacked++
+	}
+
+	ch.conn.sendContent(ch.id, &wire.BasicGetOk{
+		DeliveryTag:  ch.deliveryTag,
+		Redelivered:  msg.redelivered,
+		Exchange:     msg.exchange,
+		RoutingKey:   msg.routingKey,
+		MessageCount: uint32(len(q.messages)),
+	}, msg.props, msg.body)
+}
+
+// settle removes from the unacknowledged list the deliveries addressed by tag
+// (and all prior ones when multiple). Unknown tags close the channel.
+func (ch *serverChannel) settle(f *wire.MethodFrame, tag uint64, multiple bool) ([]*delivery, bool) {
+	var settled []*delivery
+	kept := make([]*delivery, 0, len(ch.unacked))
+
+	for _, d := range ch.unacked {
+		if d.tag == tag || (multiple && (tag == 0 || d.tag < tag)) {
+			settled = append(settled, d)
+		} else {
+			kept = append(kept, d)
+		}
+	}
+
+	if len(settled) == 0 || (!multiple && settled[0].tag != tag) ||
+		(multiple && tag != 0 && settled[len(settled)-1].tag != tag) {
+		ch.fail(f, amqp.PreconditionFailed, "PRECONDITION_FAILED - unknown delivery tag %d", tag)
+		return nil, false
+	}
+	ch.unacked = kept
+
+	return settled, true
+}
+
+// reject handles basic.nack and basic.reject, requeueing or dead lettering.
+func (ch *serverChannel) reject(f *wire.MethodFrame, tag uint64, multiple, requeue bool) {
+	settled, ok := ch.settle(f, tag, multiple)
+	if !ok {
+		return
+	}
+
+	if requeue {
+		ch.requeue(settled)
+	} else {
+		for _, d := range settled {
+			d.queue.unacked--
+			ch.conn.srv.deadLetter(d.queue, d.msg, "rejected")
+		}
+	}
+	ch.redispatch()
+}
+
+// recoverAll requeues all unacknowledged deliveries of the channel.
+func (ch *serverChannel) recoverAll() {
+	pending := ch.unacked
+	ch.unacked = nil
+	sort.Slice(pending, func(i, j int) bool { return pending[i].tag < pending[j].tag })
+	ch.requeue(pending)
+}
+
+// header receives the content header of a pending publish.
+func (ch *serverChannel) header(f *wire.HeaderFrame) {
+	if ch.closing {
+		return
+	}
+	if ch.publishing == nil || ch.size >= 0 {
+		ch.conn.closeWithLocked(amqp.UnexpectedFrame, "UNEXPECTED_FRAME - unexpected content header", 60, 0)
+		return
+	}
+
+	ch.props = f.Properties
+	ch.size = int(f.Size)
+	ch.content = make([]byte, 0, ch.size)
+
+	if ch.size == 0 {
+		ch.publish()
+	}
+}
+
+// body receives a content body chunk of a pending publish.
+func (ch *serverChannel) body(f *wire.BodyFrame) {
+	if ch.closing {
+		return
+	}
+	if ch.publishing == nil || ch.size < 0 {
+		ch.conn.closeWithLocked(amqp.UnexpectedFrame, "UNEXPECTED_FRAME - unexpected content body", 60, 0)
+		return
+	}
+
+	ch.content = append(ch.content, f.Body...)
+	if len(ch.content) >= ch.size {
+		ch.publish()
+	}
+}
+
+// publish routes a fully received publishing, then sends returns and confirms.
+func (ch *serverChannel) publish() {
+	srv := ch.conn.srv
+	m := ch.publishing
+	ch.publishing = nil
+	f := &wire.MethodFrame{ChannelId: ch.id, ClassId: 60, MethodId: 40, Method: m}
+
+	ex, ok := ch.lookupExchange(f, m.Exchange)
+	if !ok {
+		return
+	}
+	if ex.internal {
+		ch.fail(f, amqp.AccessRefused, "ACCESS_REFUSED - cannot publish to internal exchange '%s' in vhost '/'", m.Exchange)
+		return
+	}
+
+	if ch.props.ReplyTo == directReplyTo {
+		if ch.replyKey == "" {
+			ch.fail(f, amqp.PreconditionFailed, "PRECONDITION_FAILED - fast reply consumer does not exist")
+			return
+		}
+		ch.props.ReplyTo = ch.replyKey
+	}
+
+	msg := &message{exchange: m.Exchange, routingKey: m.RoutingKey, props: ch.props, body: ch.content}
+	routed := 0
+	if m.Exchange == "" && strings.HasPrefix(m.RoutingKey, directReplyTo+".") {
+		// direct reply-to: straight to the requester's channel, bypassing any queue
+		if target, ok := srv.replies[m.RoutingKey]; ok && !target.closing {
+			target.deliveryTag++
+			target.conn.sendContent(target.id, &wire.BasicDeliver{
+				ConsumerTag: target.replyTag,
+				DeliveryTag: target.deliveryTag,
+				Exchange:    msg.exchange,
+				RoutingKey:  msg.routingKey,
+			}, msg.props, msg.body)
+			routed = 1
+		}
+	} else {
+		routed = srv.route(m.Exchange, m.RoutingKey, msg)
+	}
+
+	if routed == 0 && m.Mandatory {
+		ch.conn.sendContent(ch.id, &wire.BasicReturn{
+			ReplyCode:  amqp.NoRoute,
+			ReplyText:  "NO_ROUTE",
+			Exchange:   m.Exchange,
+			RoutingKey: m.RoutingKey,
+		}, ch.props, ch.content)
+	}
+
+	if ch.confirm {
+		ch.publishSeq++
+		if srv.nackPublishes {
+			ch.conn.send(ch.id, &wire.BasicNack{DeliveryTag: ch.publishSeq})
+		} else {
+			ch.conn.send(ch.id, &wire.BasicAck{DeliveryTag: ch.publishSeq})
+		}
+	}
+}
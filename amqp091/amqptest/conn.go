@@ -0,0 +1,408 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package amqptest
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+	"github.com/oarkflow/amqp/amqp091/internal/wire"
+)
+
+const (
+	defaultFrameMax   = 131072
+	defaultChannelMax = 2047
+	closeTimeout      = time.Second
+)
+
+// serverConn is the broker side of one client connection.
+type serverConn struct {
+	srv      *Server
+	rwc      net.Conn
+	frameMax int
+	channels map[uint16]*serverChannel // guarded by srv.mu
+	closing  bool                      // connection.close sent, awaiting close-ok
+
+	wmu    sync.Mutex
+	wcond  *sync.Cond
+	out    []wire.Frame // outbound queue, decouples the writes from the broker lock
+	done   bool
+	closed chan struct{}
+}
+
+// newServerConn prepares the protocol handler of a client stream.
+func newServerConn(srv *Server, rwc net.Conn) *serverConn {
+	c := &serverConn{
+		srv:      srv,
+		rwc:      rwc,
+		frameMax: defaultFrameMax,
+		channels: make(map[uint16]*serverChannel),
+		closed:   make(chan struct{}),
+	}
+	c.wcond = sync.NewCond(&c.wmu)
+
+	return c
+}
+
+// run performs the handshake and then serves the frames till the stream ends.
+func (c *serverConn) run() {
+	go c.writer()
+	defer c.cleanup()
+
+	r := bufio.NewReader(c.rwc)
+	frames := wire.NewReader(r)
+
+	if err := wire.ReadProtocolHeader(r); err != nil {
+		// advertise what we speak then hang up
+		c.rwc.Write([]byte("AMQP\x00\x00\x09\x01"))
+		return
+	}
+	if !c.handshake(frames) {
+		return
+	}
+
+	for {
+		frame, err := frames.ReadFrame()
+		if err != nil {
+			return
+		}
+		if !c.demux(frame) {
+			return
+		}
+	}
+}
+
+// handshake performs the start/tune/open sequence.
+func (c *serverConn) handshake(frames *wire.Reader) bool {
+	c.send(0, &wire.ConnectionStart{
+		VersionMajor: 0,
+		VersionMinor: 9,
+		ServerProperties: amqp.Table{
+			"product": "amqptest",
+			"capabilities": amqp.Table{
+				"publisher_confirms":         true,
+				"exchange_exchange_bindings": true,
+				"basic.nack":                 true,
+				"consumer_cancel_notify":     true,
+				"connection.blocked":         true,
+				"per_consumer_qos":           true,
+			},
+		},
+		Mechanisms: "PLAIN AMQPLAIN",
+		Locales:    "en_US",
+	})
+	if _, ok := expect[*wire.ConnectionStartOk](frames); !ok {
+		return false
+	}
+
+	c.send(0, &wire.ConnectionTune{ChannelMax: defaultChannelMax, FrameMax: defaultFrameMax})
+	tune, ok := expect[*wire.ConnectionTuneOk](frames)
+	if !ok {
+		return false
+	}
+	if tune.FrameMax != 0 && int(tune.FrameMax) < c.frameMax {
+		c.frameMax = int(tune.FrameMax)
+	}
+	if tune.Heartbeat != 0 {
+		go c.heartbeater(time.Duration(tune.Heartbeat) * time.Second / 2)
+	}
+
+	if _, ok := expect[*wire.ConnectionOpen](frames); !ok {
+		return false
+	}
+	c.send(0, &wire.ConnectionOpenOk{})
+
+	c.srv.mu.Lock()
+	blocked := c.srv.blocked
+	c.srv.mu.Unlock()
+	if blocked != nil {
+		c.send(0, &wire.ConnectionBlocked{Reason: *blocked})
+	}
+
+	return true
+}
+
+// expect reads the next method frame, skipping heartbeats, and asserts its type.
+func expect[T wire.Message](frames *wire.Reader) (T, bool) {
+	var zero T
+
+	for {
+		frame, err := frames.ReadFrame()
+		if err != nil {
+			return zero, false
+		}
+		if _, ok := frame.(*wire.HeartbeatFrame); ok {
+			continue
+		}
+		if mf, ok := frame.(*wire.MethodFrame); ok {
+			m, ok := mf.Method.(T)
+			return m, ok
+		}
+		return zero, false
+	}
+}
+
+// heartbeater keeps the client read deadlines happy.
+func (c *serverConn) heartbeater(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+			c.enqueue(&wire.HeartbeatFrame{})
+		}
+	}
+}
+
+// demux routes a frame to the connection or the targeted channel.
+// Returns false when the connection must be terminated.
+func (c *serverConn) demux(frame wire.Frame) bool {
+	c.srv.mu.Lock()
+	defer c.srv.mu.Unlock()
+
+	switch f := frame.(type) {
+	case *wire.HeartbeatFrame:
+		return true
+	case *wire.MethodFrame:
+		if f.ChannelId == 0 {
+			return c.handle(f)
+		}
+		return c.handleChannel(f)
+	case *wire.HeaderFrame:
+		if ch, ok := c.channels[f.ChannelId]; ok && !c.closing {
+			ch.header(f)
+			return true
+		}
+	case *wire.BodyFrame:
+		if ch, ok := c.channels[f.ChannelId]; ok && !c.closing {
+			ch.body(f)
+			return true
+		}
+	}
+
+	if c.closing {
+		return true
+	}
+	c.closeWithLocked(amqp.UnexpectedFrame, "UNEXPECTED_FRAME - content for an unknown channel", 0, 0)
+	return true
+}
+
+// handle serves the connection class methods. Must be called with the server lock held.
+func (c *serverConn) handle(f *wire.MethodFrame) bool {
+	switch f.Method.(type) {
+	case *wire.ConnectionClose:
+		c.send(0, &wire.ConnectionCloseOk{})
+		c.flush()
+		return false
+	case *wire.ConnectionCloseOk:
+		return false
+	case *wire.ConnectionUpdateSecret:
+		c.send(0, &wire.ConnectionUpdateSecretOk{})
+	default:
+		if !c.closing {
+			c.closeWithLocked(amqp.CommandInvalid, "COMMAND_INVALID - unexpected method on channel 0", f.ClassId, f.MethodId)
+		}
+	}
+
+	return true
+}
+
+// handleChannel serves the methods of a channel. Must be called with the server lock held.
+func (c *serverConn) handleChannel(f *wire.MethodFrame) bool {
+	if c.closing {
+		// only the close-ok matters now
+		return true
+	}
+
+	ch, ok := c.channels[f.ChannelId]
+
+	if _, isOpen := f.Method.(*wire.ChannelOpen); isOpen {
+		if ok {
+			c.closeWithLocked(amqp.ChannelError, "CHANNEL_ERROR - second 'channel.open' seen", f.ClassId, f.MethodId)
+			return true
+		}
+		c.channels[f.ChannelId] = newServerChannel(c, f.ChannelId)
+		c.send(f.ChannelId, &wire.ChannelOpenOk{})
+		return true
+	}
+
+	if !ok {
+		if _, isCloseOk := f.Method.(*wire.ChannelCloseOk); !isCloseOk {
+			c.closeWithLocked(amqp.ChannelError, "CHANNEL_ERROR - expected 'channel.open'", f.ClassId, f.MethodId)
+		}
+		return true
+	}
+
+	ch.handle(f)
+	return true
+}
+
+// closeWith initiates a server side connection close.
+func (c *serverConn) closeWith(code uint16, reason string, classId, methodId uint16) {
+	c.srv.mu.Lock()
+	defer c.srv.mu.Unlock()
+
+	c.closeWithLocked(code, reason, classId, methodId)
+}
+
+// closeWithLocked sends connection.close and hangs up if the client does not answer in time.
+func (c *serverConn) closeWithLocked(code uint16, reason string, classId, methodId uint16) {
+	if c.closing {
+		return
+	}
+	c.closing = true
+
+	c.send(0, &wire.ConnectionClose{ReplyCode: code, ReplyText: reason, ClassId: classId, MethodId: methodId})
+
+	go func() {
+		select {
+		case <-c.closed:
+		case <-time.After(closeTimeout):
+			c.rwc.Close()
+		}
+	}()
+}
+
+// cleanup releases all resources held by the connection.
+func (c *serverConn) cleanup() {
+	c.srv.mu.Lock()
+	for _, ch := range c.channels {
+		ch.release()
+	}
+	c.channels = map[uint16]*serverChannel{}
+
+	for _, q := range c.srv.queues {
+		if q.owner == c {
+			c.srv.deleteQueue(q)
+		}
+	}
+	c.srv.mu.Unlock()
+
+	c.srv.forget(c)
+
+	c.wmu.Lock()
+	c.done = true
+	c.wcond.Broadcast()
+	c.wmu.Unlock()
+
+	select {
+	case <-c.closed:
+	case <-time.After(closeTimeout):
+	}
+	c.rwc.Close()
+	<-c.closed
+}
+
+// channelIds lists the open channels.
+func (c *serverConn) channelIds() []uint16 {
+	c.srv.mu.Lock()
+	defer c.srv.mu.Unlock()
+
+	ids := make([]uint16, 0, len(c.channels))
+	for id := range c.channels {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// send queues a method frame.
+func (c *serverConn) send(channel uint16, m wire.Message) {
+	c.enqueue(&wire.MethodFrame{ChannelId: channel, Method: m})
+}
+
+// sendContent queues atomically a content carrying method followed by its header and body frames.
+func (c *serverConn) sendContent(channel uint16, m wire.Message, props wire.Properties, body []byte) {
+	frames := []wire.Frame{
+		&wire.MethodFrame{ChannelId: channel, Method: m},
+		wire.ContentHeader(channel, len(body), props),
+	}
+
+	chunk := c.frameMax - 8 // frame header and end octet
+	for i := 0; i < len(body); i += chunk {
+		j := i + chunk
+		if j > len(body) {
+			j = len(body)
+		}
+		frames = append(frames, &wire.BodyFrame{ChannelId: channel, Body: body[i:j]})
+	}
+
+	c.enqueue(frames...)
+}
+
+// enqueue appends frames to the outbound queue.
+func (c *serverConn) enqueue(frames ...wire.Frame) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.done {
+		return
+	}
+	c.out = append(c.out, frames...)
+	c.wcond.Broadcast()
+}
+
+// flush waits for the outbound queue to drain (or the writer to stop).
+func (c *serverConn) flush() {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	for len(c.out) != 0 && !c.done {
+		c.wcond.Wait()
+	}
+}
+
+// writer drains the outbound queue onto the stream.
+func (c *serverConn) writer() {
+	defer close(c.closed)
+
+	buf := bufio.NewWriter(c.rwc)
+	w := wire.NewWriter(buf)
+
+	for {
+		c.wmu.Lock()
+		for len(c.out) == 0 && !c.done {
+			c.wcond.Wait()
+		}
+		if len(c.out) == 0 && c.done {
+			c.wmu.Unlock()
+			return
+		}
+		frames := c.out
+		c.out = nil
+		c.wmu.Unlock()
+
+		for _, f := range frames {
+			if err := w.WriteFrameNoFlush(f); err != nil {
+				c.abort()
+				return
+			}
+		}
+		if err := buf.Flush(); err != nil {
+			c.abort()
+			return
+		}
+
+		c.wmu.Lock()
+		c.wcond.Broadcast() // wakes up flush()
+		c.wmu.Unlock()
+	}
+}
+
+// abort stops the writer on stream errors, unblocking any flush.
+func (c *serverConn) abort() {
+	c.rwc.Close()
+
+	c.wmu.Lock()
+	c.done = true
+	c.out = nil
+	c.wcond.Broadcast()
+	c.wmu.Unlock()
+}
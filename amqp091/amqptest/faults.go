@@ -0,0 +1,387 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package amqptest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrDialRejected is returned by [FaultDialer.Dial] while rejecting dials.
+var ErrDialRejected = errors.New("amqptest: dial rejected by fault injection")
+
+// heartbeatFrame is the wire image of a connection level heartbeat.
+var heartbeatFrame = []byte{8, 0, 0, 0, 0, 0, 0, 206}
+
+// protocolHeaderSize is the length of the header opening a client stream, ahead of the frames.
+const protocolHeaderSize = 8
+
+// FaultDialer wraps a dial function (ex: [Server.Dial] or net.Dial) with faults
+// that can be switched on and off at runtime while connections are in use.
+// Pass its [FaultDialer.Dial] method as [amqp091.Config.Dial]:
+//
+//	faults := amqptest.NewFaultDialer(srv.Dial)
+//	conn := grabbit.NewConnection(srv.URL(), amqp.Config{Dial: faults.Dial})
+//	...
+//	faults.RejectDials(3) // next three reconnection attempts fail
+//	faults.Sever()        // cut all live streams now
+type FaultDialer struct {
+	dial func(network, addr string) (net.Conn, error)
+
+	mu             sync.Mutex
+	conns          map[*faultConn]struct{}
+	dials          int           // total dial attempts
+	reject         int           // remaining dials to refuse; negative means all
+	blackHole      bool          // writes are silently discarded
+	dropHeartbeats bool          // outgoing heartbeats are silently discarded
+	readDelay      time.Duration // latency added to every read
+	writeDelay     time.Duration // latency added to every write
+	frameBudget    int           // frames still written before severing; negative means no limit
+	severTimer     *time.Timer   // pending delayed sever
+}
+
+// NewFaultDialer creates a fault injecting dialer on top of dial.
+// When dial is nil then net.Dial is used.
+func NewFaultDialer(dial func(network, addr string) (net.Conn, error)) *FaultDialer {
+	if dial == nil {
+		dial = net.Dial
+	}
+
+	return &FaultDialer{
+		dial:        dial,
+		conns:       make(map[*faultConn]struct{}),
+		frameBudget: -1,
+	}
+}
+
+// Dial has the signature of [amqp091.Config.Dial]. It honours the
+// rejection policy then wraps the established stream.
+func (d *FaultDialer) Dial(network, addr string) (net.Conn, error) {
+	d.mu.Lock()
+	d.dials++
+	if d.reject != 0 {
+		if d.reject > 0 {
+			d.reject--
+		}
+		d.mu.Unlock()
+		return nil, &net.OpError{Op: "dial", Net: network, Err: ErrDialRejected}
+	}
+	d.mu.Unlock()
+
+	conn, err := d.dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	fc := &faultConn{Conn: conn, dialer: d, closed: make(chan struct{})}
+
+	d.mu.Lock()
+	d.conns[fc] = struct{}{}
+	d.mu.Unlock()
+
+	return fc, nil
+}
+
+// Dials returns the number of dial attempts seen so far, rejected ones included.
+func (d *FaultDialer) Dials() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.dials
+}
+
+// Active returns the number of live streams established by this dialer.
+func (d *FaultDialer) Active() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.conns)
+}
+
+// Sever closes abruptly all live streams and returns how many were cut.
+func (d *FaultDialer) Sever() int {
+	d.mu.Lock()
+	conns := make([]*faultConn, 0, len(d.conns))
+	for c := range d.conns {
+		conns = append(conns, c)
+	}
+	d.mu.Unlock()
+
+	for _, c := range conns {
+		c.Close()
+	}
+
+	return len(conns)
+}
+
+// SeverAfterFrames severs all streams once the client wrote n more frames over any
+// of them, the nth frame still being delivered: ex: a publishing cut between its
+// method and its content. A negative n switches it off.
+func (d *FaultDialer) SeverAfterFrames(n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.frameBudget = n
+}
+
+// SeverAfter schedules a [FaultDialer.Sever] once the delay elapsed, replacing the pending one.
+func (d *FaultDialer) SeverAfter(delay time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.severTimer != nil {
+		d.severTimer.Stop()
+	}
+	d.severTimer = time.AfterFunc(delay, func() { d.Sever() })
+}
+
+// RejectDials refuses the next n dial attempts. A negative n refuses
+// all attempts until RejectDials(0) is called.
+func (d *FaultDialer) RejectDials(n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.reject = n
+}
+
+// BlackHoleWrites makes writes on all streams (live and future) report
+// success without delivering anything, as if the peer vanished.
+func (d *FaultDialer) BlackHoleWrites(enabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.blackHole = enabled
+}
+
+// DropHeartbeats discards the heartbeat frames sent by the client,
+// so that the broker side eventually considers the peer dead.
+func (d *FaultDialer) DropHeartbeats(enabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.dropHeartbeats = enabled
+}
+
+// DelayReads adds latency to every read. A long delay stalls the reader
+// (still interruptible by closing the stream).
+func (d *FaultDialer) DelayReads(delay time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.readDelay = delay
+}
+
+// DelayWrites adds latency to every write.
+func (d *FaultDialer) DelayWrites(delay time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.writeDelay = delay
+}
+
+// Heal switches off all the faults; live streams carry on normally.
+func (d *FaultDialer) Heal() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.reject = 0
+	d.blackHole = false
+	d.dropHeartbeats = false
+	d.readDelay = 0
+	d.writeDelay = 0
+	d.frameBudget = -1
+	if d.severTimer != nil {
+		d.severTimer.Stop()
+		d.severTimer = nil
+	}
+}
+
+// faultConn is a stream subject to the faults of its dialer.
+type faultConn struct {
+	net.Conn
+	dialer *FaultDialer
+	once   sync.Once
+	closed chan struct{}
+
+	mu            sync.Mutex
+	readDeadline  time.Time
+	writeDeadline time.Time
+	frames        frameScanner // written frames, guarded by the dialer lock
+}
+
+// frameScanner follows the frame boundaries of a client stream, the protocol header first.
+type frameScanner struct {
+	header int    // protocol header bytes seen
+	head   []byte // partial frame header: type, channel and payload size
+	left   int    // payload and frame end bytes still expected
+}
+
+// scan consumes the written bytes. It returns the count of frames they complete and
+// the offset right after the limit-th of them, -1 when fewer complete or limit is negative.
+func (s *frameScanner) scan(b []byte, limit int) (frames, cut int) {
+	cut = -1
+	if limit == 0 {
+		cut = 0
+	}
+
+	for i := 0; i < len(b); {
+		switch {
+		case s.header < protocolHeaderSize:
+			n := min(protocolHeaderSize-s.header, len(b)-i)
+			s.header += n
+			i += n
+		case s.left > 0:
+			n := min(s.left, len(b)-i)
+			s.left -= n
+			i += n
+			if s.left == 0 {
+				if frames++; frames == limit {
+					cut = i
+				}
+			}
+		default:
+			n := min(7-len(s.head), len(b)-i)
+			s.head = append(s.head, b[i:i+n]...)
+			i += n
+			if len(s.head) == 7 {
+				s.left = int(binary.BigEndian.Uint32(s.head[3:])) + 1
+				s.head = s.head[:0]
+			}
+		}
+	}
+
+	return frames, cut
+}
+
+// sleep waits for the delay, the deadline or the stream closing, whichever comes first.
+func (c *faultConn) sleep(delay time.Duration, deadline time.Time) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	expired := false
+	if !deadline.IsZero() && time.Until(deadline) < delay {
+		delay = time.Until(deadline)
+		expired = true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		if expired {
+			return os.ErrDeadlineExceeded
+		}
+		return nil
+	case <-c.closed:
+		return net.ErrClosed
+	}
+}
+
+// SetDeadline records the deadlines so that the injected latency honours them.
+func (c *faultConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline, c.writeDeadline = t, t
+	c.mu.Unlock()
+
+	return c.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline records the deadline so that the injected latency honours it.
+func (c *faultConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+
+	return c.Conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline records the deadline so that the injected latency honours it.
+func (c *faultConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.writeDeadline = t
+	c.mu.Unlock()
+
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// Read applies the read latency before reading.
+func (c *faultConn) Read(b []byte) (int, error) {
+	c.dialer.mu.Lock()
+	delay := c.dialer.readDelay
+	c.dialer.mu.Unlock()
+
+	c.mu.Lock()
+	deadline := c.readDeadline
+	c.mu.Unlock()
+
+	if err := c.sleep(delay, deadline); err != nil {
+		return 0, err
+	}
+
+	return c.Conn.Read(b)
+}
+
+// Write applies the write latency, the discarding policies and the frame budget.
+func (c *faultConn) Write(b []byte) (int, error) {
+	d := c.dialer
+	d.mu.Lock()
+	delay := d.writeDelay
+	discard := d.blackHole || (d.dropHeartbeats && bytes.Equal(b, heartbeatFrame))
+	frames, cut := c.frames.scan(b, d.frameBudget)
+	if cut >= 0 {
+		d.frameBudget = -1
+	} else if d.frameBudget > 0 {
+		d.frameBudget -= frames
+	}
+	d.mu.Unlock()
+
+	c.mu.Lock()
+	deadline := c.writeDeadline
+	c.mu.Unlock()
+
+	if err := c.sleep(delay, deadline); err != nil {
+		return 0, err
+	}
+	if cut >= 0 {
+		n := 0
+		if !discard {
+			n, _ = c.Conn.Write(b[:cut])
+		}
+		d.Sever()
+		return n, net.ErrClosed
+	}
+	if discard {
+		select {
+		case <-c.closed:
+			return 0, net.ErrClosed
+		default:
+			return len(b), nil
+		}
+	}
+
+	return c.Conn.Write(b)
+}
+
+// Close closes the stream and stops tracking it.
+func (c *faultConn) Close() error {
+	var err error
+
+	c.once.Do(func() {
+		close(c.closed)
+		err = c.Conn.Close()
+
+		c.dialer.mu.Lock()
+		delete(c.dialer.conns, c)
+		c.dialer.mu.Unlock()
+	})
+
+	return err
+}
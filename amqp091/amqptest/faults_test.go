@@ -0,0 +1,109 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package amqptest_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+	"github.com/oarkflow/amqp/amqp091/amqptest"
+)
+
+// awaitClosed waits for the connection being closed.
+func awaitClosed(t *testing.T, closed <-chan *amqp.Error, timeout time.Duration) {
+	t.Helper()
+
+	select {
+	case <-closed:
+	case <-time.After(timeout):
+		t.Fatal("connection not closed")
+	}
+}
+
+func TestFaultDialer(t *testing.T) {
+	srv := newServer(t)
+	faults := amqptest.NewFaultDialer(srv.Dial)
+
+	conn, _ := dial(t, srv, faults.Dial)
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	if n := faults.Sever(); n != 1 {
+		t.Fatalf("%d streams severed", n)
+	}
+	awaitClosed(t, closed, 5*time.Second)
+
+	faults.RejectDials(1)
+	if _, err := amqp.DialConfig(srv.URL(), amqp.Config{Dial: faults.Dial}); !errors.Is(err, amqptest.ErrDialRejected) {
+		t.Fatalf("dial error %v", err)
+	}
+	dial(t, srv, faults.Dial)
+	if n := faults.Dials(); n != 3 {
+		t.Fatalf("%d dials", n)
+	}
+	if n := faults.Active(); n != 1 {
+		t.Fatalf("%d active streams", n)
+	}
+}
+
+func TestSeverAfterFrames(t *testing.T) {
+	srv := newServer(t)
+	faults := amqptest.NewFaultDialer(srv.Dial)
+
+	conn, ch := dial(t, srv, faults.Dial)
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	if _, err := ch.QueueDeclare("q", false, false, false, false, nil); err != nil {
+		t.Fatalf("declare: %v", err)
+	}
+
+	// method and header delivered, body cut
+	faults.SeverAfterFrames(2)
+	if err := ch.Publish("", "q", false, false, amqp.Publishing{Body: []byte("lost")}); err == nil {
+		t.Fatal("cut publishing succeeded")
+	}
+	awaitClosed(t, closed, 5*time.Second)
+	if info, _ := srv.Queue("q"); info.Ready != 0 {
+		t.Fatalf("partial publishing enqueued: %+v", info)
+	}
+
+	// the budget is spent: new streams carry on
+	_, ch = dial(t, srv, faults.Dial)
+	if err := ch.Publish("", "q", false, false, amqp.Publishing{Body: []byte("kept")}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for info, _ := srv.Queue("q"); info.Ready != 1; info, _ = srv.Queue("q") {
+		if time.Now().After(deadline) {
+			t.Fatalf("publishing not enqueued: %+v", info)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSeverAfter(t *testing.T) {
+	srv := newServer(t)
+	faults := amqptest.NewFaultDialer(srv.Dial)
+
+	conn, _ := dial(t, srv, faults.Dial)
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	start := time.Now()
+	faults.SeverAfter(50 * time.Millisecond)
+	if conn.IsClosed() {
+		t.Fatal("connection closed ahead of the delay")
+	}
+	awaitClosed(t, closed, 5*time.Second)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("severed after %v", elapsed)
+	}
+
+	// healing cancels the pending sever
+	conn, _ = dial(t, srv, faults.Dial)
+	faults.SeverAfter(50 * time.Millisecond)
+	faults.Heal()
+	time.Sleep(100 * time.Millisecond)
+	if conn.IsClosed() || faults.Active() != 1 {
+		t.Fatal("healed connection severed")
+	}
+}
@@ -0,0 +1,348 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package amqptest provides a minimal, in-memory AMQP 0-9-1 broker meant for
+hermetic tests of code built on top of amqp091 and grabbit.
+
+The broker speaks the real wire protocol (it shares the internal frame codec of amqp091)
+either over a loopback listener or over in-memory pipes, so clients need no
+special casing:
+
+	srv := amqptest.NewServer()
+	defer srv.Close()
+
+	conn, err := amqp.DialConfig(srv.URL(), amqp.Config{Dial: srv.Dial})
+
+Supported: exchange declare/delete/bind/unbind (direct, fanout, topic, headers),
+queue declare/bind/unbind/purge/delete (including server named, exclusive and
+auto-delete queues), basic qos/consume/cancel/publish/get/ack/nack/reject/recover,
+direct reply-to (amq.rabbitmq.reply-to), mandatory returns, dead lettering on
+rejection and on expiry (x-message-ttl, per message expiration), publisher
+confirms, connection.blocked and channel.flow.
+
+Not supported: transactions, per vhost isolation, authentication and message
+persistence across [Server.Close].
+
+Fault injection helpers ([Server.DropConnections], [Server.CloseConnections],
+[Server.Block], [Server.Flow], [Server.NackPublishes], [Server.DeleteQueue])
+allow driving the client recovery paths deterministically. Network level
+failures (streams severed at once, after a delay or after some frames,
+black-holed writes, latency, refused dials) are simulated by wrapping any
+dial function with a [FaultDialer].
+*/
+package amqptest
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+	"github.com/oarkflow/amqp/amqp091/internal/wire"
+)
+
+// Server is an in-process AMQP 0-9-1 broker. Create one with [NewServer].
+type Server struct {
+	Listener net.Listener // loopback listener, see [Server.URL]
+
+	mu            sync.Mutex
+	exchanges     map[string]*exchange
+	queues        map[string]*queue
+	conns         map[*serverConn]struct{}
+	replies       map[string]*serverChannel // direct reply-to routing keys
+	blocked       *string                   // reason while connections are blocked
+	nackPublishes bool                      // negative publisher confirms
+	counter       uint64                    // server generated names
+	closed        bool
+	wg            sync.WaitGroup
+}
+
+// NewServer starts and returns a new broker listening on a loopback
+// address. The caller should call Close when finished, to shut it down.
+// It panics when no local port can be allocated, similar to httptest.NewServer.
+func NewServer() *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("amqptest: failed to listen on a port: %v", err))
+	}
+
+	s := &Server{
+		Listener:  ln,
+		exchanges: make(map[string]*exchange),
+		queues:    make(map[string]*queue),
+		conns:     make(map[*serverConn]struct{}),
+		replies:   make(map[string]*serverChannel),
+	}
+	s.predeclare()
+
+	s.wg.Add(1)
+	go s.accept()
+
+	return s
+}
+
+// URL returns the amqp URI of the loopback listener, using the default guest credentials.
+func (s *Server) URL() string {
+	return fmt.Sprintf("amqp://guest:guest@%s/", s.Listener.Addr().String())
+}
+
+// Dial has the signature of [amqp.Config.Dial] and connects the client
+// over an in-memory pipe instead of the loopback listener. The address is ignored.
+func (s *Server) Dial(network, addr string) (net.Conn, error) {
+	client, server := net.Pipe()
+
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+
+	if closed {
+		client.Close()
+		server.Close()
+		return nil, &net.OpError{Op: "dial", Net: network, Err: net.ErrClosed}
+	}
+
+	s.serve(server)
+
+	return client, nil
+}
+
+// Close shuts down the listener and drops every client connection.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	err := s.Listener.Close()
+	s.DropConnections()
+	s.wg.Wait()
+
+	return err
+}
+
+// accept serves the incoming loopback connections till the listener is closed.
+func (s *Server) accept() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.Listener.Accept()
+		if err != nil {
+			return
+		}
+		s.serve(conn)
+	}
+}
+
+// serve registers and runs the protocol handler for a new client stream.
+func (s *Server) serve(rwc net.Conn) {
+	c := newServerConn(s, rwc)
+
+	s.mu.Lock()
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		c.run()
+	}()
+}
+
+// forget removes a finished connection from the tracked ones.
+func (s *Server) forget(c *serverConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conns, c)
+}
+
+// connections returns a snapshot of the currently tracked connections.
+func (s *Server) connections() []*serverConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conns := make([]*serverConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+
+	return conns
+}
+
+// Connections returns the number of open client connections.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.conns)
+}
+
+// DropConnections severs abruptly (no connection.close) all client connections,
+// simulating a network failure or a broker crash.
+func (s *Server) DropConnections() {
+	for _, c := range s.connections() {
+		c.rwc.Close()
+	}
+}
+
+// CloseConnections gracefully closes all client connections
+// with the given reply code and text (ex: [amqp.ConnectionForced]).
+func (s *Server) CloseConnections(code uint16, reason string) {
+	for _, c := range s.connections() {
+		c.closeWith(code, reason, 0, 0)
+	}
+}
+
+// Block sends connection.blocked with the given reason to all connections;
+// new connections are blocked as well until [Server.Unblock] is called.
+func (s *Server) Block(reason string) {
+	s.mu.Lock()
+	s.blocked = &reason
+	s.mu.Unlock()
+
+	for _, c := range s.connections() {
+		c.send(0, &wire.ConnectionBlocked{Reason: reason})
+	}
+}
+
+// Unblock sends connection.unblocked to all connections.
+func (s *Server) Unblock() {
+	s.mu.Lock()
+	s.blocked = nil
+	s.mu.Unlock()
+
+	for _, c := range s.connections() {
+		c.send(0, &wire.ConnectionUnblocked{})
+	}
+}
+
+// Flow sends a server initiated channel.flow to every open channel.
+func (s *Server) Flow(active bool) {
+	for _, c := range s.connections() {
+		for _, id := range c.channelIds() {
+			c.send(id, &wire.ChannelFlow{Active: active})
+		}
+	}
+}
+
+// NackPublishes makes the publisher confirms negative (basic.nack) while enabled.
+func (s *Server) NackPublishes(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nackPublishes = enabled
+}
+
+// QueueInfo describes the state of a queue as seen by the broker.
+type QueueInfo struct {
+	Name       string
+	Durable    bool
+	AutoDelete bool
+	Exclusive  bool
+	Args       amqp.Table
+	Ready      int // messages waiting for delivery
+	Unacked    int // messages delivered but not yet acknowledged
+	Consumers  int
+}
+
+// Queue returns the state of the named queue.
+func (s *Server) Queue(name string) (QueueInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[name]
+	if !ok {
+		return QueueInfo{}, false
+	}
+
+	return QueueInfo{
+		Name:       q.name,
+		Durable:    q.durable,
+		AutoDelete: q.autoDelete,
+		Exclusive:  q.owner != nil,
+		Args:       q.args,
+		Ready:      len(q.messages),
+		Unacked:    q.unacked,
+		Consumers:  len(q.consumers),
+	}, true
+}
+
+// Queues lists the names of all declared queues.
+func (s *Server) Queues() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.queues))
+	for name := range s.queues {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// HasExchange reports if the named exchange is declared.
+func (s *Server) HasExchange(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.exchanges[name]
+	return ok
+}
+
+// DeleteQueue removes a queue behind the clients' back; its consumers
+// receive a basic.cancel (consumer cancel notification).
+func (s *Server) DeleteQueue(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[name]
+	if ok {
+		s.deleteQueue(q)
+	}
+
+	return ok
+}
+
+// Publish injects a message as if published by a client, returning
+// the number of queues it was routed to.
+func (s *Server) Publish(exchange, key string, msg amqp.Publishing) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.route(exchange, key, &message{
+		exchange:   exchange,
+		routingKey: key,
+		props:      propertiesFrom(msg),
+		body:       msg.Body,
+	})
+}
+
+// propertiesFrom converts the application side publishing into wire properties.
+func propertiesFrom(msg amqp.Publishing) wire.Properties {
+	return wire.Properties{
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		Headers:         msg.Headers,
+		DeliveryMode:    msg.DeliveryMode,
+		Priority:        msg.Priority,
+		CorrelationId:   msg.CorrelationId,
+		ReplyTo:         msg.ReplyTo,
+		Expiration:      msg.Expiration,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		UserId:          msg.UserId,
+		AppId:           msg.AppId,
+	}
+}
+
+// nextName generates a unique server side name with the given prefix.
+// Must be called with the server lock held.
+func (s *Server) nextName(prefix string) string {
+	s.counter++
+	return fmt.Sprintf("%s-%d", prefix, s.counter)
+}
@@ -0,0 +1,252 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package amqptest_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+	"github.com/oarkflow/amqp/amqp091/amqptest"
+)
+
+// dial opens a client connection and channel to the server, through the dial function when set.
+func dial(t *testing.T, srv *amqptest.Server, dialer func(network, addr string) (net.Conn, error)) (*amqp.Connection, *amqp.Channel) {
+	t.Helper()
+
+	if dialer == nil {
+		dialer = srv.Dial
+	}
+	conn, err := amqp.DialConfig(srv.URL(), amqp.Config{Dial: dialer})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ch, err := conn.Channel()
+	if err != nil {
+		t.Fatalf("channel: %v", err)
+	}
+
+	return conn, ch
+}
+
+// newServer starts a server closed with the test.
+func newServer(t *testing.T) *amqptest.Server {
+	t.Helper()
+
+	srv := amqptest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	return srv
+}
+
+// get fetches a message of the queue, failing the test when empty.
+func get(t *testing.T, ch *amqp.Channel, queue string) amqp.Delivery {
+	t.Helper()
+
+	msg, ok, err := ch.Get(queue, true)
+	if err != nil || !ok {
+		t.Fatalf("get %s: %v, %v", queue, ok, err)
+	}
+	return msg
+}
+
+func TestRouting(t *testing.T) {
+	srv := newServer(t)
+	_, ch := dial(t, srv, nil)
+
+	for _, kind := range []string{amqp.ExchangeDirect, amqp.ExchangeFanout, amqp.ExchangeTopic, amqp.ExchangeHeaders} {
+		if err := ch.ExchangeDeclare("x."+kind, kind, false, true, false, false, nil); err != nil {
+			t.Fatalf("declare %s: %v", kind, err)
+		}
+	}
+	if _, err := ch.QueueDeclare("q", false, false, false, false, nil); err != nil {
+		t.Fatalf("declare queue: %v", err)
+	}
+	binds := []struct {
+		exchange, key string
+		args          amqp.Table
+	}{
+		{"x.direct", "orders", nil},
+		{"x.fanout", "ignored", nil},
+		{"x.topic", "orders.*.eu", nil},
+		{"x.headers", "", amqp.Table{"x-match": "all", "region": "eu"}},
+	}
+	for _, b := range binds {
+		if err := ch.QueueBind("q", b.key, b.exchange, false, b.args); err != nil {
+			t.Fatalf("bind %s: %v", b.exchange, err)
+		}
+	}
+
+	cases := []struct {
+		exchange, key string
+		headers       amqp.Table
+		routed        int
+	}{
+		{"x.direct", "orders", nil, 1},
+		{"x.direct", "other", nil, 0},
+		{"x.fanout", "", nil, 1},
+		{"x.topic", "orders.new.eu", nil, 1},
+		{"x.topic", "orders.new.us", nil, 0},
+		{"x.headers", "", amqp.Table{"region": "eu"}, 1},
+		{"x.headers", "", amqp.Table{"region": "us"}, 0},
+		{"", "q", nil, 1},
+	}
+	for _, c := range cases {
+		if n := srv.Publish(c.exchange, c.key, amqp.Publishing{Headers: c.headers}); n != c.routed {
+			t.Errorf("%s %q routed to %d queues", c.exchange, c.key, n)
+		}
+	}
+	if info, _ := srv.Queue("q"); info.Ready != 5 {
+		t.Fatalf("queue %+v", info)
+	}
+}
+
+func TestConfirmsAndReturns(t *testing.T) {
+	srv := newServer(t)
+	_, ch := dial(t, srv, nil)
+
+	if err := ch.Confirm(false); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	returns := ch.NotifyReturn(make(chan amqp.Return, 1))
+	if _, err := ch.QueueDeclare("q", false, false, false, false, nil); err != nil {
+		t.Fatalf("declare: %v", err)
+	}
+
+	confirmed := func(key string, mandatory bool) bool {
+		t.Helper()
+		deferred, err := ch.PublishWithDeferredConfirm("", key, mandatory, false, amqp.Publishing{})
+		if err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+		select {
+		case <-deferred.Done():
+			return deferred.Acked()
+		case <-time.After(5 * time.Second):
+			t.Fatal("not confirmed")
+		}
+		return false
+	}
+
+	if !confirmed("q", true) {
+		t.Fatal("routed message nacked")
+	}
+	if !confirmed("missing", true) {
+		t.Fatal("unroutable message nacked")
+	}
+	select {
+	case ret := <-returns:
+		if ret.ReplyCode != amqp.NoRoute || ret.RoutingKey != "missing" {
+			t.Fatalf("return %+v", ret)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("unroutable message not returned")
+	}
+
+	srv.NackPublishes(true)
+	if confirmed("q", false) {
+		t.Fatal("message acked while nacking")
+	}
+}
+
+func TestDeadLettering(t *testing.T) {
+	srv := newServer(t)
+	_, ch := dial(t, srv, nil)
+
+	if _, err := ch.QueueDeclare("dead", false, false, false, false, nil); err != nil {
+		t.Fatalf("declare: %v", err)
+	}
+	if _, err := ch.QueueDeclare("work", false, false, false, false, amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": "dead",
+	}); err != nil {
+		t.Fatalf("declare: %v", err)
+	}
+
+	srv.Publish("", "work", amqp.Publishing{Body: []byte("rejected")})
+	msg, ok, err := ch.Get("work", false)
+	if err != nil || !ok {
+		t.Fatalf("get: %v, %v", ok, err)
+	}
+	if err := msg.Reject(false); err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	if msg := get(t, ch, "dead"); string(msg.Body) != "rejected" || msg.Headers["x-first-death-reason"] != "rejected" {
+		t.Fatalf("dead letter %+v", msg)
+	}
+
+	srv.Publish("", "work", amqp.Publishing{Body: []byte("expired"), Expiration: "10"})
+	time.Sleep(50 * time.Millisecond)
+	if msg := get(t, ch, "dead"); string(msg.Body) != "expired" || msg.Headers["x-first-death-reason"] != "expired" {
+		t.Fatalf("dead letter %+v", msg)
+	}
+	if info, _ := srv.Queue("work"); info.Ready+info.Unacked != 0 {
+		t.Fatalf("queue %+v", info)
+	}
+}
+
+func TestTemporaryQueues(t *testing.T) {
+	srv := newServer(t)
+	conn, ch := dial(t, srv, nil)
+
+	exclusive, err := ch.QueueDeclare("", false, false, true, false, nil)
+	if err != nil {
+		t.Fatalf("declare: %v", err)
+	}
+	autoDelete, err := ch.QueueDeclare("", false, true, false, false, nil)
+	if err != nil {
+		t.Fatalf("declare: %v", err)
+	}
+	if exclusive.Name == autoDelete.Name {
+		t.Fatalf("server-named queues share %s", exclusive.Name)
+	}
+
+	// exclusive to the declaring connection
+	_, other := dial(t, srv, nil)
+	var amqpErr *amqp.Error
+	if _, err := other.QueueDeclarePassive(exclusive.Name, false, false, true, false, nil); !errors.As(err, &amqpErr) || amqpErr.Code != amqp.ResourceLocked {
+		t.Fatalf("exclusive queue error %v", err)
+	}
+
+	// deleted along the last consumer
+	if _, err := ch.Consume(autoDelete.Name, "tag", false, false, false, false, nil); err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if err := ch.Cancel("tag", false); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if _, found := srv.Queue(autoDelete.Name); found {
+		t.Fatal("auto-delete queue kept")
+	}
+
+	// deleted along the connection
+	conn.Close()
+	if _, found := srv.Queue(exclusive.Name); found {
+		t.Fatal("exclusive queue kept")
+	}
+}
+
+func TestBlockedConnection(t *testing.T) {
+	srv := newServer(t)
+	conn, _ := dial(t, srv, nil)
+	blockings := conn.NotifyBlocked(make(chan amqp.Blocking, 2))
+
+	srv.Block("low on memory")
+	srv.Unblock()
+
+	for _, active := range []bool{true, false} {
+		select {
+		case blocking := <-blockings:
+			if blocking.Active != active {
+				t.Fatalf("blocking %+v", blocking)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("blocking not notified")
+		}
+	}
+}
@@ -8,6 +8,8 @@ package amqp091
 import (
 	"bytes"
 	"fmt"
+
+	"github.com/oarkflow/amqp/amqp091/internal/wire"
 )
 
 // Authentication interface provides a means for different SASL authentication
@@ -48,7 +50,7 @@ func (auth *AMQPlainAuth) Mechanism() string {
 func (auth *AMQPlainAuth) Response() string {
 	var buf bytes.Buffer
 	table := Table{"LOGIN": auth.Username, "PASSWORD": auth.Password}
-	if err := writeTable(&buf, table); err != nil {
+	if err := wire.WriteTable(&buf, table); err != nil {
 		return ""
 	}
 	return buf.String()[4:]
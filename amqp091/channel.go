@@ -8,6 +8,7 @@ package amqp091
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"reflect"
 	"sync"
 	"sync/atomic"
@@ -179,7 +180,7 @@ func (ch *Channel) call(req message, res ...message) error {
 		return err
 	}
 	
-	if req.wait() {
+	if req.Wait() {
 		select {
 		case e, ok := <-ch.errors:
 			if ok {
@@ -225,8 +226,8 @@ func (ch *Channel) sendClosed(msg message) (err error) {
 
 func (ch *Channel) sendOpen(msg message) (err error) {
 	if content, ok := msg.(messageWithContent); ok {
-		props, body := content.getContent()
-		class, _ := content.id()
+		props, body := content.GetContent()
+		class, _ := content.ID()
 		
 		// catch client max frame size==0 and server max frame size==0
 		// set size to length of what we're trying to publish
@@ -318,9 +319,14 @@ func (ch *Channel) dispatch(msg message) {
 		// publishing is happening concurrently
 		ch.m.Lock()
 		if err := ch.send(&channelCloseOk{}); err != nil {
-			Logger.Printf("error sending channelCloseOk, channel id: %d error: %+v", ch.id, err)
+			ch.connection.logAttrs(slog.LevelError, "error sending channelCloseOk",
+				slog.Int("channel_id", int(ch.id)), slog.Any("error", err))
 		}
 		ch.m.Unlock()
+		ch.connection.logAttrs(slog.LevelWarn, "channel closed by server",
+			slog.Int("channel_id", int(ch.id)),
+			slog.Int("reply_code", int(m.ReplyCode)),
+			slog.String("reply_text", m.ReplyText))
 		ch.connection.closeChannel(ch, newError(m.ReplyCode, m.ReplyText))
 	
 	case *channelFlow:
@@ -330,7 +336,8 @@ func (ch *Channel) dispatch(msg message) {
 		}
 		ch.notifyM.RUnlock()
 		if err := ch.send(&channelFlowOk{Active: m.Active}); err != nil {
-			Logger.Printf("error sending channelFlowOk, channel id: %d error: %+v", ch.id, err)
+			ch.connection.logAttrs(slog.LevelError, "error sending channelFlowOk",
+				slog.Int("channel_id", int(ch.id)), slog.Any("error", err))
 		}
 	
 	case *basicCancel:
@@ -422,7 +429,7 @@ func (ch *Channel) recvHeader(f frame) {
 		ch.header = frame
 		
 		if frame.Size == 0 {
-			ch.message.setContent(ch.header.Properties, ch.body)
+			ch.message.SetContent(ch.header.Properties, ch.body)
 			ch.dispatch(ch.message) // termination state
 			ch.transition((*Channel).recvMethod)
 			return
@@ -457,7 +464,7 @@ func (ch *Channel) recvContent(f frame) {
 		ch.body = append(ch.body, frame.Body...)
 		
 		if uint64(len(ch.body)) >= ch.header.Size {
-			ch.message.setContent(ch.header.Properties, ch.body)
+			ch.message.SetContent(ch.header.Properties, ch.body)
 			ch.dispatch(ch.message) // termination state
 			ch.transition((*Channel).recvMethod)
 			return
@@ -488,6 +495,11 @@ func (ch *Channel) Close() error {
 	)
 }
 
+// ID returns the channel number allocated on the connection.
+func (ch *Channel) ID() uint16 {
+	return ch.id
+}
+
 // IsClosed returns true if the channel is marked as closed, otherwise false
 // is returned.
 func (ch *Channel) IsClosed() bool {
@@ -745,7 +757,7 @@ func (ch *Channel) Cancel(consumer string, noWait bool) error {
 		return err
 	}
 	
-	if req.wait() {
+	if req.Wait() {
 		ch.consumers.cancel(res.ConsumerTag)
 	} else {
 		// Potentially could drop deliveries in flight
@@ -830,7 +842,7 @@ func (ch *Channel) QueueDeclare(name string, durable, autoDelete, exclusive, noW
 		return Queue{}, err
 	}
 	
-	if req.wait() {
+	if req.Wait() {
 		return Queue{
 			Name:      res.Queue,
 			Messages:  int(res.MessageCount),
@@ -868,7 +880,7 @@ func (ch *Channel) QueueDeclarePassive(name string, durable, autoDelete, exclusi
 		return Queue{}, err
 	}
 	
-	if req.wait() {
+	if req.Wait() {
 		return Queue{
 			Name:      res.Queue,
 			Messages:  int(res.MessageCount),
@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"os"
 	"reflect"
@@ -20,6 +21,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/oarkflow/amqp/amqp091/internal/wire"
 )
 
 const (
@@ -74,6 +77,11 @@ type Config struct {
 	// If Dial is nil, net.DialTimeout with a 30s connection and 30s deadline is
 	// used during TLS and AMQP handshaking.
 	Dial func(network, addr string) (net.Conn, error)
+
+	// Logger receives the structured records of the protocol events of the
+	// connection and its channels, such as their closing by the server along
+	// the reply code. Nil (the default) disables structured logging.
+	Logger *slog.Logger
 }
 
 // NewConnectionProperties creates an amqp.Table to be used as amqp.Config.Properties.
@@ -103,6 +111,7 @@ type Connection struct {
 	writer    *writer
 	sends     chan time.Time     // timestamps of each frame sent
 	deadlines chan readDeadliner // heartbeater updates read deadlines
+	logger    *slog.Logger       // structured logging, see Config.Logger
 
 	allocator *allocator // id generator valid after openTune
 	channels  map[uint16]*Channel
@@ -260,13 +269,14 @@ to use your own custom transport.
 func Open(conn io.ReadWriteCloser, config Config) (*Connection, error) {
 	c := &Connection{
 		conn:      conn,
-		writer:    &writer{bufio.NewWriter(conn)},
+		writer:    wire.NewWriter(bufio.NewWriter(conn)),
 		channels:  make(map[uint16]*Channel),
 		rpc:       make(chan message),
 		sends:     make(chan time.Time),
 		errors:    make(chan *Error, 1),
 		close:     make(chan struct{}),
 		deadlines: make(chan readDeadliner, 1),
+		logger:    config.Logger,
 	}
 	go c.reader(conn)
 	return c, c.open(config)
@@ -544,7 +554,7 @@ func (c *Connection) sendUnflushed(f frame) error {
 // This method is intended to be used with sendUnflushed() to explicitly flush
 // the buffer after all required Frames have been written to the buffer.
 func (c *Connection) flush() (err error) {
-	if buf, ok := c.writer.w.(*bufio.Writer); ok {
+	if buf, ok := c.writer.Buffer(); ok {
 		err = buf.Flush()
 
 		// Moving send notifier to flush increases basicPublish for the small message
@@ -612,7 +622,7 @@ func (c *Connection) shutdown(err *Error) {
 // All methods sent to the connection channel should be synchronous so we
 // can handle them directly without a framing component
 func (c *Connection) demux(f frame) {
-	if f.channel() == 0 {
+	if f.Channel() == 0 {
 		c.dispatch0(f)
 	} else {
 		c.dispatchN(f)
@@ -627,8 +637,12 @@ func (c *Connection) dispatch0(f frame) {
 			// Send immediately as shutdown will close our side of the writer.
 			f := &methodFrame{ChannelId: 0, Method: &connectionCloseOk{}}
 			if err := c.send(f); err != nil {
-				Logger.Printf("error sending connectionCloseOk, error: %+v", err)
+				c.logAttrs(slog.LevelError, "error sending connectionCloseOk", slog.Any("error", err))
 			}
+			c.logAttrs(slog.LevelWarn, "connection closed by server",
+				slog.String("vhost", c.Config.Vhost),
+				slog.Int("reply_code", int(m.ReplyCode)),
+				slog.String("reply_text", m.ReplyText))
 			c.shutdown(newError(m.ReplyCode, m.ReplyText))
 		case *connectionBlocked:
 			for _, c := range c.blocks {
@@ -653,7 +667,7 @@ func (c *Connection) dispatch0(f frame) {
 		// closeWith use call don't block reader
 		go func() {
 			if err := c.closeWith(ErrUnexpectedFrame); err != nil {
-				Logger.Printf("error sending connectionCloseOk with ErrUnexpectedFrame, error: %+v", err)
+				c.logAttrs(slog.LevelError, "error sending connectionCloseOk with ErrUnexpectedFrame", slog.Any("error", err))
 			}
 		}()
 	}
@@ -661,11 +675,11 @@ func (c *Connection) dispatch0(f frame) {
 
 func (c *Connection) dispatchN(f frame) {
 	c.m.Lock()
-	channel, ok := c.channels[f.channel()]
+	channel, ok := c.channels[f.Channel()]
 	if ok {
 		updateChannel(f, channel)
 	} else {
-		Logger.Printf("[debug] dropping frame, channel %d does not exist", f.channel())
+		c.logAttrs(slog.LevelDebug, "dropping frame, channel does not exist", slog.Int("channel_id", int(f.Channel())))
 	}
 	c.m.Unlock()
 
@@ -694,9 +708,10 @@ func (c *Connection) dispatchClosed(f frame) {
 	if mf, ok := f.(*methodFrame); ok {
 		switch mf.Method.(type) {
 		case *channelClose:
-			f := &methodFrame{ChannelId: f.channel(), Method: &channelCloseOk{}}
+			f := &methodFrame{ChannelId: f.Channel(), Method: &channelCloseOk{}}
 			if err := c.send(f); err != nil {
-				Logger.Printf("error sending channelCloseOk, channel id: %d error: %+v", f.channel(), err)
+				c.logAttrs(slog.LevelError, "error sending channelCloseOk",
+					slog.Int("channel_id", int(f.Channel())), slog.Any("error", err))
 			}
 		case *channelCloseOk:
 			// we are already closed, so do nothing
@@ -705,7 +720,7 @@ func (c *Connection) dispatchClosed(f frame) {
 			// closeWith use call don't block reader
 			go func() {
 				if err := c.closeWith(ErrClosed); err != nil {
-					Logger.Printf("error sending connectionCloseOk with ErrClosed, error: %+v", err)
+					c.logAttrs(slog.LevelError, "error sending connectionCloseOk with ErrClosed", slog.Any("error", err))
 				}
 			}()
 		}
@@ -717,7 +732,7 @@ func (c *Connection) dispatchClosed(f frame) {
 // handle on channel 0 (the connection channel).
 func (c *Connection) reader(r io.Reader) {
 	buf := bufio.NewReader(r)
-	frames := &reader{buf}
+	frames := wire.NewReader(buf)
 	conn, haveDeadliner := r.(readDeadliner)
 
 	defer close(c.rpc)
@@ -784,7 +799,7 @@ func (c *Connection) heartbeater(interval time.Duration, done chan *Error) {
 				if err := conn.SetReadDeadline(time.Now().Add(maxServerHeartbeatsInFlight * interval)); err != nil {
 					var opErr *net.OpError
 					if !errors.As(err, &opErr) {
-						Logger.Printf("error setting read deadline in heartbeater: %+v", err)
+						c.logAttrs(slog.LevelError, "error setting read deadline in heartbeater", slog.Any("error", err))
 						return
 					}
 				}
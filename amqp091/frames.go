@@ -0,0 +1,91 @@
+// Copyright (c) 2021 VMware, Inc. or its affiliates. All Rights Reserved.
+// Copyright (c) 2012-2021, Sean Treadway, SoundCloud Ltd.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package amqp091
+
+import "github.com/oarkflow/amqp/amqp091/internal/wire"
+
+// The frames and protocol methods are encoded by the internal wire package,
+// shared with the amqptest broker; these aliases keep them private to the client.
+type (
+	frame   = wire.Frame
+	message = wire.Message
+	reader  = wire.Reader
+	writer  = wire.Writer
+)
+
+type (
+	connectionStart          = wire.ConnectionStart
+	connectionStartOk        = wire.ConnectionStartOk
+	connectionSecure         = wire.ConnectionSecure
+	connectionSecureOk       = wire.ConnectionSecureOk
+	connectionTune           = wire.ConnectionTune
+	connectionTuneOk         = wire.ConnectionTuneOk
+	connectionOpen           = wire.ConnectionOpen
+	connectionOpenOk         = wire.ConnectionOpenOk
+	connectionClose          = wire.ConnectionClose
+	connectionCloseOk        = wire.ConnectionCloseOk
+	connectionBlocked        = wire.ConnectionBlocked
+	connectionUnblocked      = wire.ConnectionUnblocked
+	connectionUpdateSecret   = wire.ConnectionUpdateSecret
+	connectionUpdateSecretOk = wire.ConnectionUpdateSecretOk
+	channelOpen              = wire.ChannelOpen
+	channelOpenOk            = wire.ChannelOpenOk
+	channelFlow              = wire.ChannelFlow
+	channelFlowOk            = wire.ChannelFlowOk
+	channelClose             = wire.ChannelClose
+	channelCloseOk           = wire.ChannelCloseOk
+	exchangeDeclare          = wire.ExchangeDeclare
+	exchangeDeclareOk        = wire.ExchangeDeclareOk
+	exchangeDelete           = wire.ExchangeDelete
+	exchangeDeleteOk         = wire.ExchangeDeleteOk
+	exchangeBind             = wire.ExchangeBind
+	exchangeBindOk           = wire.ExchangeBindOk
+	exchangeUnbind           = wire.ExchangeUnbind
+	exchangeUnbindOk         = wire.ExchangeUnbindOk
+	queueDeclare             = wire.QueueDeclare
+	queueDeclareOk           = wire.QueueDeclareOk
+	queueBind                = wire.QueueBind
+	queueBindOk              = wire.QueueBindOk
+	queueUnbind              = wire.QueueUnbind
+	queueUnbindOk            = wire.QueueUnbindOk
+	queuePurge               = wire.QueuePurge
+	queuePurgeOk             = wire.QueuePurgeOk
+	queueDelete              = wire.QueueDelete
+	queueDeleteOk            = wire.QueueDeleteOk
+	basicQos                 = wire.BasicQos
+	basicQosOk               = wire.BasicQosOk
+	basicConsume             = wire.BasicConsume
+	basicConsumeOk           = wire.BasicConsumeOk
+	basicCancel              = wire.BasicCancel
+	basicCancelOk            = wire.BasicCancelOk
+	basicPublish             = wire.BasicPublish
+	basicReturn              = wire.BasicReturn
+	basicDeliver             = wire.BasicDeliver
+	basicGet                 = wire.BasicGet
+	basicGetOk               = wire.BasicGetOk
+	basicGetEmpty            = wire.BasicGetEmpty
+	basicAck                 = wire.BasicAck
+	basicReject              = wire.BasicReject
+	basicRecoverAsync        = wire.BasicRecoverAsync
+	basicRecover             = wire.BasicRecover
+	basicRecoverOk           = wire.BasicRecoverOk
+	basicNack                = wire.BasicNack
+	txSelect                 = wire.TxSelect
+	txSelectOk               = wire.TxSelectOk
+	txCommit                 = wire.TxCommit
+	txCommitOk               = wire.TxCommitOk
+	txRollback               = wire.TxRollback
+	txRollbackOk             = wire.TxRollbackOk
+	confirmSelect            = wire.ConfirmSelect
+	confirmSelectOk          = wire.ConfirmSelectOk
+	methodFrame              = wire.MethodFrame
+	headerFrame              = wire.HeaderFrame
+	bodyFrame                = wire.BodyFrame
+	heartbeatFrame           = wire.HeartbeatFrame
+	properties               = wire.Properties
+	messageWithContent       = wire.MessageWithContent
+	protocolHeader           = wire.ProtocolHeader
+)
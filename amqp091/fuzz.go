@@ -8,10 +8,14 @@
 
 package amqp091
 
-import "bytes"
+import (
+	"bytes"
+
+	"github.com/oarkflow/amqp/amqp091/internal/wire"
+)
 
 func Fuzz(data []byte) int {
-	r := reader{bytes.NewReader(data)}
+	r := wire.NewReader(bytes.NewReader(data))
 	frame, err := r.ReadFrame()
 	if err != nil {
 		if frame != nil {
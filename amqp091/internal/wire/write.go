@@ -3,7 +3,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package amqp091
+package wire
 
 import (
 	"bufio"
@@ -15,13 +15,13 @@ import (
 	"time"
 )
 
-func (w *writer) WriteFrameNoFlush(frame frame) (err error) {
-	err = frame.write(w.w)
+func (w *Writer) WriteFrameNoFlush(frame Frame) (err error) {
+	err = frame.Write(w.w)
 	return
 }
 
-func (w *writer) WriteFrame(frame frame) (err error) {
-	if err = frame.write(w.w); err != nil {
+func (w *Writer) WriteFrame(frame Frame) (err error) {
+	if err = frame.Write(w.w); err != nil {
 		return
 	}
 
@@ -32,14 +32,14 @@ func (w *writer) WriteFrame(frame frame) (err error) {
 	return
 }
 
-func (f *methodFrame) write(w io.Writer) (err error) {
+func (f *MethodFrame) Write(w io.Writer) (err error) {
 	var payload bytes.Buffer
 
 	if f.Method == nil {
 		return errors.New("malformed frame: missing method")
 	}
 
-	class, method := f.Method.id()
+	class, method := f.Method.ID()
 
 	if err = binary.Write(&payload, binary.BigEndian, class); err != nil {
 		return
@@ -49,7 +49,7 @@ func (f *methodFrame) write(w io.Writer) (err error) {
 		return
 	}
 
-	if err = f.Method.write(&payload); err != nil {
+	if err = f.Method.Write(&payload); err != nil {
 		return
 	}
 
@@ -59,7 +59,7 @@ func (f *methodFrame) write(w io.Writer) (err error) {
 // Heartbeat
 //
 // Payload is empty
-func (f *heartbeatFrame) write(w io.Writer) (err error) {
+func (f *HeartbeatFrame) Write(w io.Writer) (err error) {
 	return writeFrame(w, frameHeartbeat, f.ChannelId, []byte{})
 }
 
@@ -70,7 +70,7 @@ func (f *heartbeatFrame) write(w io.Writer) (err error) {
 // +----------+--------+-----------+----------------+------------- - -
 //
 //	short     short    long long       short        remainder...
-func (f *headerFrame) write(w io.Writer) (err error) {
+func (f *HeaderFrame) Write(w io.Writer) (err error) {
 	var payload bytes.Buffer
 
 	if err = binary.Write(&payload, binary.BigEndian, f.ClassId); err != nil {
@@ -207,7 +207,7 @@ func (f *headerFrame) write(w io.Writer) (err error) {
 //
 // Payload is one byterange from the full body who's size is declared in the
 // Header frame
-func (f *bodyFrame) write(w io.Writer) (err error) {
+func (f *BodyFrame) Write(w io.Writer) (err error) {
 	return writeFrame(w, frameBody, f.ChannelId, f.Body)
 }
 
@@ -424,3 +424,14 @@ func writeTable(w io.Writer, table Table) (err error) {
 
 	return writeLongstr(w, buf.String())
 }
+
+// Buffer returns the buffered stream the frames are written to, when it is one.
+func (w *Writer) Buffer() (*bufio.Writer, bool) {
+	buf, ok := w.w.(*bufio.Writer)
+	return buf, ok
+}
+
+// WriteTable encodes the field table, preceded by its size.
+func WriteTable(w io.Writer, table Table) error {
+	return writeTable(w, table)
+}
@@ -4,6 +4,13 @@
 
 package amqp091
 
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
 type Logging interface {
 	Printf(format string, v ...interface{})
 }
@@ -21,3 +28,25 @@ type NullLogger struct {
 
 func (l NullLogger) Printf(format string, v ...interface{}) {
 }
+
+// logAttrs sends a record to the structured logger of the connection (see
+// [Config].Logger), when set, and its message followed by the key=value
+// attributes to the Printf logger.
+func (c *Connection) logAttrs(level slog.Level, msg string, attrs ...slog.Attr) {
+	if c.logger != nil {
+		c.logger.LogAttrs(context.Background(), level, msg, attrs...)
+	}
+	if _, ok := Logger.(NullLogger); ok {
+		return
+	}
+
+	var b strings.Builder
+	if level < slog.LevelInfo {
+		b.WriteString("[debug] ")
+	}
+	b.WriteString(msg)
+	for _, attr := range attrs {
+		fmt.Fprintf(&b, " %s=%+v", attr.Key, attr.Value.Any())
+	}
+	Logger.Printf("%s", b.String())
+}
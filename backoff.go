@@ -0,0 +1,291 @@
+package grabbit
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// AbortRecovery is returned by a [DelayProvider] for ending the reconnect loop
+// instead of waiting for another attempt, ex: [MaxAttemptsDelayer].
+const AbortRecovery time.Duration = -1
+
+// ExponentialDelayer grows the delay exponentially with the retry counter:
+// Base * Factor^(retry-1), bounded by Max.
+//
+// With FullJitter, the actual delay is random between zero and the computed one,
+// spreading the reconnections of many clients after a broker restart.
+type ExponentialDelayer struct {
+	Base       time.Duration // first delay, 500ms when zero
+	Max        time.Duration // upper bound, 1 minute when zero
+	Factor     float64       // growth per retry, 2 when below 1
+	FullJitter bool          // random delay in [0, computed delay)
+}
+
+// Delay implements the DelayProvider i/face for the ExponentialDelayer.
+func (d ExponentialDelayer) Delay(retry int) time.Duration {
+	base, ceiling := d.bounds()
+	factor := d.Factor
+	if factor < 1 {
+		factor = 2
+	}
+
+	delay := ceiling
+	if exp := float64(base) * math.Pow(factor, float64(max(retry, 1)-1)); exp < float64(ceiling) {
+		delay = time.Duration(exp)
+	}
+	if d.FullJitter {
+		delay = time.Duration(rand.Int63n(int64(delay) + 1))
+	}
+
+	return delay
+}
+
+// bounds returns the first and the maximum delay, defaults applied.
+func (d ExponentialDelayer) bounds() (time.Duration, time.Duration) {
+	base, ceiling := d.Base, d.Max
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+	if ceiling <= 0 {
+		ceiling = time.Minute
+	}
+	return base, max(base, ceiling)
+}
+
+// DecorrelatedJitterDelayer picks each delay randomly between Base and three times
+// the previous delay, bounded by Max; the sequence restarts with the first retry.
+// It keeps state: channels inheriting it from their connection get their own copy.
+type DecorrelatedJitterDelayer struct {
+	Base time.Duration // minimum delay, 500ms when zero
+	Max  time.Duration // upper bound, 1 minute when zero
+
+	mu       sync.Mutex
+	previous time.Duration
+}
+
+// Delay implements the DelayProvider i/face for the DecorrelatedJitterDelayer.
+func (d *DecorrelatedJitterDelayer) Delay(retry int) time.Duration {
+	base, ceiling := ExponentialDelayer{Base: d.Base, Max: d.Max}.bounds()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if retry <= 1 || d.previous < base {
+		d.previous = base
+	}
+	upper := min(3*d.previous, ceiling)
+	d.previous = base + time.Duration(rand.Int63n(int64(upper-base)+1))
+
+	return d.previous
+}
+
+// fork implements the forker i/face.
+func (d *DecorrelatedJitterDelayer) fork() DelayProvider {
+	return &DecorrelatedJitterDelayer{Base: d.Base, Max: d.Max}
+}
+
+// MaxAttemptsDelayer gives up recovering after MaxAttempts failed attempts,
+// delaying the previous ones as Delayer does.
+type MaxAttemptsDelayer struct {
+	Delayer     DelayProvider // delay between the attempts, [ExponentialDelayer] defaults when nil
+	MaxAttempts int           // failed attempts before giving up
+}
+
+// Delay implements the DelayProvider i/face for the MaxAttemptsDelayer.
+func (d MaxAttemptsDelayer) Delay(retry int) time.Duration {
+	if retry >= d.MaxAttempts {
+		return AbortRecovery
+	}
+	if d.Delayer == nil {
+		return ExponentialDelayer{}.Delay(retry)
+	}
+	return d.Delayer.Delay(retry)
+}
+
+// fork implements the forker i/face.
+func (d MaxAttemptsDelayer) fork() DelayProvider {
+	d.Delayer = forkDelayer(d.Delayer)
+	return d
+}
+
+// BreakerState tells whether a [CircuitBreaker] lets the (re)connection attempts through.
+type BreakerState int
+
+//go:generate stringer -type=BreakerState -trimprefix=Breaker
+const (
+	BreakerClosed   BreakerState = iota // attempts delayed as by the wrapped delayer
+	BreakerOpen                         // attempts suspended for the open timeout
+	BreakerHalfOpen                     // one trial attempt, closing the breaker on success
+)
+
+// CircuitBreaker delays the attempts as Delayer does till Threshold consecutive failures,
+// then opens: the next attempt waits for OpenTimeout and is a half-open trial, closing the
+// breaker on success and opening it again on failure. The state changes are raised
+// as EventBreaker, with the state as TargetName.
+//
+// It keeps state: use one per connection; channels inheriting it from their connection
+// get their own copy.
+type CircuitBreaker struct {
+	Delayer     DelayProvider // delay while closed, [ExponentialDelayer] defaults when nil
+	Threshold   int           // consecutive failures opening the breaker, 5 when zero
+	OpenTimeout time.Duration // time spent open before the half-open trial, 30s when zero
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+}
+
+// NewCircuitBreaker creates a closed breaker opening after threshold failures for openTimeout.
+func NewCircuitBreaker(delayer DelayProvider, threshold int, openTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Delayer:     delayer,
+		Threshold:   threshold,
+		OpenTimeout: openTimeout,
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Delay implements the DelayProvider i/face for the CircuitBreaker.
+func (b *CircuitBreaker) Delay(retry int) time.Duration {
+	b.mu.Lock()
+	state := b.state
+	b.mu.Unlock()
+
+	if state == BreakerOpen {
+		if b.OpenTimeout <= 0 {
+			return 30 * time.Second
+		}
+		return b.OpenTimeout
+	}
+	if b.Delayer == nil {
+		return ExponentialDelayer{}.Delay(retry)
+	}
+	return b.Delayer.Delay(retry)
+}
+
+// attempting implements the breaker i/face: the attempt after the open timeout is a trial.
+func (b *CircuitBreaker) attempting() (BreakerState, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		b.state = BreakerHalfOpen
+		return b.state, true
+	}
+	return b.state, false
+}
+
+// attempted implements the breaker i/face, counting the consecutive failures.
+func (b *CircuitBreaker) attempted(ok bool) (BreakerState, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous := b.state
+	threshold := b.Threshold
+	if threshold <= 0 {
+		threshold = 5
+	}
+
+	switch {
+	case ok:
+		b.failures = 0
+		b.state = BreakerClosed
+	case b.state == BreakerHalfOpen:
+		b.state = BreakerOpen
+	default:
+		b.failures++
+		if b.failures >= threshold {
+			b.state = BreakerOpen
+		}
+	}
+
+	return b.state, b.state != previous
+}
+
+// fork implements the forker i/face.
+func (b *CircuitBreaker) fork() DelayProvider {
+	return &CircuitBreaker{
+		Delayer:     forkDelayer(b.Delayer),
+		Threshold:   b.Threshold,
+		OpenTimeout: b.OpenTimeout,
+	}
+}
+
+// breaker is implemented by the delayers tracking the outcome of the attempts.
+type breaker interface {
+	attempting() (BreakerState, bool)       // before an attempt, returns the state and whether it changed
+	attempted(ok bool) (BreakerState, bool) // after an attempt, returns the state and whether it changed
+}
+
+// forker is implemented by the delayers keeping state, for giving
+// each channel its own copy of the delayer inherited from the connection.
+type forker interface {
+	fork() DelayProvider
+}
+
+// forkDelayer returns a stateless delayer as is, a fresh copy of a stateful one.
+func forkDelayer(delayer DelayProvider) DelayProvider {
+	if f, ok := delayer.(forker); ok {
+		return f.fork()
+	}
+	return delayer
+}
+
+// attemptBegins tells a circuit breaker delayer about an upcoming attempt.
+func attemptBegins(delayer DelayProvider) (BreakerState, bool) {
+	if b, ok := delayer.(breaker); ok {
+		return b.attempting()
+	}
+	return BreakerClosed, false
+}
+
+// attemptEnds tells a circuit breaker delayer about the outcome of an attempt.
+func attemptEnds(delayer DelayProvider, ok bool) (BreakerState, bool) {
+	if b, isBreaker := delayer.(breaker); isBreaker {
+		return b.attempted(ok)
+	}
+	return BreakerClosed, false
+}
+
+// breakerChanged raises the new state of the connection circuit breaker.
+func (conn *Connection) breakerChanged(state BreakerState) {
+	Event{
+		SourceType: CliConnection,
+		SourceName: conn.opt.name,
+		Kind:       EventBreaker,
+		TargetName: state.String(),
+	}.raise(conn.opt.notifier)
+	conn.log(conn.opt.logLevels.Recovery, "connection circuit breaker "+breakerVerb(state), nil)
+}
+
+// breakerChanged raises the new state of the channel circuit breaker.
+func (ch *Channel) breakerChanged(state BreakerState) {
+	Event{
+		SourceType: CliChannel,
+		SourceName: ch.opt.name,
+		Kind:       EventBreaker,
+		TargetName: state.String(),
+	}.raise(ch.notifier())
+	ch.log(ch.opt.logLevels.Recovery, "channel circuit breaker "+breakerVerb(state), nil)
+}
+
+// breakerVerb describes the state change for the logs.
+func breakerVerb(state BreakerState) string {
+	switch state {
+	case BreakerOpen:
+		return "opened"
+	case BreakerHalfOpen:
+		return "half-opened"
+	default:
+		return "closed"
+	}
+}
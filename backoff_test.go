@@ -0,0 +1,214 @@
+package grabbit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialDelayer(t *testing.T) {
+	tests := []struct {
+		name    string
+		delayer ExponentialDelayer
+		delays  []time.Duration // from the first retry on
+	}{
+		{"defaults", ExponentialDelayer{}, []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second}},
+		{"factor", ExponentialDelayer{Base: 100 * time.Millisecond, Factor: 3}, []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond}},
+		{"factor below 1", ExponentialDelayer{Base: time.Second, Factor: 0.5}, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}},
+		{"bounded", ExponentialDelayer{Base: time.Second, Max: 3 * time.Second}, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}},
+		{"max below base", ExponentialDelayer{Base: time.Second, Max: time.Millisecond}, []time.Duration{time.Second, time.Second}},
+	}
+
+	for _, test := range tests {
+		for i, expected := range test.delays {
+			if delay := test.delayer.Delay(i + 1); delay != expected {
+				t.Errorf("%s: retry %d delayed %v, expected %v", test.name, i+1, delay, expected)
+			}
+		}
+	}
+
+	// no overflow on the long outages
+	if delay := (ExponentialDelayer{}).Delay(10000); delay != time.Minute {
+		t.Fatalf("retry 10000 delayed %v", delay)
+	}
+	if delay := (ExponentialDelayer{}).Delay(0); delay != 500*time.Millisecond {
+		t.Fatalf("retry 0 delayed %v", delay)
+	}
+}
+
+func TestExponentialDelayerJitter(t *testing.T) {
+	delayer := ExponentialDelayer{Base: 100 * time.Millisecond, Max: time.Second, FullJitter: true}
+
+	for retry := 1; retry <= 6; retry++ {
+		ceiling := min(100*time.Millisecond<<(retry-1), time.Second)
+		var lowest, highest time.Duration = ceiling, 0
+		for i := 0; i < 1000; i++ {
+			delay := delayer.Delay(retry)
+			if delay < 0 || delay > ceiling {
+				t.Fatalf("retry %d delayed %v, beyond [0, %v]", retry, delay, ceiling)
+			}
+			lowest, highest = min(lowest, delay), max(highest, delay)
+		}
+		// spread over the whole range
+		if lowest > ceiling/10 || highest < ceiling*9/10 {
+			t.Fatalf("retry %d delays in [%v, %v] of [0, %v]", retry, lowest, highest, ceiling)
+		}
+	}
+}
+
+func TestDecorrelatedJitterDelayer(t *testing.T) {
+	base, ceiling := 100*time.Millisecond, 2*time.Second
+	delayer := &DecorrelatedJitterDelayer{Base: base, Max: ceiling}
+
+	for round := 0; round < 100; round++ {
+		previous := base
+		for retry := 1; retry <= 10; retry++ {
+			delay := delayer.Delay(retry)
+			if upper := min(3*previous, ceiling); delay < base || delay > upper {
+				t.Fatalf("retry %d delayed %v, beyond [%v, %v]", retry, delay, base, upper)
+			}
+			previous = delay
+		}
+	}
+
+	// grows away from the base, the first retry restarting the sequence
+	var reached bool
+	for i := 0; i < 100 && !reached; i++ {
+		for retry := 1; retry <= 10; retry++ {
+			reached = delayer.Delay(retry) > ceiling/2 || reached
+		}
+	}
+	if !reached {
+		t.Fatal("delays not growing")
+	}
+	if delay := delayer.Delay(1); delay > 3*base {
+		t.Fatalf("restarted at %v", delay)
+	}
+
+	// the copy given to a channel starts afresh
+	forked := forkDelayer(delayer).(*DecorrelatedJitterDelayer)
+	if forked == delayer || forked.previous != 0 || forked.Base != base || forked.Max != ceiling {
+		t.Fatalf("forked %+v", forked)
+	}
+}
+
+func TestMaxAttemptsDelayer(t *testing.T) {
+	tests := []struct {
+		name    string
+		delayer MaxAttemptsDelayer
+		delays  []time.Duration // from the first retry on
+	}{
+		{"wrapped", MaxAttemptsDelayer{Delayer: DefaultDelayer{Value: time.Second}, MaxAttempts: 3}, []time.Duration{time.Second, time.Second, AbortRecovery, AbortRecovery}},
+		{"no delayer", MaxAttemptsDelayer{MaxAttempts: 3}, []time.Duration{500 * time.Millisecond, time.Second, AbortRecovery}},
+		{"no attempts", MaxAttemptsDelayer{}, []time.Duration{AbortRecovery}},
+	}
+
+	for _, test := range tests {
+		for i, expected := range test.delays {
+			if delay := test.delayer.Delay(i + 1); delay != expected {
+				t.Errorf("%s: retry %d delayed %v, expected %v", test.name, i+1, delay, expected)
+			}
+		}
+	}
+
+	// the wrapped stateful delayer is copied
+	wrapped := &DecorrelatedJitterDelayer{}
+	if forked := forkDelayer(MaxAttemptsDelayer{Delayer: wrapped}).(MaxAttemptsDelayer); forked.Delayer == wrapped {
+		t.Fatal("wrapped delayer shared")
+	}
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	type step struct {
+		ok      bool         // outcome of the attempt
+		state   BreakerState // after the attempt
+		changed bool
+	}
+	tests := []struct {
+		name      string
+		threshold int
+		steps     []step
+	}{
+		{"closed below the threshold", 3, []step{
+			{false, BreakerClosed, false}, {false, BreakerClosed, false}, {true, BreakerClosed, false},
+			{false, BreakerClosed, false}, {false, BreakerClosed, false},
+		}},
+		{"opens at the threshold", 2, []step{
+			{false, BreakerClosed, false}, {false, BreakerOpen, true},
+		}},
+		{"half-open trial failing", 1, []step{
+			{false, BreakerOpen, true}, {false, BreakerOpen, true}, {false, BreakerOpen, true},
+		}},
+		{"half-open trial succeeding", 1, []step{
+			{false, BreakerOpen, true}, {true, BreakerClosed, true}, {true, BreakerClosed, false},
+		}},
+		{"default threshold", 0, []step{
+			{false, BreakerClosed, false}, {false, BreakerClosed, false}, {false, BreakerClosed, false},
+			{false, BreakerClosed, false}, {false, BreakerOpen, true},
+		}},
+	}
+
+	for _, test := range tests {
+		breaker := NewCircuitBreaker(nil, test.threshold, time.Second)
+		for i, step := range test.steps {
+			// the attempt following an opening is the half-open trial
+			wasOpen := breaker.State() == BreakerOpen
+			if state, changed := attemptBegins(breaker); changed != wasOpen || wasOpen && state != BreakerHalfOpen {
+				t.Fatalf("%s: step %d began %v, changed %v", test.name, i, state, changed)
+			}
+			if state, changed := attemptEnds(breaker, step.ok); state != step.state || changed != step.changed {
+				t.Fatalf("%s: step %d ended %v, changed %v; expected %v, %v", test.name, i, state, changed, step.state, step.changed)
+			}
+		}
+	}
+}
+
+func TestCircuitBreakerDelays(t *testing.T) {
+	breaker := NewCircuitBreaker(DefaultDelayer{Value: time.Second}, 1, 0)
+	if delay := breaker.Delay(1); delay != time.Second {
+		t.Fatalf("closed: delayed %v", delay)
+	}
+	attemptEnds(breaker, false)
+	if delay := breaker.Delay(2); delay != 30*time.Second {
+		t.Fatalf("open: delayed %v", delay)
+	}
+
+	breaker = NewCircuitBreaker(nil, 1, time.Minute)
+	if delay := breaker.Delay(2); delay != time.Second {
+		t.Fatalf("closed, no delayer: delayed %v", delay)
+	}
+	attemptEnds(breaker, false)
+	if delay := breaker.Delay(3); delay != time.Minute {
+		t.Fatalf("open: delayed %v", delay)
+	}
+
+	// channels get their own closed breaker
+	forked := forkDelayer(breaker).(*CircuitBreaker)
+	if forked == breaker || forked.State() != BreakerClosed || forked.OpenTimeout != time.Minute {
+		t.Fatalf("forked %+v", forked)
+	}
+}
+
+func TestAbortRecoveryEndsReconnect(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults,
+		WithConnectionDelay(MaxAttemptsDelayer{Delayer: testDelay, MaxAttempts: 2}))
+	eventually(t, 5*time.Second, func() bool { return !conn.IsClosed() }, "connection not established")
+	failures, cancel := conn.Subscribe(EventsOfKind(EventCannotEstablish), WithSubscriptionBuffer(16))
+	defer cancel()
+
+	dials := faults.Dials()
+	faults.RejectDials(-1)
+	faults.Sever()
+	expectEvents(t, failures, EventCannotEstablish, EventCannotEstablish)
+
+	// no attempt after the last one, even once the broker is reachable again
+	time.Sleep(5 * testDelay.Value)
+	faults.Heal()
+	time.Sleep(5 * testDelay.Value)
+	if n := faults.Dials() - dials; n != 2 {
+		t.Fatalf("%d dials", n)
+	}
+	if !conn.IsClosed() {
+		t.Fatal("connection recovered")
+	}
+}
@@ -3,17 +3,26 @@ package grabbit
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 
 	amqp "github.com/oarkflow/amqp/amqp091"
 )
 
 // Channel wraps the base amqp channel by creating a managed channel.
 type Channel struct {
-	baseChan SafeBaseChan   // supporting amqp channel
-	conn     *Connection    // managed connection
-	paused   SafeBool       // flow status when of publisher type
-	opt      ChannelOptions // user parameters
-	queue    string         // currently assigned work queue
+	baseChan  SafeBaseChan                          // supporting amqp channel
+	conn      atomic.Pointer[Connection]            // managed connection, swapped when migrated by a [Pool]
+	paused    SafeBool                              // flow status when of publisher type
+	opt       ChannelOptions                        // user parameters
+	queue     string                                // currently assigned work queue
+	id        atomic.Uint32                         // number of the base channel, for logging
+	migrating atomic.Bool                           // the base channel is closed for moving to another connection
+	bus       atomic.Pointer[eventBus]              // events dispatcher: own or the one of the current connection
+	topoMu    sync.Mutex                            // serializes the topology declaration and the runtime bindings
+	declared  map[*TopologyOptions]string           // name each queue of the topology was last declared with
+	transient map[*TopologyOptions]*amqp.Connection // temporary queues declared at runtime, by base connection
 }
 
 // NewChannel creates a new managed Channel with the given Connection and optional ChannelOptions.
@@ -44,26 +53,43 @@ func NewChannel(conn *Connection, optionFuncs ...func(*ChannelOptions)) *Channel
 	opt := &ChannelOptions{
 		notifier:          conn.opt.notifier,
 		name:              "default",
-		delayer:           conn.opt.delayer,
+		delayer:           forkDelayer(conn.opt.delayer),
 		cbNotifyPublish:   defaultNotifyPublish,
 		cbNotifyReturn:    defaultNotifyReturn,
 		cbProcessMessages: defaultPayloadProcessor,
 		ctx:               conn.opt.ctx,
+		metrics:           conn.opt.metrics,
+		logger:            conn.opt.logger,
+		logLevels:         conn.opt.logLevels,
 	}
 
 	for _, optionFunc := range optionFuncs {
 		optionFunc(opt)
 	}
+	if opt.logger != nil {
+		opt.logger = opt.logger.With(slog.String("channel", opt.name))
+	}
+	chainConsume(opt)
+	if opt.tracer != nil && opt.handler == nil {
+		opt.cbProcessMessages = traceBatches(opt.cbProcessMessages)
+	}
 
 	ch := &Channel{
-		baseChan: SafeBaseChan{},
-		opt:      *opt,
-		conn:     conn,
+		baseChan:  SafeBaseChan{},
+		opt:       *opt,
+		declared:  make(map[*TopologyOptions]string),
+		transient: make(map[*TopologyOptions]*amqp.Connection),
 	}
+	ch.conn.Store(conn)
+	ch.bus.Store(ch.opt.notifier)
 
 	ch.opt.ctx, ch.opt.cancelCtx = context.WithCancel(opt.ctx)
 
 	go func() {
+		if ch.opt.notifier != conn.opt.notifier {
+			// own bus (see WithChannelNotification), ends along the channel
+			defer ch.opt.notifier.close()
+		}
 		if !ch.reconnectLoop(false) {
 			return
 		}
@@ -91,17 +117,25 @@ func (ch *Channel) pause(value bool) {
 	defer ch.paused.mu.Unlock()
 
 	kind := EventUnBlocked
+	msg := "channel flow resumed"
 	if value {
 		kind = EventBlocked
+		msg = "channel flow paused"
+		ch.opt.metrics.blocked(CliChannel, ch.opt.name)
 	}
 
 	Event{
 		SourceType: CliChannel,
 		SourceName: ch.opt.name,
 		Kind:       kind,
-	}.raise(ch.opt.notifier)
+	}.raise(ch.notifier())
+	ch.log(ch.opt.logLevels.Lifecycle, msg, nil)
 
 	ch.paused.value = value
+
+	if !value && ch.opt.spool != nil {
+		ch.opt.spool.notify()
+	}
 }
 
 // manage keep the channel alive.
@@ -124,6 +158,12 @@ func (ch *Channel) manage() {
 		if recovering {
 			recovering = false
 			notifiers = ch.notifiers()
+			if ch.opt.outbox != nil {
+				go ch.opt.outbox.replay(ch)
+			}
+			if ch.opt.spool != nil {
+				ch.opt.spool.notify()
+			}
 			if ch.opt.implParams.IsConsumer {
 				go ch.gobble(notifiers.Consumer)
 			}
@@ -132,15 +172,28 @@ func (ch *Channel) manage() {
 		select {
 		case <-ch.opt.ctx.Done():
 			ch.Close() // cancelCtx() called again but idempotent
+			ch.opt.metrics.rebased(ch)
+			ch.log(ch.opt.logLevels.Lifecycle, "channel closed", nil)
 			return
-		case status := <-notifiers.Flow:
-			ch.pause(status)
+		case active := <-notifiers.Flow:
+			ch.pause(!active)
 		case confirm, notifierStatus := <-notifiers.Published:
 			if notifierStatus {
+				ch.opt.metrics.confirmed(ch, confirm.DeliveryTag, confirm.Ack)
+				ch.logConfirm(confirm)
+				if ch.opt.outbox != nil {
+					ch.opt.outbox.confirm(confirm)
+				}
 				ch.opt.cbNotifyPublish(confirm, ch)
 			}
 		case msg, notifierStatus := <-notifiers.Returned:
 			if notifierStatus {
+				ch.opt.metrics.returned(ch.opt.name)
+				ch.log(ch.opt.logLevels.Confirms, "message returned", nil,
+					slog.Int("reply_code", int(msg.ReplyCode)),
+					slog.String("reply_text", msg.ReplyText),
+					slog.String("exchange", msg.Exchange),
+					slog.String("routing_key", msg.RoutingKey))
 				ch.opt.cbNotifyReturn(msg, ch)
 			}
 		case err, notifierStatus := <-notifiers.Closed:
@@ -167,12 +220,23 @@ func (ch *Channel) manage() {
 // Returns:
 //   - a boolean value indicating whether the recovery was successful.
 func (ch *Channel) recover(err OptionalError, notifierStatus bool) bool {
+	// closed on purpose, not on demand
+	if !err.IsSet() && ch.migrating.Swap(false) {
+		err = SomeErrFromError(errMigrating, true)
+	}
+	// pending confirmations are gone with the base channel
+	if ch.opt.outbox != nil {
+		ch.opt.outbox.invalidate()
+	}
+
 	Event{
 		SourceType: CliChannel,
 		SourceName: ch.opt.name,
 		Kind:       EventDown,
 		Err:        err,
-	}.raise(ch.opt.notifier)
+	}.raise(ch.notifier())
+	ch.opt.metrics.transition(CliChannel, ch.opt.name, EventDown)
+	ch.log(ch.opt.logLevels.Lifecycle, "channel down", err.Or(nil))
 	// abort by callback
 	if !callbackAllowedDown(ch.opt.cbDown, ch.opt.name, err) {
 		return false
@@ -185,7 +249,7 @@ func (ch *Channel) recover(err OptionalError, notifierStatus bool) bool {
 			SourceType: CliChannel,
 			SourceName: ch.opt.name,
 			Kind:       EventClosed,
-		}.raise(ch.opt.notifier)
+		}.raise(ch.notifier())
 	}
 
 	// no err means gracefully closed on demand
@@ -196,6 +260,54 @@ func (ch *Channel) Context() context.Context {
 	return ch.opt.ctx
 }
 
+// Connection returns the managed connection the channel belongs to.
+func (ch *Channel) Connection() *Connection {
+	return ch.conn.Load()
+}
+
+// migrate moves the channel onto another connection: the base channel is closed
+// and the recovery re-establishes it, along its topology and consumer, on conn.
+// Unless the channel has its own bus, its events go to the subscribers of conn from then on.
+func (ch *Channel) migrate(conn *Connection) {
+	previous := ch.conn.Swap(conn)
+	if previous == conn {
+		return
+	}
+	ch.bus.CompareAndSwap(previous.opt.notifier, conn.opt.notifier)
+	if ch.opt.spool != nil {
+		previous.spools.Delete(ch.opt.spool)
+		conn.spools.Store(ch.opt.spool, struct{}{})
+	}
+
+	ch.baseChan.mu.RLock()
+	super := ch.baseChan.super
+	ch.baseChan.mu.RUnlock()
+
+	// when recovering, the next attempt already uses conn
+	if super != nil && !super.IsClosed() {
+		ch.migrating.Store(true)
+		super.Close()
+	}
+}
+
+// notifier returns the dispatcher of the channel events.
+func (ch *Channel) notifier() *eventBus {
+	return ch.bus.Load()
+}
+
+// Subscribe registers a receiver of the events dispatched to this channel notifier:
+// the connection events, unless the channel has its own [WithChannelNotification].
+// Use [EventsFrom] with the channel [Channel.Name] for retaining only its own events.
+// See [Connection.Subscribe] for the filter, options and lifetime of the subscription.
+func (ch *Channel) Subscribe(filter EventFilter, optionFuncs ...func(*SubscriptionOptions)) (<-chan Event, func()) {
+	opt := DefaultSubscriptionOptions()
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&opt)
+	}
+
+	return ch.notifier().subscribe(filter, opt)
+}
+
 // rebase tries to establish a new base channel and returns a boolean indicating success or failure.
 // It sends en event notification with either EventUp or EventCannotEstablish, depending
 // on the new channel status.
@@ -207,12 +319,15 @@ func (ch *Channel) rebase() bool {
 	result := true
 	optError := OptionalError{}
 
-	if super, err := ch.conn.Channel(); err != nil {
+	if super, err := ch.Connection().Channel(); err != nil {
 		kind = EventCannotEstablish
 		optError = SomeErrFromError(err, true)
 		result = false
 	} else {
 		ch.baseChan.set(super)
+		ch.id.Store(uint32(super.ID()))
+		// sequence numbers restart with the base channel
+		ch.opt.metrics.rebased(ch)
 	}
 
 	Event{
@@ -220,7 +335,13 @@ func (ch *Channel) rebase() bool {
 		SourceName: ch.opt.name,
 		Kind:       kind,
 		Err:        optError,
-	}.raise(ch.opt.notifier)
+	}.raise(ch.notifier())
+	ch.opt.metrics.transition(CliChannel, ch.opt.name, kind)
+	if result {
+		ch.log(ch.opt.logLevels.Lifecycle, "channel up", nil)
+	} else {
+		ch.log(ch.opt.logLevels.Recovery, "channel cannot establish", optError.Or(nil))
+	}
 	callbackDoUp(result, ch.opt.cbUp, ch.opt.name)
 
 	return result
@@ -240,8 +361,16 @@ func (ch *Channel) reconnectLoop(recovering bool) bool {
 		if !callbackAllowedRecovery(ch.opt.cbReconnect, ch.opt.name, retry) {
 			return false
 		}
+		ch.opt.metrics.attempt(CliChannel, ch.opt.name)
 
-		if ch.rebase() {
+		if state, changed := attemptBegins(ch.opt.delayer); changed {
+			ch.breakerChanged(state)
+		}
+		ok := ch.rebase()
+		if state, changed := attemptEnds(ch.opt.delayer, ok); changed {
+			ch.breakerChanged(state)
+		}
+		if ok {
 			// cannot decide (yet) which infra is critical, let the caller decide via the raised events
 			ch.makeTopology(recovering)
 			return true
@@ -260,27 +389,39 @@ func (ch *Channel) reconnectLoop(recovering bool) bool {
 // It creates a local isolated channel (chLocal) and handles any errors that occur during this process.
 // It then iterates over the topology of the channel and performs the necessary operations based on the topology configuration.
 //   - if the topology element is an exchange, it declares the exchange using the declareExchange function.
-//   - if the topology element is a queue, it declares the queue using the declareQueue function.
+//   - if the topology element is a queue, it declares the queue using the declareQueue function,
+//     a server-named queue getting a new name unless it still exists (see redeclared).
 //   - if the topology element is marked as a destination, it saves a copy of the name for back reference.
+//   - when recovering, the elements kept by the broker (durable, neither exclusive nor auto-delete) are skipped.
+//   - the temporary queues declared at runtime and deleted meanwhile are dropped (see deleted).
 //
 // Finally, it raises an event for each topology element.
 func (ch *Channel) makeTopology(recovering bool) {
+	ch.topoMu.Lock()
+	defer ch.topoMu.Unlock()
+
 	// Channels are not concurrent data/usage wise!
 	// prefer using a local isolated channel.
-	chLocal, err := ch.conn.Channel()
+	chLocal, err := ch.Connection().Channel()
 	if err != nil {
 		Event{
 			SourceType: CliChannel,
 			SourceName: "topology.auto",
 			Kind:       EventCannotEstablish,
 			Err:        SomeErrFromError(err, true),
-		}.raise(ch.opt.notifier)
+		}.raise(ch.notifier())
+		ch.log(ch.opt.logLevels.Errors, "topology channel cannot establish", err)
 		return
 	}
 	defer chLocal.Close()
 
+	var dropped []*TopologyOptions
 	for _, t := range ch.opt.topology {
-		if !t.Declare || (recovering && t.Durable) {
+		if !t.Declare || (recovering && t.survives()) {
+			continue
+		}
+		if ch.deleted(t) {
+			dropped = append(dropped, t)
 			continue
 		}
 
@@ -292,12 +433,15 @@ func (ch *Channel) makeTopology(recovering bool) {
 			optError = SomeErrFromError(err, err != nil)
 			name = t.Name
 		} else {
-			queue, err := declareQueue(chLocal, t)
+			queue, err := ch.redeclareQueue(chLocal, t)
 			optError = SomeErrFromError(err, err != nil)
 			name = queue.Name
+			if err == nil {
+				ch.redeclared(t, name)
+			}
 		}
-		// save a copy for back reference
-		if t.IsDestination {
+		// save a copy for back reference; for exchanges it only tells the binding direction
+		if t.IsDestination && !t.IsExchange {
 			ch.baseChan.mu.Lock()
 			ch.queue = name
 			ch.baseChan.mu.Unlock()
@@ -309,25 +453,35 @@ func (ch *Channel) makeTopology(recovering bool) {
 			TargetName: t.Name,
 			Kind:       EventDefineTopology,
 			Err:        optError,
-		}.raise(ch.opt.notifier)
+		}.raise(ch.notifier())
+		ch.logTopology(t, name, optError.Or(nil))
+	}
+
+	for _, t := range dropped {
+		ch.drop(t)
 	}
 }
 
 // declareExchange is a function that declares an exchange in RabbitMQ.
+// A Passive entry is only checked for existence, a missing exchange failing the topology.
 //
 // It takes in a *amqp.Channel and a *TopologyOptions as parameters.
 // It returns an error.
 func declareExchange(ch *amqp.Channel, t *TopologyOptions) error {
-	err := ch.ExchangeDeclare(t.Name, t.Kind, t.Durable, t.AutoDelete, t.Internal, t.NoWait, t.Args)
-	if err == nil && t.Bind.Enabled {
-		source, destination := t.GetRouting()
-		err = ch.ExchangeBind(destination, t.Bind.Key, source, t.Bind.NoWait, t.Bind.Args)
+	declare := ch.ExchangeDeclare
+	if t.Passive {
+		declare = ch.ExchangeDeclarePassive
+	}
+	err := declare(t.Name, t.Kind, t.Durable, t.AutoDelete, t.Internal, t.NoWait, t.Args)
+	if err == nil {
+		err = bindTopology(ch, t, t.Name)
 	}
 
 	return err
 }
 
 // declareQueue declares a queue and performs additional operations if successful.
+// A Passive entry is only checked for existence, a missing queue failing the topology.
 //
 // Parameters:
 //   - ch: Pointer to an amqp.Channel object.
@@ -337,15 +491,54 @@ func declareExchange(ch *amqp.Channel, t *TopologyOptions) error {
 //   - amqp.Queue: The declared queue.
 //   - error: An error object if there was an issue with the declaration or the additional operations.
 func declareQueue(ch *amqp.Channel, t *TopologyOptions) (amqp.Queue, error) {
-	queue, err := ch.QueueDeclare(t.Name, t.Durable, t.AutoDelete, t.Exclusive, t.NoWait, t.Args)
+	declare := ch.QueueDeclare
+	if t.Passive {
+		declare = ch.QueueDeclarePassive
+	}
+	queue, err := declare(t.Name, t.Durable, t.AutoDelete, t.Exclusive, t.NoWait, t.Args)
 	if err == nil {
 		// sometimes the assigned name comes back empty. This is an indication of conn errors
 		if len(queue.Name) == 0 {
 			err = fmt.Errorf("cannot declare durable (%v) queue %s", t.Durable, t.Name)
-		} else if t.Bind.Enabled {
-			err = ch.QueueBind(queue.Name, t.Bind.Key, t.Bind.Peer, t.Bind.NoWait, t.Bind.Args)
+		} else {
+			err = bindTopology(ch, t, queue.Name)
 		}
 	}
 
 	return queue, err
 }
+
+// bindTopology adds the bindings of the entity (named as declared) and removes the stale ones.
+func bindTopology(ch *amqp.Channel, t *TopologyOptions, name string) error {
+	for _, bind := range t.Binds() {
+		if err := applyBind(ch, t, name, bind, true); err != nil {
+			return err
+		}
+	}
+	for _, bind := range t.StaleBindings {
+		if err := applyBind(ch, t, name, bind, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyBind adds or removes one of the bindings of the entity, named as declared.
+func applyBind(ch *amqp.Channel, t *TopologyOptions, name string, bind TopologyBind, add bool) error {
+	source, destination := t.RoutingOf(bind)
+	if !t.IsExchange {
+		destination = name
+	}
+
+	switch {
+	case t.IsExchange && add:
+		return ch.ExchangeBind(destination, bind.Key, source, bind.NoWait, bind.Args)
+	case t.IsExchange:
+		return ch.ExchangeUnbind(destination, bind.Key, source, bind.NoWait, bind.Args)
+	case add:
+		return ch.QueueBind(destination, bind.Key, source, bind.NoWait, bind.Args)
+	default:
+		return ch.QueueUnbind(destination, bind.Key, source, bind.Args)
+	}
+}
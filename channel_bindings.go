@@ -0,0 +1,135 @@
+package grabbit
+
+import "slices"
+
+// Bind routes a peer exchange to the queue or exchange of the channel topology
+// ([WithChannelTopology]) and remembers the binding, so that the recovery restores it
+// along the entity. Exchange entities follow their routing direction (see [TopologyOptions.RoutingOf]).
+// A name unknown to the topology is taken as an existing queue, checked passively on recovery.
+//
+// The broker command runs on a channel of its own: a refusal does not disrupt this one.
+// The options passed to [WithChannelTopology] are left as they are; the channel keeps
+// its own copy of the entity.
+func (ch *Channel) Bind(name string, bind TopologyBind) error {
+	ch.topoMu.Lock()
+	defer ch.topoMu.Unlock()
+
+	t := ch.topologyEntity(name)
+	known := t != nil
+	if !known {
+		t = &TopologyOptions{Name: name, Passive: true, Declare: true}
+	}
+	bind.Enabled = true
+
+	if err := ch.applyBind(t, name, bind, true); err != nil {
+		return err
+	}
+
+	if known {
+		t = ch.own(t)
+	} else {
+		topology := ch.opt.topology
+		ch.opt.topology = append(topology[:len(topology):len(topology)], t)
+	}
+	if !containsBind(t.Binds(), bind) {
+		t.Bindings = append(t.Bindings, bind)
+	}
+	t.StaleBindings = removeBind(t.StaleBindings, bind)
+
+	return nil
+}
+
+// Unbind removes a binding of the queue or exchange of the channel topology,
+// which the recovery no longer restores. A name unknown to the topology is taken as a queue.
+func (ch *Channel) Unbind(name string, bind TopologyBind) error {
+	ch.topoMu.Lock()
+	defer ch.topoMu.Unlock()
+
+	t := ch.topologyEntity(name)
+	if t == nil {
+		return ch.applyBind(&TopologyOptions{Name: name}, name, bind, false)
+	}
+
+	if err := ch.applyBind(t, name, bind, false); err != nil {
+		return err
+	}
+	t = ch.own(t)
+	if t.Bind.Enabled && sameBind(t.Bind, bind) {
+		t.Bind.Enabled = false
+	}
+	t.Bindings = removeBind(t.Bindings, bind)
+
+	return nil
+}
+
+// topologyEntity returns the declared entity of the topology with the name,
+// server-named queues included.
+func (ch *Channel) topologyEntity(name string) *TopologyOptions {
+	for _, t := range ch.opt.topology {
+		if t.Declare && (t.Name == name || !t.IsExchange && t.Name == "" && ch.declared[t] == name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// own replaces the entity of the topology with a copy, which the channel can change
+// without affecting the caller's options. Its declared name carries over.
+func (ch *Channel) own(t *TopologyOptions) *TopologyOptions {
+	clone := *t
+	clone.Bindings = slices.Clone(t.Bindings)
+	clone.StaleBindings = slices.Clone(t.StaleBindings)
+
+	// the slice may be the caller's as well
+	topology := make([]*TopologyOptions, len(ch.opt.topology))
+	for i, known := range ch.opt.topology {
+		if known == t {
+			known = &clone
+		}
+		topology[i] = known
+	}
+	ch.opt.topology = topology
+
+	if name, found := ch.declared[t]; found {
+		delete(ch.declared, t)
+		ch.declared[&clone] = name
+	}
+	if owner, found := ch.transient[t]; found {
+		delete(ch.transient, t)
+		ch.transient[&clone] = owner
+	}
+
+	return &clone
+}
+
+// applyBind runs the binding command on a local channel.
+func (ch *Channel) applyBind(t *TopologyOptions, name string, bind TopologyBind, add bool) error {
+	chLocal, err := ch.Connection().Channel()
+	if err != nil {
+		return err
+	}
+	defer chLocal.Close()
+
+	return applyBind(chLocal, t, name, bind, add)
+}
+
+// containsBind tells whether the binding is listed.
+func containsBind(binds []TopologyBind, bind TopologyBind) bool {
+	for _, b := range binds {
+		if sameBind(b, bind) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeBind returns the bindings without the given one.
+func removeBind(binds []TopologyBind, bind TopologyBind) []TopologyBind {
+	kept := binds[:0:0]
+	for _, b := range binds {
+		if !sameBind(b, bind) {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,57 @@
+package grabbit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+func TestBindKeepsCallerTopology(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	// exclusive: lost along the connection, then declared again with its bindings
+	entity := &TopologyOptions{Name: "bound", Exclusive: true, Declare: true, IsDestination: true}
+	topology := []*TopologyOptions{entity}
+	original := *entity
+
+	ch := NewChannel(conn, WithChannelDelay(testDelay), WithChannelTopology(topology))
+	defer ch.Close()
+	eventually(t, 5*time.Second, func() bool { return ch.Queue() == "bound" }, "channel not established")
+
+	routed := func(key string) func() bool {
+		return func() bool { return srv.Publish("amq.direct", key, amqp.Publishing{}) == 1 }
+	}
+
+	for _, key := range []string{"kept", "dropped"} {
+		if err := ch.Bind("bound", TopologyBind{Peer: "amq.direct", Key: key}); err != nil {
+			t.Fatalf("bind %s: %v", key, err)
+		}
+	}
+	if err := ch.Unbind("bound", TopologyBind{Peer: "amq.direct", Key: "dropped"}); err != nil {
+		t.Fatalf("unbind: %v", err)
+	}
+	if !reflect.DeepEqual(*entity, original) || topology[0] != entity {
+		t.Fatalf("caller topology changed: %+v", *entity)
+	}
+
+	defined, cancel := ch.Subscribe(EventsOfKind(EventDefineTopology))
+	defer cancel()
+	faults.Sever()
+	select {
+	case event := <-defined:
+		if event.Err.IsSet() {
+			t.Fatalf("topology not restored: %v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("topology not restored")
+	}
+	if !routed("kept")() {
+		t.Fatal("binding not restored")
+	}
+	if routed("dropped")() {
+		t.Fatal("removed binding restored")
+	}
+}
@@ -1,6 +1,7 @@
 package grabbit
 
 import (
+	"log/slog"
 	"time"
 	
 	amqp "github.com/oarkflow/amqp/amqp091"
@@ -17,7 +18,8 @@ func (ch *Channel) consumer() <-chan amqp.Delivery {
 			SourceName: ch.opt.name,
 			Kind:       EventQos,
 			Err:        SomeErrFromError(err, true),
-		}.raise(ch.opt.notifier)
+		}.raise(ch.notifier())
+		ch.log(ch.opt.logLevels.Errors, "qos failed", err)
 	}
 	// overwrite the passed queue to consume with the server assigned value
 	qName := ch.opt.implParams.ConsumerQueue
@@ -39,7 +41,8 @@ func (ch *Channel) consumer() <-chan amqp.Delivery {
 			SourceName: ch.opt.name,
 			Kind:       EventConsume,
 			Err:        SomeErrFromError(err, true),
-		}.raise(ch.opt.notifier)
+		}.raise(ch.notifier())
+		ch.log(ch.opt.logLevels.Errors, "consume failed", err, slog.String("queue", qName))
 	}
 	
 	return consumer
@@ -54,6 +57,11 @@ func (ch *Channel) consumer() <-chan amqp.Delivery {
 // Parameters:
 //   - consumer: a channel of amqp.Delivery for receiving messages.
 func (ch *Channel) gobble(consumer <-chan amqp.Delivery) {
+	if ch.opt.handler != nil {
+		ch.serve(consumer)
+		return
+	}
+
 	var props DeliveriesProperties
 	mustAck := !ch.opt.implParams.ConsumerAutoAck
 	messages := make([]DeliveryData, 0, ch.opt.implParams.PrefetchCount)
@@ -65,7 +73,7 @@ func (ch *Channel) gobble(consumer <-chan amqp.Delivery) {
 			if len(messages) != 0 {
 				// conn/chan are gone, cannot ACK/NAK anyways
 				mustAck = false
-				ch.opt.cbProcessMessages(&props, messages, mustAck, ch)
+				ch.process(&props, messages, mustAck)
 			}
 			return
 		case msg, ok := <-consumer: // notifiers data
@@ -74,10 +82,11 @@ func (ch *Channel) gobble(consumer <-chan amqp.Delivery) {
 				if len(messages) != 0 {
 					// conn/chan are gone, cannot ACK/NAK anyways
 					mustAck = false
-					ch.opt.cbProcessMessages(&props, messages, mustAck, ch)
+					ch.process(&props, messages, mustAck)
 				}
 				return
 			}
+			ch.inflate(&msg)
 			
 			// set props
 			if len(messages) == 0 {
@@ -89,7 +98,7 @@ func (ch *Channel) gobble(consumer <-chan amqp.Delivery) {
 			// process
 			if len(messages) == ch.opt.implParams.PrefetchCount {
 				if len(messages) != 0 {
-					ch.opt.cbProcessMessages(&props, messages, mustAck, ch)
+					ch.process(&props, messages, mustAck)
 				}
 				messages = make([]DeliveryData, 0, ch.opt.implParams.PrefetchCount)
 			}
@@ -98,7 +107,7 @@ func (ch *Channel) gobble(consumer <-chan amqp.Delivery) {
 			kind := EventDataExhausted
 			if len(messages) != 0 {
 				kind = EventDataPartial
-				ch.opt.cbProcessMessages(&props, messages, mustAck, ch)
+				ch.process(&props, messages, mustAck)
 				messages = make([]DeliveryData, 0, ch.opt.implParams.PrefetchCount)
 			}
 			
@@ -106,7 +115,15 @@ func (ch *Channel) gobble(consumer <-chan amqp.Delivery) {
 				SourceType: CliChannel,
 				SourceName: ch.opt.name,
 				Kind:       kind,
-			}.raise(ch.opt.notifier)
+			}.raise(ch.notifier())
+			ch.opt.metrics.prefetched(ch.opt.name, kind)
 		}
 	}
 }
+
+// process passes a batch of deliveries to the user processor, measuring it.
+func (ch *Channel) process(props *DeliveriesProperties, messages []DeliveryData, mustAck bool) {
+	started := time.Now()
+	ch.opt.cbProcessMessages(props, messages, mustAck, ch)
+	ch.opt.metrics.processed(ch.opt.name, len(messages), time.Since(started))
+}
@@ -26,7 +26,7 @@ func (ch *Channel) notifiers() PersistentNotifiers {
 	notifiers := PersistentNotifiers{}
 	
 	if ch.baseChan.super != nil {
-		notifiers.Closed = ch.baseChan.super.NotifyClose(make(chan *amqp.Error))
+		notifiers.Closed = ch.baseChan.super.NotifyClose(make(chan *amqp.Error, 1)) // room for the error raised while closing on demand
 		notifiers.Cancel = ch.baseChan.super.NotifyCancel(make(chan string))
 		
 		// these are publishers specific
@@ -41,7 +41,20 @@ func (ch *Channel) notifiers() PersistentNotifiers {
 					SourceName: ch.opt.name,
 					Kind:       EventConfirm,
 					Err:        SomeErrFromError(err, true),
-				}.raise(ch.opt.notifier)
+				}.raise(ch.notifier())
+				ch.log(ch.opt.logLevels.Errors, "confirm mode failed", err)
+			}
+		}
+		// the retry policy republishes confirmed, before any delivery
+		if ch.opt.retry != nil && !ch.opt.implParams.IsPublisher {
+			if err := ch.opt.retry.listen(ch.baseChan.super); err != nil {
+				Event{
+					SourceType: CliChannel,
+					SourceName: ch.opt.name,
+					Kind:       EventConfirm,
+					Err:        SomeErrFromError(err, true),
+				}.raise(ch.notifier())
+				ch.log(ch.opt.logLevels.Errors, "confirm mode failed", err)
 			}
 		}
 		// consumer actions
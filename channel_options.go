@@ -2,6 +2,7 @@ package grabbit
 
 import (
 	"context"
+	"log/slog"
 )
 
 // ChanUsageParameters embeds [PublisherUsageOptions] and [ConsumerUsageOptions].
@@ -14,19 +15,29 @@ type ChanUsageParameters struct {
 
 // ChannelOptions represents the options for configuring a channel.
 type ChannelOptions struct {
-	notifier          chan Event              // feedback channel
-	name              string                  // tag for this channel
-	delayer           DelayProvider           // how much to wait between re-attempts
-	cbDown            CallbackWhenDown        // callback on conn lost
-	cbUp              CallbackWhenUp          // callback when conn recovered
-	cbReconnect       CallbackWhenRecovering  // callback when recovering
-	cbNotifyPublish   CallbackNotifyPublish   // publish notification handler
-	cbNotifyReturn    CallbackNotifyReturn    // returned message notification handler
-	cbProcessMessages CallbackProcessMessages // user defined message processing routine
-	topology          []*TopologyOptions      // the _whole_ infrastructure involved as array of queues and exchanges
-	implParams        ChanUsageParameters     // implementation trigger for publishers or consumers
-	ctx               context.Context         // cancellation context
-	cancelCtx         context.CancelFunc      // aborts the reconnect loop
+	notifier            *eventBus               // feedback dispatcher
+	name                string                  // tag for this channel
+	delayer             DelayProvider           // how much to wait between re-attempts
+	cbDown              CallbackWhenDown        // callback on conn lost
+	cbUp                CallbackWhenUp          // callback when conn recovered
+	cbReconnect         CallbackWhenRecovering  // callback when recovering
+	cbNotifyPublish     CallbackNotifyPublish   // publish notification handler
+	cbNotifyReturn      CallbackNotifyReturn    // returned message notification handler
+	cbProcessMessages   CallbackProcessMessages // user defined message processing routine
+	handler             DeliveryHandler         // user defined per message processing routine (optional)
+	retry               *retrier                // consumer's retry policy (optional)
+	topology            []*TopologyOptions      // the _whole_ infrastructure involved as array of queues and exchanges
+	implParams          ChanUsageParameters     // implementation trigger for publishers or consumers
+	ctx                 context.Context         // cancellation context
+	cancelCtx           context.CancelFunc      // aborts the reconnect loop
+	outbox              *outbox                 // publisher's unconfirmed messages (optional)
+	spool               *spool                  // publisher's on-disk buffer (optional)
+	compressors         []Compressor            // consumer's custom content decoders (optional)
+	consumeInterceptors []ConsumeInterceptor    // wrappers of the message processing (optional)
+	tracer              Tracer                  // consumer's processing spans (optional)
+	metrics             *Metrics                // registry fed by the channel (optional)
+	logger              *slog.Logger            // structured logging (optional)
+	logLevels           LogLevels               // level of each logging category
 }
 
 // OnChannelDown returns a function that sets the callback function to be called when the channel is down.
@@ -99,9 +110,14 @@ func WithChannelName(name string) func(options *ChannelOptions) {
 
 // WithChannelNotification provides an application defined
 // [Event] receiver to handle various alerts about the channel status.
+// They are no longer dispatched to the connection subscribers.
+// Events not fitting into ch are dropped. The dispatching ends, closing the
+// channel's own subscriptions (see [Channel.Subscribe]) but not ch, once the
+// channel context is done.
 func WithChannelNotification(ch chan Event) func(options *ChannelOptions) {
 	return func(options *ChannelOptions) {
-		options.notifier = ch
+		options.notifier = newEventBus()
+		options.notifier.attach(ch)
 	}
 }
 
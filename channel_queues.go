@@ -0,0 +1,154 @@
+package grabbit
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// redeclareQueue declares a queue of the topology. A server-named queue declared
+// before keeps its name while it still exists, ex: an exclusive queue after
+// a channel-only recovery; otherwise the server assigns a new one.
+func (ch *Channel) redeclareQueue(chLocal *amqp.Channel, t *TopologyOptions) (amqp.Queue, error) {
+	if previous := ch.declared[t]; t.Name == "" && previous != "" {
+		// a failed passive declaration closes the channel, hence a probe of its own
+		if probe, err := ch.Connection().Channel(); err == nil {
+			queue, err := probe.QueueDeclarePassive(previous, t.Durable, t.AutoDelete, t.Exclusive, false, t.Args)
+			_ = probe.Close()
+			if err == nil {
+				return queue, bindTopology(chLocal, t, queue.Name)
+			}
+		}
+	}
+
+	return declareQueue(chLocal, t)
+}
+
+// redeclared records the name the queue was declared with. A new name is propagated
+// to the consumer and raised as EventQueueRenamed, along the former one.
+func (ch *Channel) redeclared(t *TopologyOptions, name string) {
+	previous, known := ch.declared[t]
+	ch.declared[t] = name
+	if _, found := ch.transient[t]; found {
+		ch.transient[t] = ch.baseConnection()
+	}
+	if !known || previous == name {
+		return
+	}
+
+	if ch.opt.implParams.ConsumerQueue == previous {
+		ch.opt.implParams.ConsumerQueue = name
+	}
+	Event{
+		SourceType: CliChannel,
+		SourceName: ch.opt.name,
+		TargetName: name,
+		OldName:    previous,
+		Kind:       EventQueueRenamed,
+	}.raise(ch.notifier())
+	ch.log(ch.opt.logLevels.Recovery, "queue redeclared with a new name", nil,
+		slog.String("queue", name), slog.String("old_name", previous))
+}
+
+// remember adds a queue declared at runtime to the topology, so that the recovery
+// declares it again; a queue already part of the topology, by entry or by name,
+// only gets its name recorded. The topology keeps a copy of the caller's options.
+func (ch *Channel) remember(t *TopologyOptions, name string) {
+	ch.topoMu.Lock()
+	defer ch.topoMu.Unlock()
+
+	for _, known := range ch.opt.topology {
+		if known == t || known.Declare && !known.IsExchange && (known.Name == name || known.Name == "" && ch.declared[known] == name) {
+			if !known.Declare {
+				known = ch.own(known)
+				known.Declare = true
+			}
+			ch.declared[known] = name
+			return
+		}
+	}
+
+	clone := *t
+	clone.Declare = true
+	clone.Bindings = slices.Clone(t.Bindings)
+	clone.StaleBindings = slices.Clone(t.StaleBindings)
+
+	topology := ch.opt.topology
+	ch.opt.topology = append(topology[:len(topology):len(topology)], &clone)
+	ch.declared[&clone] = name
+	if t.Name == "" && (t.Exclusive || t.AutoDelete) {
+		ch.transient[&clone] = ch.baseConnection()
+	}
+}
+
+// deleted tells whether a temporary queue declared at runtime is gone while the connection
+// it was declared on lasts, ex: auto-deleted or deleted by another channel; unlike a queue
+// lost along the connection, it is not declared again. The consumed queue is always kept.
+func (ch *Channel) deleted(t *TopologyOptions) bool {
+	owner, found := ch.transient[t]
+	previous := ch.declared[t]
+	if !found || owner != ch.baseConnection() || previous == ch.opt.implParams.ConsumerQueue {
+		return false
+	}
+
+	// a failed passive declaration closes the channel, hence a probe of its own
+	probe, err := ch.Connection().Channel()
+	if err != nil {
+		return false
+	}
+	_, err = probe.QueueDeclarePassive(previous, t.Durable, t.AutoDelete, t.Exclusive, false, t.Args)
+	_ = probe.Close()
+
+	var amqpErr *amqp.Error
+	return errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound
+}
+
+// drop removes a deleted temporary queue from the topology.
+func (ch *Channel) drop(t *TopologyOptions) {
+	ch.opt.topology = slices.DeleteFunc(slices.Clone(ch.opt.topology), func(known *TopologyOptions) bool {
+		return known == t
+	})
+	ch.log(ch.opt.logLevels.Topology, "deleted queue no longer declared", nil, slog.String("queue", ch.declared[t]))
+	delete(ch.declared, t)
+	delete(ch.transient, t)
+}
+
+// baseConnection returns the current base connection, telling apart the queues
+// lost along a former connection.
+func (ch *Channel) baseConnection() *amqp.Connection {
+	conn := &ch.Connection().baseConn
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+
+	return conn.super
+}
+
+// forget removes a deleted queue from the topology.
+func (ch *Channel) forget(name string) {
+	ch.topoMu.Lock()
+	defer ch.topoMu.Unlock()
+
+	topology := make([]*TopologyOptions, 0, len(ch.opt.topology))
+	for _, t := range ch.opt.topology {
+		if !t.IsExchange && (t.Name == name || t.Name == "" && ch.declared[t] == name) {
+			delete(ch.declared, t)
+			delete(ch.transient, t)
+			continue
+		}
+		topology = append(topology, t)
+	}
+	ch.opt.topology = topology
+}
+
+// ownsExclusive tells whether the topology holds an exclusive queue, which
+// lives and dies with the connection it was declared on.
+func (ch *Channel) ownsExclusive() bool {
+	ch.topoMu.Lock()
+	defer ch.topoMu.Unlock()
+
+	return slices.ContainsFunc(ch.opt.topology, func(t *TopologyOptions) bool {
+		return t.Exclusive && !t.IsExchange
+	})
+}
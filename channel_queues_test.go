@@ -0,0 +1,79 @@
+package grabbit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRuntimeQueuesRecovery(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	ch := NewChannel(conn, WithChannelDelay(testDelay))
+	defer ch.Close()
+	eventually(t, 5*time.Second, func() bool { return !ch.IsClosed() }, "channel not established")
+
+	opt := &TopologyOptions{Exclusive: true}
+	kept, err := ch.QueueDeclareWithTopology(opt)
+	if err != nil {
+		t.Fatalf("declare: %v", err)
+	}
+	if opt.Declare {
+		t.Fatal("caller options changed")
+	}
+	// declared again by the server-assigned name
+	if _, err := ch.QueueDeclare(kept.Name, false, false, true, false, nil); err != nil {
+		t.Fatalf("redeclare: %v", err)
+	}
+	gone, err := ch.QueueDeclare("", false, true, false, false, nil)
+	if err != nil {
+		t.Fatalf("declare: %v", err)
+	}
+	if n := len(runtimeTopology(ch)); n != 2 {
+		t.Fatalf("%d queues remembered", n)
+	}
+
+	// auto-deleted meanwhile, then a channel-only recovery
+	srv.DeleteQueue(gone.Name)
+	ups, cancel := ch.Subscribe(EventsOfKind(EventUp))
+	defer cancel()
+	if _, err := ch.QueueDeclarePassive("missing", false, false, false, false, nil); err == nil {
+		t.Fatal("missing queue found")
+	}
+	select {
+	case <-ups:
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not recovered")
+	}
+	eventually(t, 5*time.Second, func() bool { return len(runtimeTopology(ch)) == 1 }, "deleted queue remembered")
+	if _, found := srv.Queue(kept.Name); !found {
+		t.Fatal("exclusive queue lost")
+	}
+
+	// lost along the connection, declared again with a new name
+	renamed, cancel := ch.Subscribe(EventsOfKind(EventQueueRenamed))
+	defer cancel()
+	faults.Sever()
+	select {
+	case event := <-renamed:
+		if event.OldName != kept.Name {
+			t.Fatalf("renamed %v", event)
+		}
+		if _, found := srv.Queue(event.TargetName); !found {
+			t.Fatalf("queue %s not declared", event.TargetName)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("exclusive queue not declared again")
+	}
+	if n := len(runtimeTopology(ch)); n != 1 {
+		t.Fatalf("%d queues remembered", n)
+	}
+}
+
+// runtimeTopology returns the topology the channel declares on recovery.
+func runtimeTopology(ch *Channel) []*TopologyOptions {
+	ch.topoMu.Lock()
+	defer ch.topoMu.Unlock()
+
+	return ch.opt.topology
+}
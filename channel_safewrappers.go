@@ -2,6 +2,7 @@ package grabbit
 
 import (
 	"context"
+	"time"
 	
 	amqp "github.com/oarkflow/amqp/amqp091"
 )
@@ -113,7 +114,12 @@ func (ch *Channel) PublishWithContext(ctx context.Context, exchange, key string,
 	defer ch.baseChan.mu.Unlock()
 	
 	if ch.baseChan.super != nil {
-		return ch.baseChan.super.PublishWithContext(ctx, exchange, key, mandatory, immediate, msg)
+		sent := time.Now()
+		deferred, err := ch.baseChan.super.PublishWithDeferredConfirmWithContext(ctx, exchange, key, mandatory, immediate, msg)
+		if err == nil {
+			ch.opt.metrics.published(ch, deliveryTag(deferred), sent, ch.opt.implParams.IsPublisher)
+		}
+		return err
 	}
 	return amqp.ErrClosed
 }
@@ -124,7 +130,12 @@ func (ch *Channel) PublishWithDeferredConfirmWithContext(ctx context.Context, ex
 	defer ch.baseChan.mu.Unlock()
 	
 	if ch.baseChan.super != nil {
-		return ch.baseChan.super.PublishWithDeferredConfirmWithContext(ctx, exchange, key, mandatory, immediate, msg)
+		sent := time.Now()
+		deferred, err := ch.baseChan.super.PublishWithDeferredConfirmWithContext(ctx, exchange, key, mandatory, immediate, msg)
+		if err == nil {
+			ch.opt.metrics.published(ch, deliveryTag(deferred), sent, ch.opt.implParams.IsPublisher)
+		}
+		return deferred, err
 	}
 	return nil, amqp.ErrClosed
 }
@@ -152,26 +163,35 @@ func (ch *Channel) GetNextPublishSeqNo() uint64 {
 }
 
 // QueueDelete safely wraps the base channel QueueDelete.
+// The queue is no longer declared on recovery.
 func (ch *Channel) QueueDelete(name string, ifUnused, ifEmpty, noWait bool) (int, error) {
 	ch.baseChan.mu.Lock()
-	defer ch.baseChan.mu.Unlock()
+	if ch.baseChan.super == nil {
+		ch.baseChan.mu.Unlock()
+		return 0, amqp.ErrClosed
+	}
+	count, err := ch.baseChan.super.QueueDelete(name, ifUnused, ifEmpty, noWait)
+	ch.baseChan.mu.Unlock()
 	
-	if ch.baseChan.super != nil {
-		return ch.baseChan.super.QueueDelete(name, ifUnused, ifEmpty, noWait)
+	if err == nil {
+		ch.forget(name)
 	}
-	return 0, amqp.ErrClosed
+	return count, err
 }
 
 // QueueDeclare safely wraps the base channel QueueDeclare.
 // Prefer using the [QueueDeclareWithTopology] instead; that also supports bindings, see [TopologyOptions]
+// The queue is remembered and declared again on recovery, when lost along the connection.
 func (ch *Channel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
-	ch.baseChan.mu.Lock()
-	defer ch.baseChan.mu.Unlock()
-	
-	if ch.baseChan.super != nil {
-		return ch.baseChan.super.QueueDeclare(name, durable, autoDelete, exclusive, noWait, args)
-	}
-	return amqp.Queue{}, amqp.ErrClosed
+	return ch.QueueDeclareWithTopology(&TopologyOptions{
+		Name:       name,
+		Durable:    durable,
+		AutoDelete: autoDelete,
+		Exclusive:  exclusive,
+		NoWait:     noWait,
+		Args:       args,
+		Declare:    true,
+	})
 }
 
 // ExchangeDelete safely wraps the base channel ExchangeDelete.
@@ -198,15 +218,22 @@ func (ch *Channel) ExchangeDeclare(name, kind string, durable, autoDelete, inter
 }
 
 // QueueDeclareWithTopology safely declares a desired queue as described in the parameter;
-// see [TopologyOptions]
+// see [TopologyOptions]. The queue is remembered and declared again on recovery, along its bindings,
+// when lost along the connection; a server-named queue raises EventQueueRenamed.
+// The channel remembers a copy of the options, leaving the caller's ones as they are.
 func (ch *Channel) QueueDeclareWithTopology(t *TopologyOptions) (amqp.Queue, error) {
 	ch.baseChan.mu.Lock()
-	defer ch.baseChan.mu.Unlock()
+	if ch.baseChan.super == nil {
+		ch.baseChan.mu.Unlock()
+		return amqp.Queue{}, amqp.ErrClosed
+	}
+	queue, err := declareQueue(ch.baseChan.super, t)
+	ch.baseChan.mu.Unlock()
 	
-	if ch.baseChan.super != nil {
-		return declareQueue(ch.baseChan.super, t)
+	if err == nil && !t.Passive {
+		ch.remember(t, queue.Name)
 	}
-	return amqp.Queue{}, amqp.ErrClosed
+	return queue, err
 }
 
 // ExchangeDeclareWithTopology safely declares a desired exchange as described in the parameter;
@@ -0,0 +1,87 @@
+package grabbit
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// newCountingConsumer consumes the queue of the topology, counting the handled messages.
+func newCountingConsumer(t *testing.T, conn *Connection, queue string, topology []*TopologyOptions) (*Consumer, *atomic.Int32) {
+	t.Helper()
+
+	handled := &atomic.Int32{}
+	opt := DefaultConsumerOptions()
+	opt.WithQueue(queue).WithHandler(func(ctx context.Context, msg Delivery) Result {
+		handled.Add(1)
+		return Ack
+	})
+	consumer := NewConsumer(conn, opt, WithChannelDelay(testDelay), WithChannelTopology(topology))
+	t.Cleanup(func() { consumer.Close() })
+
+	return consumer, handled
+}
+
+func TestConsumerResumesAfterConnectionLoss(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	_, handled := newCountingConsumer(t, conn, "jobs", testQueue("jobs"))
+
+	consuming := func() bool {
+		info, _ := srv.Queue("jobs")
+		return info.Consumers == 1
+	}
+	eventually(t, 5*time.Second, consuming, "consumer not started")
+	srv.Publish("", "jobs", amqp.Publishing{Body: []byte("before")})
+	eventually(t, 5*time.Second, func() bool { return handled.Load() == 1 }, "message not handled")
+
+	events := connectionEvents(t, conn)
+	faults.Sever()
+	expectEvents(t, events, EventDown, EventUp)
+	eventually(t, 5*time.Second, consuming, "consumer not resumed")
+
+	srv.Publish("", "jobs", amqp.Publishing{Body: []byte("after")})
+	eventually(t, 5*time.Second, func() bool { return handled.Load() == 2 }, "message not handled after recovery")
+	if info, _ := srv.Queue("jobs"); info.Ready+info.Unacked != 0 {
+		t.Fatalf("queue not settled: %+v", info)
+	}
+}
+
+func TestConsumerFollowsServerNamedQueue(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	consumer, handled := newCountingConsumer(t, conn, "", []*TopologyOptions{{
+		Exclusive: true, Declare: true, IsDestination: true,
+		Bind: TopologyBind{Enabled: true, Peer: "amq.fanout"},
+	}})
+
+	eventually(t, 5*time.Second, func() bool {
+		info, _ := srv.Queue(consumer.Channel().Queue())
+		return info.Consumers == 1
+	}, "consumer not started")
+	renamed, cancel := consumer.Channel().Subscribe(EventsOfKind(EventQueueRenamed))
+	defer cancel()
+	previous := consumer.Channel().Queue()
+
+	faults.Sever()
+	select {
+	case event := <-renamed:
+		if event.OldName != previous || event.TargetName == previous {
+			t.Fatalf("renamed %v", event)
+		}
+		eventually(t, 5*time.Second, func() bool {
+			info, _ := srv.Queue(event.TargetName)
+			return info.Consumers == 1
+		}, "consumer not moved to %s", event.TargetName)
+	case <-time.After(5 * time.Second):
+		t.Fatal("queue not renamed")
+	}
+
+	if n := srv.Publish("amq.fanout", "", amqp.Publishing{}); n != 1 {
+		t.Fatalf("routed to %d queues", n)
+	}
+	eventually(t, 5*time.Second, func() bool { return handled.Load() == 1 }, "message not handled after recovery")
+}
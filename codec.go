@@ -0,0 +1,203 @@
+package grabbit
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrUnknownContentType is reported to the [PoisonHandler] for deliveries
+// whose ContentType matches none of the configured codecs.
+var ErrUnknownContentType = errors.New("no codec for the content type")
+
+// ErrUnknownType is reported to the [PoisonHandler] for deliveries
+// whose Type property matches none of the expected types.
+var ErrUnknownType = errors.New("unexpected message type")
+
+// Codec serializes the typed messages (see [TypedPublisher], [TypedConsumer]).
+type Codec interface {
+	ContentType() string                // MIME type set on the published messages
+	Marshal(v any) ([]byte, error)      // encodes a message body
+	Unmarshal(data []byte, v any) error // decodes a message body into the pointer v
+}
+
+// JSONCodec encodes messages with encoding/json.
+type JSONCodec struct{}
+
+// ContentType implements the [Codec] interface.
+func (JSONCodec) ContentType() string { return "application/json" }
+
+// Marshal implements the [Codec] interface.
+func (JSONCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal implements the [Codec] interface.
+func (JSONCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// GobCodec encodes messages with encoding/gob. Every message carries its own type
+// description, so it is larger than a stream encoding but decodable on its own.
+type GobCodec struct{}
+
+// ContentType implements the [Codec] interface.
+func (GobCodec) ContentType() string { return "application/x-gob" }
+
+// Marshal implements the [Codec] interface.
+func (GobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal implements the [Codec] interface.
+func (GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// PoisonHandler defines a function type dealing with the deliveries which cannot be
+// decoded (unknown content type or message type, corrupted body).
+// The returned [Result] settles the delivery.
+type PoisonHandler func(ctx context.Context, msg Delivery, err error) Result
+
+// defaultPoisonHandler discards the undecodable deliveries, so they reach the
+// dead letter exchange of the queue, if any.
+func defaultPoisonHandler(ctx context.Context, msg Delivery, err error) Result {
+	return NackDiscard
+}
+
+// CodecOptions defines how the typed publishers and consumers serialize the messages.
+type CodecOptions struct {
+	Codecs   []Codec       // the first one encodes; all decode, selected by the ContentType
+	TypeName string        // Type property of the messages; defaults to the Go type name
+	Poison   PoisonHandler // undecodable deliveries; defaults to discarding them
+}
+
+// DefaultCodecOptions uses JSON and the Go type names.
+func DefaultCodecOptions() CodecOptions {
+	return CodecOptions{
+		Codecs: []Codec{JSONCodec{}},
+		Poison: defaultPoisonHandler,
+	}
+}
+
+// WithCodecs sets the codecs; the first one is used for encoding.
+func (opt *CodecOptions) WithCodecs(codecs ...Codec) *CodecOptions {
+	opt.Codecs = codecs
+	return opt
+}
+
+// WithTypeName overrides the Type property set on publishing and expected on receiving.
+func (opt *CodecOptions) WithTypeName(name string) *CodecOptions {
+	opt.TypeName = name
+	return opt
+}
+
+// WithPoison sets the handler of the undecodable deliveries.
+func (opt *CodecOptions) WithPoison(poison PoisonHandler) *CodecOptions {
+	opt.Poison = poison
+	return opt
+}
+
+// encoder returns the codec used for publishing.
+func (opt *CodecOptions) encoder() Codec {
+	if len(opt.Codecs) == 0 {
+		return JSONCodec{}
+	}
+	return opt.Codecs[0]
+}
+
+// decoder selects the codec by content type (parameters ignored).
+// An empty content type selects the encoding codec.
+func (opt *CodecOptions) decoder(contentType string) (Codec, error) {
+	if contentType == "" {
+		return opt.encoder(), nil
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+
+	for _, codec := range opt.Codecs {
+		if strings.EqualFold(codec.ContentType(), mediaType) {
+			return codec, nil
+		}
+	}
+	if len(opt.Codecs) == 0 && strings.EqualFold(JSONCodec{}.ContentType(), mediaType) {
+		return JSONCodec{}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownContentType, contentType)
+}
+
+// poison settles an undecodable delivery.
+func (opt *CodecOptions) poison(ctx context.Context, msg Delivery, err error) Result {
+	if opt.Poison == nil {
+		return defaultPoisonHandler(ctx, msg, err)
+	}
+	return opt.Poison(ctx, msg, err)
+}
+
+// typeNameOf returns the default Type property of the messages of type T.
+func typeNameOf[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
+
+// decodeAs decodes the delivery body into a T using the codec matching its ContentType.
+func decodeAs[T any](opt *CodecOptions, msg Delivery) (T, error) {
+	var value T
+
+	codec, err := opt.decoder(msg.ContentType)
+	if err != nil {
+		return value, err
+	}
+	if err := codec.Unmarshal(msg.Body, &value); err != nil {
+		return value, err
+	}
+
+	return value, nil
+}
+
+// TypedHandler defines a function type processing decoded messages.
+// The raw delivery is passed along for its properties.
+type TypedHandler[T any] func(ctx context.Context, value T, msg Delivery) Result
+
+// TypeDispatcher routes the deliveries to typed handlers based on their Type property,
+// decoding them with the codec matching their ContentType. Register the handlers
+// with [Route] then pass [TypeDispatcher.Handle] to [ConsumerOptions.WithHandler].
+type TypeDispatcher struct {
+	opt    CodecOptions
+	routes map[string]DeliveryHandler
+}
+
+// NewTypeDispatcher creates a dispatcher using the codecs and the poison handler of opt.
+func NewTypeDispatcher(opt CodecOptions) *TypeDispatcher {
+	return &TypeDispatcher{opt: opt, routes: make(map[string]DeliveryHandler)}
+}
+
+// Route registers the handler of the messages of type T, identified by typeName
+// (empty means the Go type name of T). Not safe for use while consuming.
+func Route[T any](d *TypeDispatcher, typeName string, handler TypedHandler[T]) {
+	if typeName == "" {
+		typeName = typeNameOf[T]()
+	}
+
+	d.routes[typeName] = func(ctx context.Context, msg Delivery) Result {
+		value, err := decodeAs[T](&d.opt, msg)
+		if err != nil {
+			return d.opt.poison(ctx, msg, err)
+		}
+		return handler(ctx, value, msg)
+	}
+}
+
+// Handle implements a [DeliveryHandler] dispatching by message type.
+func (d *TypeDispatcher) Handle(ctx context.Context, msg Delivery) Result {
+	route, ok := d.routes[msg.Type]
+	if !ok {
+		return d.opt.poison(ctx, msg, fmt.Errorf("%w: %q", ErrUnknownType, msg.Type))
+	}
+	return route(ctx, msg)
+}
@@ -0,0 +1,566 @@
+package grabbit
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ProtobufCodec encodes messages in the protocol buffers wire format without
+// depending on a protobuf runtime.
+//
+// Types implementing Marshal() ([]byte, error) and Unmarshal([]byte) error (ex: gogo or
+// vtprotobuf generated code) are delegated to. Otherwise the codec works by reflection
+// over the `protobuf:"..."` struct tags emitted by protoc-gen-go (and easily written by hand):
+// scalars, enums, strings, bytes, nested messages, repeated fields (packed or not)
+// and maps are supported; oneof fields and groups are not.
+type ProtobufCodec struct{}
+
+// protoMarshaler is implemented by the messages able to encode themselves.
+type protoMarshaler interface {
+	Marshal() ([]byte, error)
+}
+
+// protoUnmarshaler is implemented by the messages able to decode themselves.
+type protoUnmarshaler interface {
+	Unmarshal(data []byte) error
+}
+
+// errProtoTruncated signals a malformed wire message.
+var errProtoTruncated = errors.New("protobuf: truncated message")
+
+// ContentType implements the [Codec] interface.
+func (ProtobufCodec) ContentType() string { return "application/x-protobuf" }
+
+// Marshal implements the [Codec] interface.
+func (ProtobufCodec) Marshal(v any) ([]byte, error) {
+	if m, ok := v.(protoMarshaler); ok {
+		return m.Marshal()
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("protobuf: cannot encode %T, not a message", v)
+	}
+
+	return appendMessage(nil, rv)
+}
+
+// Unmarshal implements the [Codec] interface.
+func (ProtobufCodec) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(protoUnmarshaler); ok {
+		return m.Unmarshal(data)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("protobuf: cannot decode into %T, not a pointer", v)
+	}
+	rv = rv.Elem()
+	// pointer to pointer to message, as used by the typed consumers with T = *Message
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		if m, ok := rv.Interface().(protoUnmarshaler); ok {
+			return m.Unmarshal(data)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("protobuf: cannot decode into %T, not a message", v)
+	}
+
+	rv.Set(reflect.Zero(rv.Type()))
+	return decodeMessage(data, rv)
+}
+
+// protoField describes a tagged message field.
+type protoField struct {
+	index    int         // struct field index
+	num      uint64      // field number
+	kind     string      // varint, zigzag32, zigzag64, fixed32, fixed64 or bytes
+	repeated bool        // rep
+	packed   bool        // packed encoding of repeated scalars
+	key, val *protoField // map entry fields
+	oneof    bool        // unsupported oneof wrapper
+}
+
+// protoMessage describes the tagged fields of a struct type.
+type protoMessage struct {
+	fields []*protoField
+	byNum  map[uint64]*protoField
+}
+
+// protoMessages caches the struct descriptions by type.
+var protoMessages sync.Map
+
+// wireType returns the wire type of the field's (single) values.
+func (f *protoField) wireType() uint64 {
+	switch f.kind {
+	case "varint", "zigzag32", "zigzag64":
+		return 0
+	case "fixed64":
+		return 1
+	case "fixed32":
+		return 5
+	}
+	return 2
+}
+
+// parseProtoTag parses a `protobuf:"kind,num,label,..."` tag.
+func parseProtoTag(tag string) (*protoField, error) {
+	parts := strings.Split(tag, ",")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("protobuf: malformed tag %q", tag)
+	}
+	num, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("protobuf: malformed tag %q", tag)
+	}
+
+	f := &protoField{num: num, kind: parts[0]}
+	switch f.kind {
+	case "varint", "zigzag32", "zigzag64", "fixed32", "fixed64", "bytes":
+	default:
+		return nil, fmt.Errorf("protobuf: unsupported encoding %q", f.kind)
+	}
+	proto3 := false
+	for _, part := range parts[2:] {
+		switch part {
+		case "rep":
+			f.repeated = true
+		case "packed":
+			f.packed = true
+		case "proto3":
+			proto3 = true
+		}
+	}
+	// proto3 packs the repeated scalars by default
+	f.packed = f.repeated && f.wireType() != 2 && (f.packed || proto3)
+
+	return f, nil
+}
+
+// protoMessageOf returns the cached description of a struct type.
+func protoMessageOf(t reflect.Type) (*protoMessage, error) {
+	if cached, ok := protoMessages.Load(t); ok {
+		return cached.(*protoMessage), nil
+	}
+
+	m := &protoMessage{byNum: make(map[uint64]*protoField)}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if _, ok := sf.Tag.Lookup("protobuf_oneof"); ok {
+			m.fields = append(m.fields, &protoField{index: i, oneof: true})
+			continue
+		}
+		tag, ok := sf.Tag.Lookup("protobuf")
+		if !ok || !sf.IsExported() {
+			continue
+		}
+
+		f, err := parseProtoTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		f.index = i
+		if sf.Type.Kind() == reflect.Map {
+			if f.key, err = parseProtoTag(sf.Tag.Get("protobuf_key")); err != nil {
+				return nil, err
+			}
+			if f.val, err = parseProtoTag(sf.Tag.Get("protobuf_val")); err != nil {
+				return nil, err
+			}
+		}
+
+		m.fields = append(m.fields, f)
+		m.byNum[f.num] = f
+	}
+
+	protoMessages.Store(t, m)
+	return m, nil
+}
+
+// appendTag appends the key of a field.
+func appendTag(b []byte, num, wireType uint64) []byte {
+	return binary.AppendUvarint(b, num<<3|wireType)
+}
+
+// appendMessage appends the encoding of all the fields of a struct.
+func appendMessage(b []byte, v reflect.Value) ([]byte, error) {
+	m, err := protoMessageOf(v.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range m.fields {
+		fv := v.Field(f.index)
+
+		switch {
+		case f.oneof:
+			if !fv.IsNil() {
+				return nil, fmt.Errorf("protobuf: oneof field %s not supported", v.Type().Field(f.index).Name)
+			}
+		case f.key != nil:
+			iter := fv.MapRange()
+			for iter.Next() {
+				entry, err := appendField(nil, f.key, iter.Key(), true)
+				if err != nil {
+					return nil, err
+				}
+				if entry, err = appendField(entry, f.val, iter.Value(), true); err != nil {
+					return nil, err
+				}
+				b = appendTag(b, f.num, 2)
+				b = binary.AppendUvarint(b, uint64(len(entry)))
+				b = append(b, entry...)
+			}
+		case f.repeated && fv.Kind() == reflect.Slice:
+			if fv.Len() == 0 {
+				continue
+			}
+			if f.packed {
+				var payload []byte
+				for i := 0; i < fv.Len(); i++ {
+					if payload, err = appendScalar(payload, f.kind, fv.Index(i)); err != nil {
+						return nil, err
+					}
+				}
+				b = appendTag(b, f.num, 2)
+				b = binary.AppendUvarint(b, uint64(len(payload)))
+				b = append(b, payload...)
+				continue
+			}
+			for i := 0; i < fv.Len(); i++ {
+				if b, err = appendField(b, f, fv.Index(i), true); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			if b, err = appendField(b, f, fv, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return b, nil
+}
+
+// appendField appends a single value with its key. Zero values are
+// skipped unless forced (repeated and map entries, explicit optional).
+func appendField(b []byte, f *protoField, v reflect.Value, force bool) ([]byte, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return b, nil
+		}
+		v, force = v.Elem(), true
+	}
+
+	if f.wireType() != 2 {
+		if !force && v.IsZero() {
+			return b, nil
+		}
+		b = appendTag(b, f.num, f.wireType())
+		return appendScalar(b, f.kind, v)
+	}
+
+	var payload []byte
+	switch {
+	case v.Kind() == reflect.String:
+		if !force && v.Len() == 0 {
+			return b, nil
+		}
+		payload = []byte(v.String())
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		if !force && v.Len() == 0 {
+			return b, nil
+		}
+		payload = v.Bytes()
+	case v.Kind() == reflect.Struct:
+		var err error
+		if payload, err = appendMessage(nil, v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("protobuf: cannot encode %s as bytes", v.Type())
+	}
+
+	b = appendTag(b, f.num, 2)
+	b = binary.AppendUvarint(b, uint64(len(payload)))
+	return append(b, payload...), nil
+}
+
+// appendScalar appends a varint or fixed size value, without key.
+func appendScalar(b []byte, kind string, v reflect.Value) ([]byte, error) {
+	var bits uint64
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			bits = 1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits = uint64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		bits = v.Uint()
+	case reflect.Float32:
+		bits = uint64(math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		bits = math.Float64bits(v.Float())
+	default:
+		return nil, fmt.Errorf("protobuf: cannot encode %s as %s", v.Type(), kind)
+	}
+
+	switch kind {
+	case "varint":
+		return binary.AppendUvarint(b, bits), nil
+	case "zigzag32":
+		x := int32(bits)
+		return binary.AppendUvarint(b, uint64(uint32(x<<1)^uint32(x>>31))), nil
+	case "zigzag64":
+		x := int64(bits)
+		return binary.AppendUvarint(b, uint64(x<<1)^uint64(x>>63)), nil
+	case "fixed32":
+		return binary.LittleEndian.AppendUint32(b, uint32(bits)), nil
+	case "fixed64":
+		return binary.LittleEndian.AppendUint64(b, bits), nil
+	}
+
+	return nil, fmt.Errorf("protobuf: cannot encode %s as %s", v.Type(), kind)
+}
+
+// decodeMessage merges the wire message into the struct v.
+func decodeMessage(b []byte, v reflect.Value) error {
+	m, err := protoMessageOf(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for len(b) != 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		b = b[n:]
+		num, wireType := key>>3, key&7
+
+		f, ok := m.byNum[num]
+		if !ok {
+			if n, err = skipField(b, wireType); err != nil {
+				return err
+			}
+			b = b[n:]
+			continue
+		}
+		fv := v.Field(f.index)
+
+		switch {
+		case f.key != nil:
+			n, err = decodeMapEntry(b, wireType, f, fv)
+		case f.repeated && fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 ||
+			f.repeated && fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Slice:
+			n, err = decodeRepeated(b, wireType, f, fv)
+		default:
+			n, err = decodeValue(b, wireType, f, fv)
+		}
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+
+	return nil
+}
+
+// decodeRepeated appends one element, or a packed run of elements, to the slice fv.
+func decodeRepeated(b []byte, wireType uint64, f *protoField, fv reflect.Value) (int, error) {
+	elemType := fv.Type().Elem()
+
+	if wireType == 2 && f.wireType() != 2 {
+		payload, n, err := readBytes(b)
+		if err != nil {
+			return 0, err
+		}
+		for len(payload) != 0 {
+			elem := reflect.New(elemType).Elem()
+			used, err := decodeValue(payload, f.wireType(), f, elem)
+			if err != nil {
+				return 0, err
+			}
+			fv.Set(reflect.Append(fv, elem))
+			payload = payload[used:]
+		}
+		return n, nil
+	}
+
+	elem := reflect.New(elemType).Elem()
+	n, err := decodeValue(b, wireType, f, elem)
+	if err != nil {
+		return 0, err
+	}
+	fv.Set(reflect.Append(fv, elem))
+
+	return n, nil
+}
+
+// decodeMapEntry decodes a key/value entry into the map fv.
+func decodeMapEntry(b []byte, wireType uint64, f *protoField, fv reflect.Value) (int, error) {
+	if wireType != 2 {
+		return 0, fmt.Errorf("protobuf: map field %d with wire type %d", f.num, wireType)
+	}
+	entry, n, err := readBytes(b)
+	if err != nil {
+		return 0, err
+	}
+
+	key := reflect.New(fv.Type().Key()).Elem()
+	val := reflect.New(fv.Type().Elem()).Elem()
+	for len(entry) != 0 {
+		tag, used := binary.Uvarint(entry)
+		if used <= 0 {
+			return 0, errProtoTruncated
+		}
+		entry = entry[used:]
+
+		switch tag >> 3 {
+		case 1:
+			used, err = decodeValue(entry, tag&7, f.key, key)
+		case 2:
+			used, err = decodeValue(entry, tag&7, f.val, val)
+		default:
+			used, err = skipField(entry, tag&7)
+		}
+		if err != nil {
+			return 0, err
+		}
+		entry = entry[used:]
+	}
+
+	if fv.IsNil() {
+		fv.Set(reflect.MakeMap(fv.Type()))
+	}
+	fv.SetMapIndex(key, val)
+
+	return n, nil
+}
+
+// decodeValue decodes a single value into v, returning the consumed bytes.
+func decodeValue(b []byte, wireType uint64, f *protoField, v reflect.Value) (int, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	var bits uint64
+	var n int
+	switch wireType {
+	case 0:
+		if bits, n = binary.Uvarint(b); n <= 0 {
+			return 0, errProtoTruncated
+		}
+	case 1:
+		if len(b) < 8 {
+			return 0, errProtoTruncated
+		}
+		bits, n = binary.LittleEndian.Uint64(b), 8
+	case 5:
+		if len(b) < 4 {
+			return 0, errProtoTruncated
+		}
+		bits, n = uint64(binary.LittleEndian.Uint32(b)), 4
+	case 2:
+		payload, n, err := readBytes(b)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(payload))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(append([]byte{}, payload...))
+		case v.Kind() == reflect.Struct:
+			if err := decodeMessage(payload, v); err != nil {
+				return 0, err
+			}
+		default:
+			return 0, fmt.Errorf("protobuf: cannot decode bytes into %s", v.Type())
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("protobuf: unsupported wire type %d", wireType)
+	}
+
+	switch f.kind {
+	case "zigzag32":
+		x := uint32(bits)
+		bits = uint64(int64(int32(x>>1) ^ -int32(x&1)))
+	case "zigzag64":
+		bits = uint64(int64(bits>>1) ^ -int64(bits&1))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(bits != 0)
+	case reflect.Int32:
+		v.SetInt(int64(int32(bits)))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int64:
+		v.SetInt(int64(bits))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(bits)
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(uint32(bits))))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(bits))
+	default:
+		return 0, fmt.Errorf("protobuf: cannot decode %s into %s", f.kind, v.Type())
+	}
+
+	return n, nil
+}
+
+// readBytes reads a length delimited payload, returning it and the consumed bytes.
+func readBytes(b []byte) ([]byte, int, error) {
+	size, n := binary.Uvarint(b)
+	if n <= 0 || uint64(len(b)-n) < size {
+		return nil, 0, errProtoTruncated
+	}
+	return b[n : n+int(size)], n + int(size), nil
+}
+
+// skipField returns the size of an unknown field's value.
+func skipField(b []byte, wireType uint64) (int, error) {
+	switch wireType {
+	case 0:
+		if _, n := binary.Uvarint(b); n > 0 {
+			return n, nil
+		}
+	case 1:
+		if len(b) >= 8 {
+			return 8, nil
+		}
+	case 5:
+		if len(b) >= 4 {
+			return 4, nil
+		}
+	case 2:
+		_, n, err := readBytes(b)
+		return n, err
+	default:
+		return 0, fmt.Errorf("protobuf: unsupported wire type %d", wireType)
+	}
+
+	return 0, errProtoTruncated
+}
@@ -0,0 +1,181 @@
+package grabbit
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// protoScalars covers the scalar encodings, packed by proto3.
+type protoScalars struct {
+	Varint   int64     `protobuf:"varint,1,opt,name=varint,proto3"`
+	Text     string    `protobuf:"bytes,2,opt,name=text,proto3"`
+	Sint32   int32     `protobuf:"zigzag32,3,opt,name=sint32,proto3"`
+	Packed   []int32   `protobuf:"varint,4,rep,packed,name=packed,proto3"`
+	Sint64   int64     `protobuf:"zigzag64,5,opt,name=sint64,proto3"`
+	Fixed32  uint32    `protobuf:"fixed32,6,opt,name=fixed32,proto3"`
+	Double   float64   `protobuf:"fixed64,7,opt,name=double,proto3"`
+	Flag     bool      `protobuf:"varint,8,opt,name=flag,proto3"`
+	Unpacked []string  `protobuf:"bytes,9,rep,name=unpacked,proto3"`
+	Floats   []float32 `protobuf:"fixed32,10,rep,name=floats"`
+}
+
+// protoOrder covers the nested messages, maps and explicit optional fields.
+type protoOrder struct {
+	ID       uint64               `protobuf:"varint,1,opt,name=id,proto3"`
+	Customer *protoCustomer       `protobuf:"bytes,2,opt,name=customer,proto3"`
+	Lines    []protoLine          `protobuf:"bytes,3,rep,name=lines,proto3"`
+	Tags     map[string]int32     `protobuf:"bytes,4,rep,name=tags,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Discount *int32               `protobuf:"varint,5,opt,name=discount,proto3,oneof"`
+	Blob     []byte               `protobuf:"bytes,6,opt,name=blob,proto3"`
+	Notes    map[int64]*protoLine `protobuf:"bytes,7,rep,name=notes,proto3" protobuf_key:"zigzag64,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+type protoCustomer struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3"`
+}
+
+type protoLine struct {
+	Sku      string `protobuf:"bytes,1,opt,name=sku,proto3"`
+	Quantity int32  `protobuf:"varint,2,opt,name=quantity,proto3"`
+}
+
+// protoOrderV1 is an older version of protoOrder, unaware of the fields past the first.
+type protoOrderV1 struct {
+	ID uint64 `protobuf:"varint,1,opt,name=id,proto3"`
+}
+
+func TestProtobufWireEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  protoScalars
+		wire string // hex
+	}{
+		{"zero values skipped", protoScalars{}, ""},
+		{"varint", protoScalars{Varint: 150}, "089601"},
+		{"negative varint", protoScalars{Varint: -1}, "08ffffffffffffffffff01"},
+		{"string", protoScalars{Text: "testing"}, "120774657374696e67"},
+		{"zigzag32", protoScalars{Sint32: -1}, "1801"},
+		{"zigzag32 min", protoScalars{Sint32: -2147483648}, "18ffffffff0f"},
+		{"packed", protoScalars{Packed: []int32{3, 270, 86942}}, "2206038e029ea705"},
+		{"zigzag64", protoScalars{Sint64: -2}, "2803"},
+		{"zigzag64 positive", protoScalars{Sint64: 2}, "2804"},
+		{"fixed32", protoScalars{Fixed32: 1}, "3501000000"},
+		{"fixed64", protoScalars{Double: 1}, "39000000000000f03f"},
+		{"bool", protoScalars{Flag: true}, "4001"},
+		{"unpacked strings", protoScalars{Unpacked: []string{"a", ""}}, "4a01614a00"},
+		{"proto2 repeated unpacked", protoScalars{Floats: []float32{1, 2}}, "550000803f5500000040"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wire, err := ProtobufCodec{}.Marshal(&test.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(wire); got != test.wire {
+				t.Fatalf("encoded %s, expected %s", got, test.wire)
+			}
+
+			var decoded protoScalars
+			if err := (ProtobufCodec{}).Unmarshal(wire, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, test.msg) {
+				t.Fatalf("decoded %+v, expected %+v", decoded, test.msg)
+			}
+		})
+	}
+}
+
+func TestProtobufRoundTrip(t *testing.T) {
+	discount := int32(0)
+	order := protoOrder{
+		ID:       42,
+		Customer: &protoCustomer{Name: "ada"},
+		Lines:    []protoLine{{Sku: "a", Quantity: 2}, {}},
+		Tags:     map[string]int32{"rush": 1, "": 0},
+		Discount: &discount,
+		Blob:     []byte{0, 1, 2},
+		Notes:    map[int64]*protoLine{-7: {Sku: "gift"}},
+	}
+
+	wire, err := ProtobufCodec{}.Marshal(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the explicit zero of the optional field is kept
+	if !bytes.Contains(wire, []byte{5 << 3, 0}) {
+		t.Fatalf("optional zero dropped from %x", wire)
+	}
+
+	// T = *Message as for the typed consumers
+	var decoded *protoOrder
+	if err := (ProtobufCodec{}).Unmarshal(wire, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*decoded, order) {
+		t.Fatalf("decoded %+v, expected %+v", *decoded, order)
+	}
+
+	// the fields unknown to older readers are skipped
+	var older protoOrderV1
+	if err := (ProtobufCodec{}).Unmarshal(wire, &older); err != nil || older.ID != 42 {
+		t.Fatalf("older reader %+v: %v", older, err)
+	}
+}
+
+func TestProtobufPackedAndUnpackedAccepted(t *testing.T) {
+	// parsers must accept both encodings of the repeated scalars
+	for _, wire := range []string{"2206038e029ea705", "2003208e02209ea705"} {
+		b, _ := hex.DecodeString(wire)
+		var decoded protoScalars
+		if err := (ProtobufCodec{}).Unmarshal(b, &decoded); err != nil {
+			t.Fatalf("%s: %v", wire, err)
+		}
+		if !reflect.DeepEqual(decoded.Packed, []int32{3, 270, 86942}) {
+			t.Fatalf("%s: decoded %v", wire, decoded.Packed)
+		}
+	}
+}
+
+func TestProtobufErrors(t *testing.T) {
+	truncated := []string{
+		"08",           // varint value missing
+		"0896",         // varint value cut
+		"1207746573",   // string shorter than its length
+		"35010000",     // fixed32 cut
+		"39000000",     // fixed64 cut
+		"2203038e",     // packed run cut
+		"f8",           // key cut
+		"a806",         // unknown varint field without value
+		"aa0605616263", // unknown bytes field cut
+	}
+	for _, wire := range truncated {
+		b, _ := hex.DecodeString(wire)
+		var decoded protoScalars
+		if err := (ProtobufCodec{}).Unmarshal(b, &decoded); !errors.Is(err, errProtoTruncated) {
+			t.Errorf("%s: error %v", wire, err)
+		}
+	}
+
+	var decoded protoScalars
+	if err := (ProtobufCodec{}).Unmarshal([]byte{0x0b}, &decoded); err == nil {
+		t.Error("group wire type accepted")
+	}
+	if err := (ProtobufCodec{}).Unmarshal(nil, decoded); err == nil {
+		t.Error("decoded into a value")
+	}
+	if _, err := (ProtobufCodec{}).Marshal("text"); err == nil {
+		t.Error("encoded a non message")
+	}
+
+	type oneof struct {
+		Choice any `protobuf_oneof:"choice"`
+	}
+	if _, err := (ProtobufCodec{}).Marshal(oneof{Choice: 1}); err == nil {
+		t.Error("encoded a oneof")
+	}
+}
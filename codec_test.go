@@ -0,0 +1,135 @@
+package grabbit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// poisoned is an undecodable delivery passed to the poison handler.
+type poisoned struct {
+	body string
+	err  error
+}
+
+func TestTypedPublishConsume(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	codecOpt := DefaultCodecOptions()
+	codecOpt.WithCodecs(ProtobufCodec{}, JSONCodec{})
+
+	received := make(chan *protoOrder, 10)
+	poison := make(chan poisoned, 10)
+	consumerCodecOpt := codecOpt
+	consumerCodecOpt.WithPoison(func(ctx context.Context, msg Delivery, err error) Result {
+		poison <- poisoned{string(msg.Body), err}
+		return NackDiscard
+	})
+	opt := DefaultConsumerOptions()
+	opt.WithQueue("orders")
+	consumer := NewTypedConsumer(conn, opt, consumerCodecOpt, func(ctx context.Context, order *protoOrder, msg Delivery) Result {
+		received <- order
+		return Ack
+	}, WithChannelDelay(testDelay), WithChannelTopology(testQueue("orders")))
+	defer consumer.Close()
+	eventually(t, 5*time.Second, func() bool {
+		info, _ := srv.Queue("orders")
+		return info.Consumers == 1
+	}, "consumer not started")
+
+	pubOpt := DefaultPublisherOptions()
+	pubOpt.WithKey("orders")
+	publisher := NewTypedPublisher[*protoOrder](conn, pubOpt, codecOpt, WithChannelDelay(testDelay))
+	defer publisher.Close()
+	eventually(t, 5*time.Second, func() bool {
+		return !publisher.Publisher().Channel().IsClosed()
+	}, "publisher not started")
+
+	sent := &protoOrder{ID: 7, Customer: &protoCustomer{Name: "ada"}, Tags: map[string]int32{"rush": 1}}
+	if err := publisher.PublishMessage(sent, amqp.Publishing{MessageId: "7"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case order := <-received:
+		if order.ID != 7 || order.Customer.Name != "ada" || order.Tags["rush"] != 1 {
+			t.Fatalf("received %+v", order)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("order not received")
+	}
+
+	// the other codecs decode by content type
+	srv.Publish("", "orders", amqp.Publishing{
+		ContentType: "application/json; charset=utf-8",
+		Type:        typeNameOf[*protoOrder](),
+		Body:        []byte(`{"ID":8}`),
+	})
+	select {
+	case order := <-received:
+		if order.ID != 8 {
+			t.Fatalf("received %+v", order)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("json order not received")
+	}
+
+	// the codec and routing errors reach the poison handler
+	for _, test := range []struct {
+		msg amqp.Publishing
+		err error
+	}{
+		{amqp.Publishing{ContentType: "application/x-protobuf", Body: []byte("\x08")}, errProtoTruncated},
+		{amqp.Publishing{ContentType: "text/plain", Body: []byte("plain")}, ErrUnknownContentType},
+		{amqp.Publishing{ContentType: "application/x-protobuf", Type: "Invoice", Body: []byte("invoice")}, ErrUnknownType},
+	} {
+		srv.Publish("", "orders", test.msg)
+		select {
+		case got := <-poison:
+			if got.body != string(test.msg.Body) || !errors.Is(got.err, test.err) {
+				t.Fatalf("poisoned %q with %v, expected %v", got.body, got.err, test.err)
+			}
+		case order := <-received:
+			t.Fatalf("undecodable %q received as %+v", test.msg.Body, order)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q not poisoned", test.msg.Body)
+		}
+	}
+	eventually(t, 5*time.Second, func() bool {
+		info, _ := srv.Queue("orders")
+		return info.Ready == 0 && info.Unacked == 0
+	}, "poisoned deliveries not settled")
+}
+
+func TestTypeDispatcherRoutes(t *testing.T) {
+	codecOpt := DefaultCodecOptions()
+	codecOpt.WithPoison(func(ctx context.Context, msg Delivery, err error) Result {
+		if errors.Is(err, ErrUnknownType) {
+			return NackRequeue
+		}
+		return NackDiscard
+	})
+	dispatcher := NewTypeDispatcher(codecOpt)
+	var got *protoOrder
+	Route(dispatcher, "", func(ctx context.Context, order *protoOrder, msg Delivery) Result {
+		got = order
+		return Ack
+	})
+
+	ctx := context.Background()
+	msg := Delivery{DeliveryData: DeliveryData{Type: typeNameOf[*protoOrder](), Body: []byte(`{"ID":3}`)}}
+	if result := dispatcher.Handle(ctx, msg); result != Ack || got == nil || got.ID != 3 {
+		t.Fatalf("result %v, order %+v", result, got)
+	}
+	msg.Body = []byte("{")
+	if result := dispatcher.Handle(ctx, msg); result != NackDiscard {
+		t.Fatalf("corrupt body result %v", result)
+	}
+	msg.Type = "Invoice"
+	if result := dispatcher.Handle(ctx, msg); result != NackRequeue {
+		t.Fatalf("unknown type result %v", result)
+	}
+}
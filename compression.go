@@ -0,0 +1,176 @@
+package grabbit
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"strings"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// Compressor compresses message bodies. Its Encoding is set as the ContentEncoding
+// of the compressed messages and selects it for decompressing the deliveries.
+type Compressor interface {
+	Encoding() string                       // ContentEncoding value (ex: "gzip")
+	Compress(data []byte) ([]byte, error)   // compresses a body
+	Decompress(data []byte) ([]byte, error) // restores a compressed body
+}
+
+// GzipCompressor compresses with compress/gzip. Level zero means the default level.
+type GzipCompressor struct {
+	Level int // compression level (see compress/gzip); 0 is gzip.DefaultCompression
+}
+
+// Encoding implements the [Compressor] interface.
+func (GzipCompressor) Encoding() string { return "gzip" }
+
+// Compress implements the [Compressor] interface.
+func (c GzipCompressor) Compress(data []byte) ([]byte, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress implements the [Compressor] interface.
+func (GzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// FlateCompressor compresses with compress/flate (raw DEFLATE, no header nor checksum),
+// cheaper than gzip for small bodies. Level zero means the default level.
+type FlateCompressor struct {
+	Level int // compression level (see compress/flate); 0 is flate.DefaultCompression
+}
+
+// Encoding implements the [Compressor] interface.
+func (FlateCompressor) Encoding() string { return "deflate" }
+
+// Compress implements the [Compressor] interface.
+func (c FlateCompressor) Compress(data []byte) ([]byte, error) {
+	level := c.Level
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress implements the [Compressor] interface.
+func (FlateCompressor) Decompress(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// builtinCompressors are always available for decompressing the deliveries.
+var builtinCompressors = []Compressor{GzipCompressor{}, FlateCompressor{}, SnappyCompressor{}}
+
+// CompressionInterceptor compresses the bodies of at least threshold bytes
+// and sets their ContentEncoding. Messages already having a ContentEncoding,
+// or not getting smaller, are published unchanged.
+// Consumers decompress the built-in encodings automatically; see [WithChannelCompressors]
+// for custom ones. [PublisherOptions.WithCompression] is the usual shortcut.
+func CompressionInterceptor(compressor Compressor, threshold int) PublishInterceptor {
+	return func(next PublishFunc) PublishFunc {
+		return func(opt PublisherOptions, msg amqp.Publishing) (*DeferredConfirmation, error) {
+			if len(msg.Body) < threshold || msg.ContentEncoding != "" {
+				return next(opt, msg)
+			}
+
+			compressed, err := compressor.Compress(msg.Body)
+			if err != nil {
+				return nil, err
+			}
+			if len(compressed) < len(msg.Body) {
+				msg.Body = compressed
+				msg.ContentEncoding = compressor.Encoding()
+			}
+
+			return next(opt, msg)
+		}
+	}
+}
+
+// WithChannelCompressors registers custom compressors for decompressing the deliveries
+// of consumer channels, in addition to the built-in gzip, deflate and snappy ones.
+func WithChannelCompressors(compressors ...Compressor) func(options *ChannelOptions) {
+	return func(options *ChannelOptions) {
+		options.compressors = append(options.compressors, compressors...)
+	}
+}
+
+// decompressor returns the compressor handling the given content encoding, if any.
+func (ch *Channel) decompressor(encoding string) Compressor {
+	for _, compressors := range [][]Compressor{ch.opt.compressors, builtinCompressors} {
+		for _, compressor := range compressors {
+			if strings.EqualFold(compressor.Encoding(), encoding) {
+				return compressor
+			}
+		}
+	}
+	return nil
+}
+
+// inflate decompresses in place the body of a delivery having a known ContentEncoding,
+// clearing the encoding. Deliveries which fail decompressing are left untouched
+// and reported with an [EventMessageReceived] event.
+func (ch *Channel) inflate(msg *amqp.Delivery) {
+	if msg.ContentEncoding == "" {
+		return
+	}
+	compressor := ch.decompressor(msg.ContentEncoding)
+	if compressor == nil {
+		return
+	}
+
+	body, err := compressor.Decompress(msg.Body)
+	if err != nil {
+		Event{
+			SourceType: CliChannel,
+			SourceName: ch.opt.name,
+			TargetName: msg.ContentEncoding,
+			Kind:       EventMessageReceived,
+			Err:        SomeErrFromError(err, true),
+		}.raise(ch.notifier())
+		return
+	}
+
+	msg.Body = body
+	msg.ContentEncoding = ""
+}
@@ -0,0 +1,158 @@
+package grabbit
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// errSnappyCorrupt signals an invalid snappy block.
+var errSnappyCorrupt = errors.New("snappy: corrupt input")
+
+// SnappyCompressor compresses in the snappy block format (as produced by snappy.Encode
+// of github.com/golang/snappy, not the framed stream format). It trades compression
+// ratio for speed. The encoder is a plain greedy matcher; any conforming decoder reads it.
+type SnappyCompressor struct{}
+
+// Encoding implements the [Compressor] interface.
+func (SnappyCompressor) Encoding() string { return "snappy" }
+
+// Compress implements the [Compressor] interface.
+func (SnappyCompressor) Compress(data []byte) ([]byte, error) {
+	dst := binary.AppendUvarint(make([]byte, 0, len(data)/2+16), uint64(len(data)))
+
+	const tableBits = 14
+	var table [1 << tableBits]int32 // last position+1 of each hashed 4 bytes sequence
+
+	literal := 0 // start of the pending literal
+	for i := 0; i+4 <= len(data); {
+		seq := binary.LittleEndian.Uint32(data[i:])
+		h := (seq * 0x1e35a7bd) >> (32 - tableBits)
+		candidate := int(table[h]) - 1
+		table[h] = int32(i + 1)
+
+		if candidate < 0 || i-candidate > 0xFFFF || binary.LittleEndian.Uint32(data[candidate:]) != seq {
+			i++
+			continue
+		}
+
+		length := 4
+		for i+length < len(data) && data[candidate+length] == data[i+length] {
+			length++
+		}
+		dst = appendSnappyLiteral(dst, data[literal:i])
+		dst = appendSnappyCopy(dst, i-candidate, length)
+		i += length
+		literal = i
+	}
+
+	return appendSnappyLiteral(dst, data[literal:]), nil
+}
+
+// appendSnappyLiteral appends a literal element.
+func appendSnappyLiteral(dst, literal []byte) []byte {
+	if len(literal) == 0 {
+		return dst
+	}
+
+	n := uint32(len(literal) - 1)
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2)
+	case n < 1<<8:
+		dst = append(dst, 60<<2, byte(n))
+	case n < 1<<16:
+		dst = append(dst, 61<<2, byte(n), byte(n>>8))
+	case n < 1<<24:
+		dst = append(dst, 62<<2, byte(n), byte(n>>8), byte(n>>16))
+	default:
+		dst = append(dst, 63<<2, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+	}
+
+	return append(dst, literal...)
+}
+
+// appendSnappyCopy appends copy elements (2 bytes offset) of at most 64 bytes each.
+func appendSnappyCopy(dst []byte, offset, length int) []byte {
+	for length > 0 {
+		n := min(length, 64)
+		dst = append(dst, byte(n-1)<<2|2, byte(offset), byte(offset>>8))
+		length -= n
+	}
+	return dst
+}
+
+// Decompress implements the [Compressor] interface.
+func (SnappyCompressor) Decompress(data []byte) ([]byte, error) {
+	size, n := binary.Uvarint(data)
+	// the densest element (64 bytes copy) takes 3 bytes
+	if n <= 0 || size > uint64(len(data))*22 {
+		return nil, errSnappyCorrupt
+	}
+	src := data[n:]
+	dst := make([]byte, 0, size)
+
+	for len(src) != 0 {
+		tag := src[0]
+		var length, offset int
+
+		switch tag & 3 {
+		case 0: // literal
+			length = int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				extra := length - 59
+				if len(src) < extra {
+					return nil, errSnappyCorrupt
+				}
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(src[i])
+				}
+				src = src[extra:]
+			}
+			length++
+			if length > len(src) || uint64(len(dst)+length) > size {
+				return nil, errSnappyCorrupt
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+		case 1: // copy, 1 byte offset
+			if len(src) < 2 {
+				return nil, errSnappyCorrupt
+			}
+			length = 4 + int(tag>>2)&7
+			offset = int(tag>>5)<<8 | int(src[1])
+			src = src[2:]
+		case 2: // copy, 2 bytes offset
+			if len(src) < 3 {
+				return nil, errSnappyCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+		case 3: // copy, 4 bytes offset
+			if len(src) < 5 {
+				return nil, errSnappyCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+		}
+
+		if offset <= 0 || offset > len(dst) || uint64(len(dst)+length) > size {
+			return nil, errSnappyCorrupt
+		}
+		// byte by byte: the source may overlap the produced bytes
+		for start := len(dst) - offset; length > 0; length-- {
+			dst = append(dst, dst[start])
+			start++
+		}
+	}
+
+	if uint64(len(dst)) != size {
+		return nil, errSnappyCorrupt
+	}
+
+	return dst, nil
+}
@@ -0,0 +1,237 @@
+package grabbit
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// reverseCompressor is a custom encoding, reversing the bodies.
+type reverseCompressor struct{}
+
+func (reverseCompressor) Encoding() string { return "x-reverse" }
+
+func (reverseCompressor) Compress(data []byte) ([]byte, error) {
+	data = slices.Clone(data)
+	slices.Reverse(data)
+	return data[:len(data)-1], nil // "smaller"
+}
+
+func (reverseCompressor) Decompress(data []byte) ([]byte, error) {
+	data = slices.Clone(data)
+	slices.Reverse(data)
+	return data, nil
+}
+
+// snappyInputs are bodies exercising the literal and copy elements of every size.
+func snappyInputs() map[string][]byte {
+	random := make([]byte, 70000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	return map[string][]byte{
+		"empty":         {},
+		"single":        []byte("a"),
+		"short":         []byte("abc"),
+		"run":           bytes.Repeat([]byte("a"), 1000),
+		"overlap":       []byte("abcabcabcabcabcabcabcabc"),
+		"text":          []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 200)),
+		"literal 60":    random[:61],
+		"literal 256":   random[:300],
+		"literal 65536": random,
+		"far copy":      append(append(slices.Clone(random[:100]), random[:65000]...), random[:100]...),
+	}
+}
+
+func TestSnappyRoundTrip(t *testing.T) {
+	for name, data := range snappyInputs() {
+		t.Run(name, func(t *testing.T) {
+			compressed, err := SnappyCompressor{}.Compress(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			restored, err := SnappyCompressor{}.Decompress(compressed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(restored, data) {
+				t.Fatalf("restored %d bytes differ from the %d original ones", len(restored), len(data))
+			}
+		})
+	}
+
+	text := snappyInputs()["text"]
+	if compressed, _ := (SnappyCompressor{}).Compress(text); len(compressed) > len(text)/10 {
+		t.Fatalf("%d bytes compressed to %d", len(text), len(compressed))
+	}
+}
+
+func TestSnappyDecodesAllElements(t *testing.T) {
+	// blocks of other encoders, with the elements this one does not emit
+	tests := []struct {
+		name  string
+		block string // hex
+		data  string
+	}{
+		{"empty", "00", ""},
+		{"literal", "040c61626364", "abcd"},
+		{"copy 1 byte offset", "0c0c616263641104", "abcdabcdabcd"},
+		{"copy 2 bytes offset", "0c0c616263641e0400", "abcdabcdabcd"},
+		{"copy 4 bytes offset", "0c0c616263641f04000000", "abcdabcdabcd"},
+		{"overlapping copy", "0800610d01", "aaaaaaaa"},
+		{"literal 1 byte length", "04f00361626364", "abcd"},
+	}
+
+	for _, test := range tests {
+		block, _ := hex.DecodeString(test.block)
+		data, err := SnappyCompressor{}.Decompress(block)
+		if err != nil || string(data) != test.data {
+			t.Errorf("%s: decoded %q, %v", test.name, data, err)
+		}
+	}
+}
+
+func TestSnappyCorruptInput(t *testing.T) {
+	corrupt := []struct {
+		name  string
+		block string // hex
+	}{
+		{"no length", ""},
+		{"length overflow", "ffffffffffffffffffff01"},
+		{"length beyond the densest encoding", "ff0f00"},
+		{"zero offset", "0c0c616263641100"},
+		{"offset before start", "0c0c616263641105"},
+		{"copy past the length", "080c616263641104"},
+		{"literal past the length", "020c61626364"},
+		{"shorter than the length", "0d0c616263641104"},
+	}
+	for _, test := range corrupt {
+		block, _ := hex.DecodeString(test.block)
+		if _, err := (SnappyCompressor{}).Decompress(block); !errors.Is(err, errSnappyCorrupt) {
+			t.Errorf("%s: error %v", test.name, err)
+		}
+	}
+
+	// every cut of a valid block is detected
+	for name, data := range snappyInputs() {
+		compressed, _ := SnappyCompressor{}.Compress(data)
+		for cut := 0; cut < len(compressed); cut += max(1, len(compressed)/100) {
+			if _, err := (SnappyCompressor{}).Decompress(compressed[:cut]); !errors.Is(err, errSnappyCorrupt) {
+				t.Fatalf("%s truncated to %d of %d bytes: error %v", name, cut, len(compressed), err)
+			}
+		}
+	}
+}
+
+func TestCompressionInterceptor(t *testing.T) {
+	text := []byte(strings.Repeat("compressible ", 20))
+	random := make([]byte, 300)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := []struct {
+		name     string
+		msg      amqp.Publishing
+		encoding string
+	}{
+		{"compressed", amqp.Publishing{Body: text}, "snappy"},
+		{"below the threshold", amqp.Publishing{Body: text[:50]}, ""},
+		{"already encoded", amqp.Publishing{Body: text, ContentEncoding: "gzip"}, "gzip"},
+		{"not getting smaller", amqp.Publishing{Body: random}, ""},
+	}
+
+	for _, test := range tests {
+		var sent amqp.Publishing
+		publish := CompressionInterceptor(SnappyCompressor{}, 100)(func(opt PublisherOptions, msg amqp.Publishing) (*DeferredConfirmation, error) {
+			sent = msg
+			return nil, nil
+		})
+		if _, err := publish(DefaultPublisherOptions(), test.msg); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if sent.ContentEncoding != test.encoding {
+			t.Errorf("%s: encoding %q, expected %q", test.name, sent.ContentEncoding, test.encoding)
+		}
+		if sent.ContentEncoding == test.msg.ContentEncoding && !bytes.Equal(sent.Body, test.msg.Body) {
+			t.Errorf("%s: body changed", test.name)
+		}
+	}
+}
+
+func TestCompressionNegotiation(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	received := make(chan Delivery, 10)
+	opt := DefaultConsumerOptions()
+	opt.WithQueue("compressed").WithHandler(func(ctx context.Context, msg Delivery) Result {
+		received <- msg
+		return Ack
+	})
+	consumer := NewConsumer(conn, opt, WithChannelDelay(testDelay),
+		WithChannelTopology(testQueue("compressed")), WithChannelCompressors(reverseCompressor{}))
+	defer consumer.Close()
+	eventually(t, 5*time.Second, func() bool {
+		info, _ := srv.Queue("compressed")
+		return info.Consumers == 1
+	}, "consumer not started")
+	failures, cancel := consumer.Channel().Subscribe(EventsOfKind(EventMessageReceived))
+	defer cancel()
+
+	text := []byte(strings.Repeat("compressible ", 20))
+	pubOpt := DefaultPublisherOptions()
+	pubOpt.WithKey("compressed").WithCompression(SnappyCompressor{}, 100)
+	pub := newTestPublisher(t, conn, pubOpt)
+	if err := pub.Publish(amqp.Publishing{Body: text}); err != nil {
+		t.Fatal(err)
+	}
+
+	gzipped, _ := GzipCompressor{}.Compress(text)
+	reversed, _ := reverseCompressor{}.Compress(text)
+	tests := []struct {
+		name     string
+		msg      amqp.Publishing // published by another client, empty for the publisher above
+		body     []byte
+		encoding string // left on the delivery
+		failure  bool   // decompression failure reported
+	}{
+		{name: "snappy interceptor", body: text},
+		{name: "builtin gzip", msg: amqp.Publishing{ContentEncoding: "GZIP", Body: gzipped}, body: text},
+		{name: "custom encoding", msg: amqp.Publishing{ContentEncoding: "x-reverse", Body: reversed}, body: text[1:]},
+		{name: "unknown encoding", msg: amqp.Publishing{ContentEncoding: "br", Body: []byte("brotli")}, body: []byte("brotli"), encoding: "br"},
+		{name: "corrupt snappy", msg: amqp.Publishing{ContentEncoding: "snappy", Body: []byte("\x0c\x0c")}, body: []byte("\x0c\x0c"), encoding: "snappy", failure: true},
+	}
+
+	for _, test := range tests {
+		if test.msg.Body != nil {
+			srv.Publish("", "compressed", test.msg)
+		}
+
+		select {
+		case msg := <-received:
+			if !bytes.Equal(msg.Body, test.body) || msg.ContentEncoding != test.encoding {
+				t.Fatalf("%s: received %q encoded %q", test.name, msg.Body, msg.ContentEncoding)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: not received", test.name)
+		}
+
+		select {
+		case event := <-failures:
+			if !test.failure || event.TargetName != "snappy" || !errors.Is(event.Err.Or(nil), errSnappyCorrupt) {
+				t.Fatalf("%s: event %v", test.name, event)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if test.failure {
+				t.Fatalf("%s: failure not reported", test.name)
+			}
+		}
+	}
+}
@@ -14,11 +14,12 @@ func _() {
 	_ = x[ConfirmationPrevious-3]
 	_ = x[ConfirmationACK-4]
 	_ = x[ConfirmationNAK-5]
+	_ = x[ConfirmationSpooled-6]
 }
 
-const _ConfirmationOutcome_name = "no timely responsedata confirmation channel is closedbase channel has not been put into confirm modelower sequence number than expectedACK (publish confirmed)NAK (publish negative acknowledgement)"
+const _ConfirmationOutcome_name = "no timely responsedata confirmation channel is closedbase channel has not been put into confirm modelower sequence number than expectedACK (publish confirmed)NAK (publish negative acknowledgement)stored on disk for later publishing"
 
-var _ConfirmationOutcome_index = [...]uint8{0, 18, 53, 100, 135, 158, 196}
+var _ConfirmationOutcome_index = [...]uint8{0, 18, 53, 100, 135, 158, 196, 231}
 
 func (i ConfirmationOutcome) String() string {
 	if i < 0 || i >= ConfirmationOutcome(len(_ConfirmationOutcome_index)-1) {
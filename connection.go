@@ -3,7 +3,9 @@ package grabbit
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/url"
+	"sync"
 	"time"
 
 	amqp "github.com/oarkflow/amqp/amqp091"
@@ -13,10 +15,11 @@ import (
 // (impl. details: rabbit URL, [ConnectionOptions] and a cancelling context).
 // Applications should obtain a connection using [NewConnection].
 type Connection struct {
-	baseConn SafeBaseConn      // supporting amqp connection
-	address  string            // where to connect
-	blocked  SafeBool          // TCP stream status
-	opt      ConnectionOptions // user parameters
+	baseConn  SafeBaseConn      // supporting amqp connection
+	endpoints endpoints         // where to connect
+	blocked   SafeBool          // TCP stream status
+	opt       ConnectionOptions // user parameters
+	spools    sync.Map          // *spool of the publishers on this connection, woken up on unblocking
 }
 
 // NewConnection creates a new managed Connection object with the given address, configuration, and option functions.
@@ -32,6 +35,8 @@ type Connection struct {
 //		  WithConnectionEvent(connStatusChan),
 //	  )
 //
+// Use [WithConnectionEndpoints] or [NewClusterConnection] for failing over to other cluster nodes.
+//
 // Parameters:
 //   - address: the address of the connection.
 //   - config: the AMQP configuration.
@@ -40,40 +45,45 @@ type Connection struct {
 // Returns: a new Connection object.
 func NewConnection(address string, config amqp.Config, optionFuncs ...func(*ConnectionOptions)) *Connection {
 	opt := ConnectionOptions{
-		notifier: make(chan Event),
-		name:     "default",
-		delayer:  DefaultDelayer{Value: 7500 * time.Millisecond},
-		ctx:      context.Background(),
+		notifier:  newEventBus(),
+		name:      "default",
+		delayer:   DefaultDelayer{Value: 7500 * time.Millisecond},
+		strategy:  RoundRobinStrategy{},
+		ctx:       context.Background(),
+		logLevels: DefaultLogLevels(),
 	}
 
 	for _, optionFunc := range optionFuncs {
 		optionFunc(&opt)
 	}
+	opt.logger = connectionLogger(opt.logger, opt.name, address, config)
+	if config.Logger == nil {
+		// the protocol events land along the connection's own records
+		config.Logger = opt.logger
+	}
 	if opt.onEvent != nil {
-		events := make(chan Event, 10)
-		eventDone := make(chan bool)
-
+		// ends once the bus closes the subscription
+		events, _ := opt.notifier.subscribe(nil, opt.onEventOpt)
 		go func() {
-			for {
-				select {
-				case event := <-events:
-					opt.onEvent(event)
-				case <-eventDone:
-					close(events)
-				}
+			for event := range events {
+				opt.onEvent(event)
 			}
 		}()
-		opt.notifier = events
 	}
 	conn := &Connection{
 		baseConn: SafeBaseConn{},
-		address:  address,
-		opt:      opt,
+		endpoints: endpoints{
+			addresses: append([]string{address}, opt.endpoints...),
+			current:   -1,
+		},
+		opt: opt,
 	}
 
 	conn.opt.ctx, conn.opt.cancelCtx = context.WithCancel(opt.ctx)
 
 	go func() {
+		defer conn.opt.notifier.close()
+
 		if !conn.reconnectLoop(config) {
 			return
 		}
@@ -83,6 +93,33 @@ func NewConnection(address string, config amqp.Config, optionFuncs ...func(*Conn
 	return conn
 }
 
+// Name returns the tag defined originally when creating this connection.
+func (conn *Connection) Name() string {
+	return conn.opt.name
+}
+
+// Subscribe registers a receiver of the events raised by the connection and by its
+// channels (except those having their own [WithChannelNotification]). Only the events
+// selected by the filter (nil for all) are delivered, buffered and handled on overflow
+// as set by the option functions (default [DefaultSubscriptionOptions]).
+//
+// The returned channel is closed by calling unsubscribe or once the connection
+// is closed or its context is cancelled.
+func (conn *Connection) Subscribe(filter EventFilter, optionFuncs ...func(*SubscriptionOptions)) (<-chan Event, func()) {
+	opt := DefaultSubscriptionOptions()
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&opt)
+	}
+
+	return conn.opt.notifier.subscribe(filter, opt)
+}
+
+// Subscriptions reports the buffered and dropped events of each subscriber,
+// including the notification channels and the [OnEvent] callback.
+func (conn *Connection) Subscriptions() []SubscriptionStats {
+	return conn.opt.notifier.stats()
+}
+
 // setFlow updates the flow control status of the Connection.
 //
 // It takes a value of type amqp.Blocking as a parameter and updates the
@@ -105,8 +142,15 @@ func (conn *Connection) setFlow(value amqp.Blocking) {
 	var kind EventType
 	if value.Active {
 		kind = EventBlocked
+		conn.opt.metrics.blocked(CliConnection, conn.opt.name)
+		conn.log(conn.opt.logLevels.Lifecycle, "connection blocked", nil, slog.String("reason", value.Reason))
 	} else {
 		kind = EventUnBlocked
+		conn.log(conn.opt.logLevels.Lifecycle, "connection unblocked", nil)
+		conn.spools.Range(func(key, _ any) bool {
+			key.(*spool).notify()
+			return true
+		})
 	}
 
 	Event{
@@ -119,19 +163,22 @@ func (conn *Connection) setFlow(value amqp.Blocking) {
 
 // refreshCredentials refreshes the credentials of the Connection.
 // If the credentials field of the Connection object is not nil it retrieves the password from the credentials object and
-// updates the address field of the Connection object with the username included in the URL and the password.
+// returns the address with the username included in the URL and the password.
 //
-// No parameters.
-// No return type.
-func (conn *Connection) refreshCredentials() {
+// Parameters:
+//   - address: the URI of the node to dial.
+//
+// Returns: the address to dial.
+func (conn *Connection) refreshCredentials(address string) string {
 	if conn.opt.credentials != nil {
 		if secret, err := conn.opt.credentials.Password(); err == nil {
-			if u, err := url.Parse(conn.address); err == nil {
+			if u, err := url.Parse(address); err == nil {
 				u.User = url.UserPassword(u.User.Username(), secret)
-				conn.address = u.String()
+				return u.String()
 			}
 		}
 	}
+	return address
 }
 
 // notificationChannels returns the notification channels for the Connection.
@@ -149,7 +196,7 @@ func (conn *Connection) notificationChannels() (chan *amqp.Error, chan amqp.Bloc
 	defer conn.baseConn.mu.Unlock()
 
 	if conn.baseConn.super != nil {
-		evtClosed := conn.baseConn.super.NotifyClose(make(chan *amqp.Error))
+		evtClosed := conn.baseConn.super.NotifyClose(make(chan *amqp.Error, 1))   // room for the error raised while closing on demand
 		evtBlocked := conn.baseConn.super.NotifyBlocked(make(chan amqp.Blocking)) // TODO: is this persistent (similar to chan.Flow?)
 		return evtClosed, evtBlocked, nil
 	}
@@ -162,17 +209,31 @@ func (conn *Connection) notificationChannels() (chan *amqp.Error, chan amqp.Bloc
 // It takes a config parameter of type amqp.Config.
 // This function does not return anything.
 func (conn *Connection) manage(config amqp.Config) {
+	var evtClosed chan *amqp.Error
+	var evtBlocked chan amqp.Blocking
+	retry := 0
+
 	for {
-		evtClosed, evtBlocked, err := conn.notificationChannels()
-		if err != nil {
-			// FIXME adopt a circuit breaker policy
-			time.Sleep(conn.opt.delayer.Delay(3))
-			continue
+		// register once per base connection: the library delivers to every
+		// registered notifier, an abandoned one would block its reader
+		if evtClosed == nil {
+			var err error
+			evtClosed, evtBlocked, err = conn.notificationChannels()
+			if err != nil {
+				retry = (retry + 1) % 0xFFFF
+				if !delayerCompleted(conn.opt.ctx, conn.opt.delayer, retry) {
+					conn.Close()
+					return
+				}
+				continue
+			}
+			retry = 0
 		}
 
 		select {
 		case <-conn.opt.ctx.Done():
 			conn.Close() // cancelCtx() called again but idempotent
+			conn.log(conn.opt.logLevels.Lifecycle, "connection closed", nil)
 			return
 		case status := <-evtBlocked:
 			conn.setFlow(status)
@@ -180,6 +241,7 @@ func (conn *Connection) manage(config amqp.Config) {
 			if !conn.recover(config, SomeErrFromError(err, err != nil), notifierStatus) {
 				return
 			}
+			evtClosed, evtBlocked = nil, nil
 		}
 	}
 }
@@ -205,6 +267,8 @@ func (conn *Connection) recover(config amqp.Config, err OptionalError, notifierS
 		Kind:       EventDown,
 		Err:        err,
 	}.raise(conn.opt.notifier)
+	conn.opt.metrics.transition(CliConnection, conn.opt.name, EventDown)
+	conn.log(conn.opt.logLevels.Lifecycle, "connection down", err.Or(nil))
 
 	if !callbackAllowedDown(conn.opt.cbDown, conn.opt.name, err) {
 		return false
@@ -226,31 +290,44 @@ func (conn *Connection) recover(config amqp.Config, err OptionalError, notifierS
 // rebase establishes a new base connection to the AMQP server using the given configuration.
 //
 // The function takes a `config` parameter of type `amqp.Config` which
-// specifies the configuration options for the connection.
+// specifies the configuration options for the connection, and the attempt
+// number used for selecting the node to dial.
 //
 // It returns a boolean value indicating whether the connection was
 // successfully established.
-func (conn *Connection) rebase(config amqp.Config) bool {
+func (conn *Connection) rebase(config amqp.Config, attempt int) bool {
 	result := true
 	kind := EventUp
 	optError := OptionalError{}
 
-	conn.refreshCredentials()
+	address := conn.refreshCredentials(conn.endpoints.next(conn.opt.strategy, attempt))
+	node := conn.Endpoint()
 
-	if super, err := amqp.DialConfig(conn.address, config); err != nil {
+	if super, err := amqp.DialConfig(address, config); err != nil {
 		optError = SomeErrFromError(err, true)
 		kind = EventCannotEstablish
 		result = false
 	} else {
 		conn.baseConn.set(super)
+		// a fresh base connection starts unblocked
+		conn.blocked.mu.Lock()
+		conn.blocked.value = false
+		conn.blocked.mu.Unlock()
 	}
 
 	Event{
 		SourceType: CliConnection,
 		SourceName: conn.opt.name,
+		TargetName: node,
 		Kind:       kind,
 		Err:        optError,
 	}.raise(conn.opt.notifier)
+	conn.opt.metrics.transition(CliConnection, conn.opt.name, kind)
+	if result {
+		conn.log(conn.opt.logLevels.Lifecycle, "connection up", nil, slog.String("node", node))
+	} else {
+		conn.log(conn.opt.logLevels.Recovery, "connection cannot establish", optError.Or(nil), slog.String("node", node))
+	}
 	callbackDoUp(result, conn.opt.cbUp, conn.opt.name)
 
 	return result
@@ -268,8 +345,16 @@ func (conn *Connection) reconnectLoop(config amqp.Config) bool {
 		if !callbackAllowedRecovery(conn.opt.cbReconnect, conn.opt.name, retry) {
 			return false
 		}
+		conn.opt.metrics.attempt(CliConnection, conn.opt.name)
 
-		if conn.rebase(config) {
+		if state, changed := attemptBegins(conn.opt.delayer); changed {
+			conn.breakerChanged(state)
+		}
+		ok := conn.rebase(config, retry)
+		if state, changed := attemptEnds(conn.opt.delayer, ok); changed {
+			conn.breakerChanged(state)
+		}
+		if ok {
 			return true
 		}
 		if !delayerCompleted(conn.opt.ctx, conn.opt.delayer, retry) {
@@ -0,0 +1,242 @@
+package grabbit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// EndpointStrategy chooses the broker node of each connection attempt when the connection
+// has several endpoints (see [NewClusterConnection] and [WithConnectionEndpoints]).
+type EndpointStrategy interface {
+	// Select returns the index in endpoints of the node to dial. The attempt counts from 1
+	// the consecutive tries of establishing the connection; last is the index dialed by the
+	// previous try (-1 initially).
+	Select(endpoints []string, attempt int, last int) int
+}
+
+// RoundRobinStrategy dials the endpoints in turn, moving on at every attempt.
+// This is the default strategy.
+type RoundRobinStrategy struct{}
+
+// Select implements the [EndpointStrategy] interface.
+func (RoundRobinStrategy) Select(endpoints []string, attempt int, last int) int {
+	return (last + 1) % len(endpoints)
+}
+
+// RandomStrategy dials a random endpoint, a different one than the previous
+// failed attempt when possible.
+type RandomStrategy struct{}
+
+// Select implements the [EndpointStrategy] interface.
+func (RandomStrategy) Select(endpoints []string, attempt int, last int) int {
+	if attempt > 1 && last >= 0 && len(endpoints) > 1 {
+		return (last + 1 + rand.Intn(len(endpoints)-1)) % len(endpoints)
+	}
+	return rand.Intn(len(endpoints))
+}
+
+// PriorityStrategy dials the endpoints in the order they are listed, starting over
+// from the first one at every recovery, i.e. the first endpoint is preferred when available.
+type PriorityStrategy struct{}
+
+// Select implements the [EndpointStrategy] interface.
+func (PriorityStrategy) Select(endpoints []string, attempt int, last int) int {
+	return (attempt - 1) % len(endpoints)
+}
+
+// QueueLeaderStrategy prefers the node hosting a queue (the leader of quorum and
+// stream queues), sparing the intra-cluster hops of its messages. The following attempts
+// dial the other endpoints in priority order. Nodes are matched to endpoints by host name,
+// ex: "rabbit@node1" matches "amqp://node1:5672/" and "amqp://node1.example.com/".
+type QueueLeaderStrategy struct {
+	// Locate returns the name of the node hosting the queue, ex: [ManagementLocator].
+	// Failures fall back to the priority order.
+	Locate func(ctx context.Context) (string, error)
+	// Timeout bounds the Locate calls, 5 seconds when zero.
+	Timeout time.Duration
+}
+
+// Select implements the [EndpointStrategy] interface.
+func (s QueueLeaderStrategy) Select(endpoints []string, attempt int, last int) int {
+	leader := -1
+	if s.Locate != nil {
+		timeout := s.Timeout
+		if timeout == 0 {
+			timeout = 5 * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		node, err := s.Locate(ctx)
+		cancel()
+		if err == nil {
+			leader = endpointOfNode(endpoints, node)
+		}
+	}
+	if leader < 0 {
+		return PriorityStrategy{}.Select(endpoints, attempt, last)
+	}
+
+	// leader first, then the others in priority order
+	index := (attempt - 1) % len(endpoints)
+	switch {
+	case index == 0:
+		return leader
+	case index <= leader:
+		return index - 1
+	default:
+		return index
+	}
+}
+
+// endpointOfNode returns the index of the endpoint on the host of a RabbitMQ node, -1 if none.
+func endpointOfNode(endpoints []string, node string) int {
+	_, host, found := strings.Cut(node, "@")
+	if !found {
+		host = node
+	}
+	host = strings.ToLower(host)
+
+	for i, endpoint := range endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			continue
+		}
+		hostname := strings.ToLower(u.Hostname())
+		if hostname == host || strings.HasPrefix(hostname, host+".") || strings.HasPrefix(host, hostname+".") {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// ManagementLocator returns a [QueueLeaderStrategy] locator querying the RabbitMQ
+// management HTTP API (ex: "http://node1:15672") for the node hosting the queue.
+func ManagementLocator(apiURL, username, password, vhost, queue string) func(ctx context.Context) (string, error) {
+	endpoint := fmt.Sprintf("%s/api/queues/%s/%s",
+		strings.TrimSuffix(apiURL, "/"), url.PathEscape(vhost), url.PathEscape(queue))
+
+	return func(ctx context.Context) (string, error) {
+		var info struct {
+			Leader string `json:"leader"` // quorum and stream queues
+			Node   string `json:"node"`   // classic queues
+		}
+		if err := managementGet(ctx, endpoint, username, password, &info); err != nil {
+			return "", err
+		}
+		if info.Leader != "" {
+			return info.Leader, nil
+		}
+		if info.Node != "" {
+			return info.Node, nil
+		}
+		return "", fmt.Errorf("management API: no node hosting queue %q", queue)
+	}
+}
+
+// managementGet decodes the JSON reply of a RabbitMQ management HTTP API endpoint.
+func managementGet(ctx context.Context, endpoint, username, password string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(username, password)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("management API: %s", resp.Status)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// NewClusterConnection creates a managed connection to one of the nodes of a cluster,
+// failing over to the other nodes as chosen by the [EndpointStrategy] (see
+// [WithConnectionStrategy]). The node in use is reported by [Connection.Endpoint]
+// and in the TargetName of the EventUp and EventCannotEstablish events.
+// See [NewConnection] for the other parameters.
+func NewClusterConnection(addresses []string, config amqp.Config, optionFuncs ...func(*ConnectionOptions)) *Connection {
+	if len(addresses) == 0 {
+		addresses = []string{""}
+	}
+	optionFuncs = append([]func(*ConnectionOptions){WithConnectionEndpoints(addresses[1:]...)}, optionFuncs...)
+
+	return NewConnection(addresses[0], config, optionFuncs...)
+}
+
+// WithConnectionEndpoints adds the URIs of other nodes of the cluster, tried after
+// the address passed to [NewConnection].
+func WithConnectionEndpoints(addresses ...string) func(options *ConnectionOptions) {
+	return func(options *ConnectionOptions) {
+		options.endpoints = append(options.endpoints, addresses...)
+	}
+}
+
+// WithConnectionStrategy sets the selection of the endpoint of each connection attempt.
+func WithConnectionStrategy(strategy EndpointStrategy) func(options *ConnectionOptions) {
+	return func(options *ConnectionOptions) {
+		options.strategy = strategy
+	}
+}
+
+// endpoints holds the broker nodes of a connection and the one in use.
+type endpoints struct {
+	mu        sync.RWMutex
+	addresses []string
+	current   int // index of the node dialed last, -1 initially
+}
+
+// next selects and returns the address for the attempt.
+func (e *endpoints) next(strategy EndpointStrategy, attempt int) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	index := 0
+	if len(e.addresses) > 1 {
+		index = strategy.Select(e.addresses, attempt, e.current)
+		if index < 0 || index >= len(e.addresses) {
+			index = 0
+		}
+	}
+	e.current = index
+
+	return e.addresses[index]
+}
+
+// node returns the host of the address dialed last.
+func (e *endpoints) node() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.current < 0 {
+		return ""
+	}
+	return endpointHost(e.addresses[e.current])
+}
+
+// endpointHost returns the host:port of an AMQP URI, thus without the credentials.
+func endpointHost(address string) string {
+	if uri, err := amqp.ParseURI(address); err == nil {
+		return fmt.Sprintf("%s:%d", uri.Host, uri.Port)
+	}
+	return ""
+}
+
+// Endpoint returns the host:port of the broker node dialed last.
+func (conn *Connection) Endpoint() string {
+	return conn.endpoints.node()
+}
@@ -2,6 +2,7 @@ package grabbit
 
 import (
 	"context"
+	"log/slog"
 )
 
 // ConnectionOptions defines a collection of attributes used internally
@@ -11,7 +12,7 @@ import (
 // via WithConnectionOption<Fct> family, ex:
 // [OnConnectionDown], [WithConnectionCtx], [WithConnectionEvent].
 type ConnectionOptions struct {
-	notifier    chan Event             // status events feedback channel
+	notifier    *eventBus              // status events dispatcher
 	name        string                 // tag for this connection
 	credentials SecretProvider         // value for UpdateSecret()
 	delayer     DelayProvider          // how much to wait between re-attempts
@@ -20,7 +21,13 @@ type ConnectionOptions struct {
 	cbReconnect CallbackWhenRecovering // callback when recovering
 	ctx         context.Context        // cancellation context
 	cancelCtx   context.CancelFunc     // aborts the reconnect loop
+	metrics     *Metrics               // registry fed by the connection and its channels (optional)
+	logger      *slog.Logger           // structured logging of the connection and its channels (optional)
+	logLevels   LogLevels              // level of each logging category
+	endpoints   []string               // other nodes of the cluster
+	strategy    EndpointStrategy       // selection of the node to dial
 	onEvent     func(event Event)
+	onEventOpt  SubscriptionOptions // delivery of the events to onEvent
 }
 
 // OnConnectionDown stores the application space callback for
@@ -31,9 +38,19 @@ func OnConnectionDown(down CallbackWhenDown) func(options *ConnectionOptions) {
 	}
 }
 
-func OnEvent(down func(event Event)) func(options *ConnectionOptions) {
+// OnEvent stores the application space callback receiving all the events of the
+// connection and of its channels, on a routine of its own. The delivery follows
+// [DefaultSubscriptionOptions] unless changed by optionFuncs: no event is lost, a callback
+// lagging behind stalling the routines raising them once the buffer is full.
+// [OverflowDropNewest] or [OverflowDropOldest] drop them instead, counted by [Connection.Subscriptions].
+func OnEvent(down func(event Event), optionFuncs ...func(*SubscriptionOptions)) func(options *ConnectionOptions) {
 	return func(options *ConnectionOptions) {
 		options.onEvent = down
+		options.onEventOpt = DefaultSubscriptionOptions()
+		options.onEventOpt.Name = "OnEvent"
+		for _, optionFunc := range optionFuncs {
+			optionFunc(&options.onEventOpt)
+		}
 	}
 }
 
@@ -89,8 +106,10 @@ func WithConnectionName(name string) func(options *ConnectionOptions) {
 
 // WithConnectionEvent provides an application defined
 // [Event] receiver to handle various alerts about the connection status.
+// Events not fitting into ch are dropped; prefer [Connection.Subscribe]
+// for choosing the overflow policy.
 func WithConnectionEvent(ch chan Event) func(options *ConnectionOptions) {
 	return func(options *ConnectionOptions) {
-		options.notifier = ch
+		options.notifier.attach(ch)
 	}
 }
@@ -0,0 +1,67 @@
+package grabbit
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// connectionEvents subscribes to the lifecycle events of the connection.
+func connectionEvents(t *testing.T, conn *Connection) <-chan Event {
+	t.Helper()
+
+	events, cancel := conn.Subscribe(func(event Event) bool {
+		return event.SourceType == CliConnection && (event.Kind == EventUp || event.Kind == EventDown)
+	}, WithSubscriptionBuffer(16))
+	t.Cleanup(cancel)
+
+	return events
+}
+
+// expectEvents waits for the kinds of events, in order.
+func expectEvents(t *testing.T, events <-chan Event, kinds ...EventType) {
+	t.Helper()
+
+	for _, kind := range kinds {
+		select {
+		case event := <-events:
+			if event.Kind != kind {
+				t.Fatalf("event %v, expected %v", event, kind)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %v event", kind)
+		}
+	}
+}
+
+func TestConnectionRecoversAfterRefusedDials(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	eventually(t, 5*time.Second, func() bool { return !conn.IsClosed() }, "connection not established")
+	events := connectionEvents(t, conn)
+
+	dials := faults.Dials()
+	faults.RejectDials(3)
+	faults.Sever()
+
+	expectEvents(t, events, EventDown, EventUp)
+	if n := faults.Dials() - dials; n != 4 {
+		t.Fatalf("%d dials", n)
+	}
+	if conn.IsClosed() {
+		t.Fatal("connection not recovered")
+	}
+}
+
+func TestConnectionRecoversAfterBrokerClose(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	eventually(t, 5*time.Second, func() bool { return !conn.IsClosed() }, "connection not established")
+	events := connectionEvents(t, conn)
+
+	srv.CloseConnections(amqp.ConnectionForced, "CONNECTION_FORCED - broker forced connection closure")
+
+	expectEvents(t, events, EventDown, EventUp)
+	eventually(t, 5*time.Second, func() bool { return srv.Connections() == 1 }, "connection not recovered")
+}
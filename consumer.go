@@ -2,13 +2,17 @@ package grabbit
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 )
 
+// ErrUnknownQueue is returned by [Consumer.Lag] before the consumed queue got its name.
+var ErrUnknownQueue = errors.New("consumed queue not known yet")
+
 // defaultPayloadProcessor processes the payload using default logic.
 //
 // It takes the following parameters:
@@ -24,7 +28,7 @@ func defaultPayloadProcessor(props *DeliveriesProperties, messages []DeliveryDat
 		SourceName: props.ConsumerTag,
 		Kind:       EventMessageReceived,
 		Err:        SomeErrFromString("default processor discards messages"),
-	}.raise(ch.opt.notifier)
+	}.raise(ch.notifier())
 
 	if mustAck && len(messages) != 0 {
 		ch.Ack(messages[len(messages)-1].DeliveryTag, true)
@@ -58,16 +62,40 @@ func NewConsumer(conn *Connection, opt ConsumerOptions, optionFuncs ...func(*Cha
 		ConsumerUsageOptions: opt.ConsumerUsageOptions,
 	}
 	chanOpt := append(optionFuncs, WithChannelUsageParams(useParams))
+	if opt.Handler != nil {
+		chanOpt = append(chanOpt, withChannelHandler(opt.Handler))
+	}
+	if len(opt.Interceptors) != 0 {
+		chanOpt = append(chanOpt, WithConsumeInterceptors(opt.Interceptors...))
+	}
+	if opt.Tracer != nil {
+		chanOpt = append(chanOpt, WithChannelTracer(opt.Tracer))
+	}
+	if opt.Retry != nil && opt.ConsumerQueue != "" {
+		chanOpt = append(chanOpt, withChannelRetry(newRetrier(*opt.Retry, opt.ConsumerQueue)))
+	}
 
-	return &Consumer{
+	c := &Consumer{
 		channel: NewChannel(conn, chanOpt...),
 		opt:     opt,
 	}
+	if opt.Retry != nil && opt.ConsumerQueue == "" {
+		// delay queues cannot dead-letter back into a queue named by the server
+		Event{
+			SourceType: CliChannel,
+			SourceName: c.channel.Name(),
+			Kind:       EventCannotEstablish,
+			Err:        SomeErrFromError(errRetryUnnamedQueue, true),
+		}.raise(c.channel.notifier())
+		c.channel.log(c.channel.opt.logLevels.Errors, "retry policy ignored", errRetryUnnamedQueue)
+	}
+
+	return c
 }
 
 // Available returns the status of both the underlying connection and channel.
 func (c *Consumer) Available() (bool, bool) {
-	return !c.channel.conn.IsClosed(), !c.channel.IsClosed()
+	return !c.channel.Connection().IsClosed(), !c.channel.IsClosed()
 }
 
 // AwaitAvailable waits till the consumer infrastructure is ready or timeout expires.
@@ -85,17 +113,14 @@ func (c *Consumer) AwaitAvailable(timeout, pollFreq time.Duration) bool {
 	// status polling
 	ticker := time.NewTicker(pollFreq)
 	defer ticker.Stop()
-	done := make(chan bool)
 
 	// session timeout
-	go func() {
-		time.Sleep(timeout)
-		done <- true
-	}()
+	expired := time.NewTimer(timeout)
+	defer expired.Stop()
 
 	for {
 		select {
-		case <-done:
+		case <-expired.C:
 			return false
 		case <-ticker.C:
 			if connUp, chanUp := c.Available(); connUp && chanUp {
@@ -105,14 +130,39 @@ func (c *Consumer) AwaitAvailable(timeout, pollFreq time.Duration) bool {
 	}
 }
 
+// Lag returns the number of messages ready for delivery in the consumed queue,
+// as reported by the broker. The queue is inspected over a short-lived channel of
+// its own, a missing queue (NOT_FOUND) closing that channel and not the consumer's.
+// It fails with [ErrUnknownQueue] till the server assigned name is known.
+func (c *Consumer) Lag() (int, error) {
+	name := c.channel.consumedQueue()
+	if name == "" {
+		return 0, ErrUnknownQueue
+	}
+
+	ch, err := c.channel.Connection().Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	queue, err := ch.QueueDeclarePassive(name, false, false, false, false, nil)
+	return queue.Messages, err
+}
+
 // Close shuts down cleanly the publisher channel.
 func (c *Consumer) Close() error {
 	return c.channel.Close()
 }
 
-// Wait shuts down cleanly the publisher channel.
+// Wait blocks till receiving an interrupt or termination signal, then calls ctxCancel.
+// The signal is logged via the channel logger, [slog.Default] when not set.
 func (c *Consumer) Wait(ctxCancel context.CancelFunc) {
 	defer ctxCancel()
+	logger := c.channel.opt.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
 	// block main thread - wait for shutdown signal
 	sigs := make(chan os.Signal, 1)
 	done := make(chan struct{})
@@ -121,10 +171,10 @@ func (c *Consumer) Wait(ctxCancel context.CancelFunc) {
 
 	go func() {
 		sig := <-sigs
-		log.Println(sig)
+		logger.Info("signal received", slog.String("signal", sig.String()))
 		close(done)
 	}()
 
-	log.Println("awaiting signal")
+	logger.Info("awaiting signal")
 	<-done
 }
@@ -0,0 +1,200 @@
+package grabbit
+
+import (
+	"context"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// Result tells the consumer how to settle a delivery once handled.
+// It is returned by the [DeliveryHandler] passed via [ConsumerOptions.WithHandler].
+type Result int
+
+//go:generate stringer -type=Result
+const (
+	Ack         Result = iota // processed; acknowledge it
+	NackRequeue               // not processed; put it back in the queue
+	NackDiscard               // not processable; drop it (or dead-letter it when the queue is set so)
+	Retry                     // transient failure; handled by the retry policy or requeued when none
+)
+
+// Delivery is a single received message handed over to a [DeliveryHandler].
+type Delivery struct {
+	DeliveriesProperties // message properties (exact, not shared with other deliveries)
+	DeliveryData         // message payload and identification
+}
+
+// DeliveryHandler defines a function type processing one message at a time.
+// The library settles (ack, nack) the delivery based on the returned [Result].
+// The context is cancelled when the consumer channel is closed.
+type DeliveryHandler func(ctx context.Context, msg Delivery) Result
+
+// ackTracker settles deliveries in their reception order, coalescing runs of
+// equal outcomes into single multiple=true acknowledgements.
+type ackTracker struct {
+	pending []uint64          // received and not yet settled delivery tags, ascending
+	results map[uint64]Result // outcomes of the handled deliveries
+	spans   map[uint64]Span   // processing spans ending with the settlement (tracing only)
+}
+
+// newAckTracker creates an empty tracker.
+func newAckTracker() *ackTracker {
+	return &ackTracker{results: make(map[uint64]Result), spans: make(map[uint64]Span)}
+}
+
+// add registers a received delivery.
+func (t *ackTracker) add(tag uint64) {
+	t.pending = append(t.pending, tag)
+}
+
+// done records the outcome of a delivery and its processing span, if any.
+func (t *ackTracker) done(tag uint64, result Result, span Span) {
+	t.results[tag] = result
+	if span != nil {
+		t.spans[tag] = span
+	}
+}
+
+// abandon ends the spans of the deliveries which cannot be settled anymore.
+func (t *ackTracker) abandon() {
+	for tag, span := range t.spans {
+		span.End(amqp.ErrClosed)
+		delete(t.spans, tag)
+	}
+}
+
+// flush settles the longest prefix of handled deliveries. Since all deliveries before
+// a run are already settled, a single multiple=true call covers the whole run.
+func (t *ackTracker) flush(ch *Channel) {
+	for len(t.pending) != 0 {
+		first, ok := t.results[t.pending[0]]
+		if !ok {
+			return
+		}
+
+		// extend the run of equal outcomes
+		last := 0
+		for i := 1; i < len(t.pending); i++ {
+			if result, ok := t.results[t.pending[i]]; !ok || result != first {
+				break
+			}
+			last = i
+		}
+
+		tag := t.pending[last]
+		multiple := last != 0
+		switch first {
+		case Ack:
+			ch.Ack(tag, multiple)
+		case NackDiscard:
+			ch.Nack(tag, multiple, false)
+		default:
+			ch.Nack(tag, multiple, true)
+		}
+
+		for _, settled := range t.pending[:last+1] {
+			delete(t.results, settled)
+			if span, ok := t.spans[settled]; ok {
+				endDelivery(span, first)
+				delete(t.spans, settled)
+			}
+		}
+		t.pending = t.pending[last+1:]
+	}
+}
+
+// withChannelHandler makes the channel hand over each delivery to the handler
+// instead of the batch oriented processor.
+func withChannelHandler(handler DeliveryHandler) func(options *ChannelOptions) {
+	return func(options *ChannelOptions) {
+		options.handler = handler
+	}
+}
+
+// deliveryFrom creates the handler's view of a low level delivery.
+func deliveryFrom(msg *amqp.Delivery) Delivery {
+	return Delivery{
+		DeliveriesProperties: DeliveryPropsFrom(msg),
+		DeliveryData:         DeliveryDataFrom(msg),
+	}
+}
+
+// handle decompresses and passes the delivery to the handler, then applies the retry policy, if any.
+// The processing span, when tracing, is left to be ended with the settlement.
+func (ch *Channel) handle(msg *amqp.Delivery) (Result, Span) {
+	ch.inflate(msg)
+	ctx, span := ch.traceDelivery(ch.opt.ctx, msg)
+
+	started := time.Now()
+	result := ch.opt.handler(ctx, deliveryFrom(msg))
+	ch.opt.metrics.processed(ch.opt.name, 0, time.Since(started))
+	if result == Retry && ch.opt.retry != nil {
+		result = ch.opt.retry.retry(ch, msg)
+	}
+
+	return result, span
+}
+
+// serve runs the per message consumer function (see [ConsumerOptions.WithHandler]).
+//
+// Every delivery is handed over to the handler and its outcome recorded. The outcomes
+// are settled once no further delivery is readily available or when the prefetch
+// window is full, so that contiguous equal outcomes travel in a single acknowledgement.
+func (ch *Channel) serve(consumer <-chan amqp.Delivery) {
+	if ch.opt.implParams.Workers > 1 {
+		ch.serveConcurrently(consumer)
+		return
+	}
+
+	mustAck := !ch.opt.implParams.ConsumerAutoAck
+	window := max(ch.opt.implParams.PrefetchCount, 1)
+	tracker := newAckTracker()
+	defer tracker.abandon()
+
+	// handle returns false when the consumer is gone
+	handle := func(msg amqp.Delivery, ok bool) bool {
+		if !ok {
+			// conn/chan are gone, cannot ACK/NAK anyways
+			ch.Cancel(ch.opt.implParams.ConsumerName, true)
+			return false
+		}
+
+		result, span := ch.handle(&msg)
+		if mustAck {
+			tracker.add(msg.DeliveryTag)
+			tracker.done(msg.DeliveryTag, result, span)
+			if len(tracker.pending) >= window {
+				tracker.flush(ch)
+			}
+		} else {
+			endDelivery(span, result)
+		}
+
+		return true
+	}
+
+	for {
+		// drain the burst first
+		select {
+		case msg, ok := <-consumer:
+			if !handle(msg, ok) {
+				return
+			}
+			continue
+		default:
+		}
+
+		tracker.flush(ch)
+
+		select {
+		case <-ch.opt.ctx.Done(): // main chan and notifiers.Consumers should also be gone
+			ch.Cancel(ch.opt.implParams.ConsumerName, true)
+			return
+		case msg, ok := <-consumer:
+			if !handle(msg, ok) {
+				return
+			}
+		}
+	}
+}
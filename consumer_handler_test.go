@@ -0,0 +1,191 @@
+package grabbit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// settledSpan records how a delivery span ended.
+type settledSpan struct {
+	mu      sync.Mutex
+	ends    int
+	outcome string
+}
+
+func (s *settledSpan) SpanContext() SpanContext { return SpanContext{} }
+
+func (s *settledSpan) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key == AttrOutcome {
+		s.outcome = value
+	}
+}
+
+func (s *settledSpan) End(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ends++
+}
+
+// deadLetteredQueue is a topology whose discarded deliveries go to the "dead" queue.
+func deadLetteredQueue(name string) []*TopologyOptions {
+	return []*TopologyOptions{
+		{Name: "dead", Durable: true, Declare: true},
+		{Name: name, Durable: true, Declare: true, IsDestination: true, Args: amqp.Table{
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": "dead",
+		}},
+	}
+}
+
+func TestAckTrackerSettlesContiguousRuns(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	ch := NewChannel(conn, WithChannelDelay(testDelay), WithChannelTopology(deadLetteredQueue("tracked")))
+	defer ch.Close()
+	eventually(t, 5*time.Second, func() bool { return !ch.IsClosed() }, "channel not established")
+	downs, cancel := ch.Subscribe(EventsOfKind(EventDown))
+	defer cancel()
+
+	for i := 1; i <= 6; i++ {
+		srv.Publish("", "tracked", amqp.Publishing{Body: []byte(fmt.Sprint(i))})
+	}
+	deliveries, err := ch.baseChan.Super().Consume("tracked", "", false, false, false, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := newAckTracker()
+	for i := 0; i < 6; i++ {
+		select {
+		case msg := <-deliveries:
+			tracker.add(msg.DeliveryTag)
+		case <-time.After(5 * time.Second):
+			t.Fatal("not delivered")
+		}
+	}
+
+	expect := func(unacked, dead int, pending ...uint64) {
+		t.Helper()
+		eventually(t, 5*time.Second, func() bool {
+			info, _ := srv.Queue("tracked")
+			discarded, _ := srv.Queue("dead")
+			return info.Unacked == unacked && discarded.Ready == dead
+		}, "not %d unacked and %d dead", unacked, dead)
+		if fmt.Sprint(tracker.pending) != fmt.Sprint(pending) {
+			t.Fatalf("pending %v, expected %v", tracker.pending, pending)
+		}
+	}
+
+	// nothing settles ahead of the first delivery
+	spans := make([]*settledSpan, 7)
+	for tag := range spans {
+		spans[tag] = &settledSpan{}
+	}
+	tracker.done(2, Ack, spans[2])
+	tracker.flush(ch)
+	expect(6, 0, 1, 2, 3, 4, 5, 6)
+
+	// ack 1-2 as a run, then the nack of 3 alone: 5 waits for 4
+	tracker.done(1, Ack, spans[1])
+	tracker.done(3, NackDiscard, spans[3])
+	tracker.done(5, Ack, spans[5])
+	tracker.flush(ch)
+	expect(3, 1, 4, 5, 6)
+
+	// the multiple ack of 4-6 does not cover the settled tags again
+	tracker.done(4, Ack, spans[4])
+	tracker.done(6, Ack, spans[6])
+	tracker.flush(ch)
+	expect(0, 1)
+
+	select {
+	case event := <-downs:
+		t.Fatalf("channel closed by the broker: %v", event.Err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	for tag, span := range spans[1:] {
+		expected := Ack.String()
+		if tag+1 == 3 {
+			expected = NackDiscard.String()
+		}
+		if span.ends != 1 || span.outcome != expected {
+			t.Errorf("span of %d ended %d times as %q", tag+1, span.ends, span.outcome)
+		}
+	}
+	if len(tracker.results) != 0 || len(tracker.spans) != 0 {
+		t.Fatalf("settled outcomes kept: %v, %v", tracker.results, tracker.spans)
+	}
+}
+
+func TestAckTrackerAbandonEndsSpans(t *testing.T) {
+	tracker := newAckTracker()
+	span := &settledSpan{}
+	tracker.add(1)
+	tracker.done(1, Ack, span)
+
+	tracker.abandon()
+	if span.ends != 1 || span.outcome != "" || len(tracker.spans) != 0 {
+		t.Fatalf("span ended %d times as %q", span.ends, span.outcome)
+	}
+}
+
+func TestHandlerFlushes(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	var handled atomic.Int32
+	release := make(chan struct{})
+	opt := DefaultConsumerOptions()
+	opt.WithQueue("flushed").WithPrefetchCount(3).WithHandler(func(ctx context.Context, msg Delivery) Result {
+		if string(msg.Body) == "stuck" {
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+		}
+		handled.Add(1)
+		return Ack
+	})
+	consumer := NewConsumer(conn, opt, WithChannelDelay(testDelay), WithChannelTopology(testQueue("flushed")))
+	defer consumer.Close()
+	eventually(t, 5*time.Second, func() bool {
+		info, _ := srv.Queue("flushed")
+		return info.Consumers == 1
+	}, "consumer not started")
+
+	unacked := func(ready, unacked int) func() bool {
+		return func() bool {
+			info, _ := srv.Queue("flushed")
+			return info.Ready == ready && info.Unacked == unacked
+		}
+	}
+
+	// idle: a lone delivery is settled without waiting for the window to fill
+	srv.Publish("", "flushed", amqp.Publishing{Body: []byte("lone")})
+	eventually(t, 5*time.Second, unacked(0, 0), "lone delivery not settled")
+
+	// count: the full window is settled while the handler is stuck further on
+	for i := 0; i < 3; i++ {
+		srv.Publish("", "flushed", amqp.Publishing{Body: []byte("burst")})
+	}
+	srv.Publish("", "flushed", amqp.Publishing{Body: []byte("stuck")})
+	srv.Publish("", "flushed", amqp.Publishing{Body: []byte("after")})
+	eventually(t, 5*time.Second, unacked(0, 2), "window not settled before the stuck delivery")
+	if n := handled.Load(); n != 4 {
+		t.Fatalf("%d handled", n)
+	}
+
+	// shutdown: the unsettled deliveries return to the queue
+	consumer.Close()
+	eventually(t, 5*time.Second, unacked(2, 0), "deliveries not returned on shutdown")
+	close(release)
+}
@@ -23,10 +23,17 @@ type ConsumerUsageOptions struct {
 	ConsumerNoLocal   bool          // see [amqp.Consume]
 	ConsumerNoWait    bool          // see [amqp.Consume]
 	ConsumerArgs      amqp.Table    // core properties
+	Workers           int           // concurrent handlers in per message mode (see [ConsumerOptions.WithHandler])
+	OrderBy           OrderingKey   // keeps sequential the deliveries sharing a key when Workers > 1
 }
 
 type ConsumerOptions struct {
 	ConsumerUsageOptions
+	Handler DeliveryHandler // per message processing; replaces the channel processor when set
+	Retry   *RetryPolicy    // handling of the [Retry] outcomes; requeued when nil
+
+	Interceptors []ConsumeInterceptor // wrappers of the message processing, outermost first
+	Tracer       Tracer               // processing spans, children of the received trace context (optional)
 }
 
 // RandConsumerName creates a random string for the consumers.
@@ -157,3 +164,51 @@ func (opt *ConsumerOptions) WithArgs(args amqp.Table) *ConsumerOptions {
 	opt.ConsumerArgs = args
 	return opt
 }
+
+// WithHandler switches the consumer to per message processing: each delivery is passed
+// to the handler and settled according to the returned [Result]. Contiguous equal
+// outcomes are coalesced into single multiple=true acknowledgements.
+// It takes precedence over any [WithChannelProcessor] passed to [NewConsumer].
+func (opt *ConsumerOptions) WithHandler(handler DeliveryHandler) *ConsumerOptions {
+	opt.Handler = handler
+	return opt
+}
+
+// WithWorkers sets the number of goroutines running the per message handler
+// (see [ConsumerOptions.WithHandler]) concurrently. Acknowledgements keep being
+// sent in delivery order. Zero or one handles the messages inline.
+// Pair it with a prefetch count of at least the number of workers.
+func (opt *ConsumerOptions) WithWorkers(workers int) *ConsumerOptions {
+	opt.Workers = workers
+	return opt
+}
+
+// WithOrdering makes the workers handle sequentially, in reception order,
+// the deliveries sharing the same key. See [ByRoutingKey] and [ByHeader].
+func (opt *ConsumerOptions) WithOrdering(key OrderingKey) *ConsumerOptions {
+	opt.OrderBy = key
+	return opt
+}
+
+// WithRetry sets the policy applied to the deliveries for which the per message
+// handler (see [ConsumerOptions.WithHandler]) returns [Retry]. The delay and parking lot
+// queues are derived from, and dead-letter back into, the ConsumerQueue which must be set.
+func (opt *ConsumerOptions) WithRetry(policy RetryPolicy) *ConsumerOptions {
+	opt.Retry = &policy
+	return opt
+}
+
+// WithConsumeInterceptors appends interceptors wrapping the per message handler or
+// the batch processor of the consumer (see [WithConsumeInterceptors]); the first one is the outermost.
+func (opt *ConsumerOptions) WithConsumeInterceptors(interceptors ...ConsumeInterceptor) *ConsumerOptions {
+	opt.Interceptors = append(opt.Interceptors, interceptors...)
+	return opt
+}
+
+// WithTracer starts a processing span per delivery (per batch with [WithChannelProcessor]),
+// child of the W3C trace context found in the message headers. The per message spans
+// last until the delivery is settled and their context is passed to the handler.
+func (opt *ConsumerOptions) WithTracer(tracer Tracer) *ConsumerOptions {
+	opt.Tracer = tracer
+	return opt
+}
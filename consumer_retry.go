@@ -0,0 +1,247 @@
+package grabbit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// DefaultRetryHeader is the header counting the failed handling attempts
+// of a message, used when [RetryPolicy].Header is empty.
+const DefaultRetryHeader = "x-retry-attempt"
+
+// RetryExpirationHeader keeps the per message expiration of a republished message:
+// the copy has none, it would cut short its wait in the delay queue.
+const RetryExpirationHeader = "x-retry-expiration"
+
+// retryConfirmTimeout is the max wait for the broker confirming a republished message.
+const retryConfirmTimeout = 30 * time.Second
+
+// Failures of the retry policy, reported by events.
+var (
+	errRetryUnnamedQueue = errors.New("retry policy ignored: the consumer queue is server-named")
+	errRetryUnconfirmed  = errors.New("republished message not confirmed")
+)
+
+// RetryPolicy defines how the per message consumer (see [ConsumerOptions.WithHandler])
+// deals with deliveries for which the handler returns [Retry].
+//
+// Such messages are republished into delay queues (one per distinct backoff value) having
+// a TTL and dead-lettering back, via the default exchange, into the consumer queue.
+// Once MaxAttempts handling attempts failed the message is moved to the ParkingLot queue.
+// Delay and parking queues are durable and get declared along with the consumer topology.
+// Republishing is mandatory and confirmed: the original delivery is acknowledged only
+// once the broker accepted the copy. The policy needs a named consumer queue
+// ([ConsumerOptions.ConsumerQueue]); it is ignored, raising an [EventCannotEstablish], otherwise.
+type RetryPolicy struct {
+	MaxAttempts int             // total handling attempts, the first one included
+	Backoff     []time.Duration // delay before each retry; the last value repeats. Empty means 1s
+	ParkingLot  string          // queue receiving the exhausted messages; empty discards them (nack)
+	Header      string          // attempts counter header; empty means [DefaultRetryHeader]
+}
+
+// retrier republishes the failing deliveries of a consumer queue.
+type retrier struct {
+	policy   RetryPolicy
+	queue    string     // consumer queue, messages get back here after delay
+	sending  sync.Mutex // one republishing at a time, telling apart its return
+	mu       sync.Mutex
+	returned chan amqp.Return // mandatory returns of the current base channel
+}
+
+// withChannelRetry enables the retry policy and appends its infrastructure
+// (delay and parking lot queues) to the channel topology.
+func withChannelRetry(r *retrier) func(options *ChannelOptions) {
+	return func(options *ChannelOptions) {
+		options.retry = r
+		options.topology = append(append([]*TopologyOptions{}, options.topology...), r.topology()...)
+	}
+}
+
+// newRetrier normalizes the policy for the given consumer queue.
+func newRetrier(policy RetryPolicy, queue string) *retrier {
+	if len(policy.Backoff) == 0 {
+		policy.Backoff = []time.Duration{time.Second}
+	}
+	if policy.Header == "" {
+		policy.Header = DefaultRetryHeader
+	}
+
+	return &retrier{policy: policy, queue: queue}
+}
+
+// listen switches the current base channel of the consumer into confirm mode,
+// tracking the returns of the republished messages.
+func (r *retrier) listen(super *amqp.Channel) error {
+	r.mu.Lock()
+	r.returned = super.NotifyReturn(make(chan amqp.Return, 1))
+	r.mu.Unlock()
+
+	return super.Confirm(false)
+}
+
+// delayQueue returns the name of the queue holding the messages for the given delay.
+func (r *retrier) delayQueue(delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", r.queue, delay)
+}
+
+// topology defines the delay queues (TTL then dead-lettering back to the
+// consumer queue) and the parking lot queue.
+func (r *retrier) topology() []*TopologyOptions {
+	var topology []*TopologyOptions
+
+	seen := make(map[time.Duration]bool)
+	for _, delay := range r.policy.Backoff {
+		if seen[delay] {
+			continue
+		}
+		seen[delay] = true
+
+		topology = append(topology, &TopologyOptions{
+			Name:    r.delayQueue(delay),
+			Durable: true,
+			Declare: true,
+			Args: amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": r.queue,
+			},
+		})
+	}
+
+	if r.policy.ParkingLot != "" {
+		topology = append(topology, &TopologyOptions{
+			Name:    r.policy.ParkingLot,
+			Durable: true,
+			Declare: true,
+		})
+	}
+
+	return topology
+}
+
+// attempts returns the failed handling attempts recorded in the message headers.
+func (r *retrier) attempts(headers amqp.Table) int {
+	switch value := headers[r.policy.Header].(type) {
+	case int8:
+		return int(value)
+	case int16:
+		return int(value)
+	case int32:
+		return int(value)
+	case int64:
+		return int(value)
+	case int:
+		return int(value)
+	case uint8:
+		return int(value)
+	case uint16:
+		return int(value)
+	case uint32:
+		return int(value)
+	}
+
+	return 0
+}
+
+// retry republishes the failed delivery either into the next delay queue or into
+// the parking lot. It returns how the original delivery must be settled:
+// [Ack] once the broker confirmed the routed copy, [NackRequeue] when republishing
+// failed and [NackDiscard] when exhausted without a parking lot.
+func (r *retrier) retry(ch *Channel, msg *amqp.Delivery) Result {
+	attempts := r.attempts(msg.Headers) + 1
+
+	key := r.policy.ParkingLot
+	if attempts < r.policy.MaxAttempts {
+		delay := r.policy.Backoff[min(attempts, len(r.policy.Backoff))-1]
+		key = r.delayQueue(delay)
+	} else if key == "" {
+		return NackDiscard
+	}
+
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[r.policy.Header] = int32(attempts)
+	if msg.Expiration != "" {
+		headers[RetryExpirationHeader] = msg.Expiration
+	}
+
+	err := r.republish(ch, key, amqp.Publishing{
+		Headers:         headers,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    msg.DeliveryMode,
+		Priority:        msg.Priority,
+		CorrelationId:   msg.CorrelationId,
+		ReplyTo:         msg.ReplyTo,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		UserId:          msg.UserId,
+		AppId:           msg.AppId,
+		Body:            msg.Body,
+	})
+	if err != nil {
+		Event{
+			SourceType: CliChannel,
+			SourceName: ch.opt.name,
+			TargetName: key,
+			Kind:       EventMessagePublished,
+			Err:        SomeErrFromError(err, true),
+		}.raise(ch.notifier())
+		return NackRequeue
+	}
+
+	return Ack
+}
+
+// republish sends the message mandatory and waits for its confirmation. A message
+// returned as unroutable (ex: delay queue deleted) is confirmed as well, hence the
+// return, received ahead of the confirmation, is checked.
+func (r *retrier) republish(ch *Channel, key string, msg amqp.Publishing) error {
+	r.sending.Lock()
+	defer r.sending.Unlock()
+
+	r.mu.Lock()
+	returned := r.returned
+	r.mu.Unlock()
+
+	// a late return of a previous republishing timed out
+	for len(returned) > 0 {
+		<-returned
+	}
+
+	deferred, err := ch.PublishWithDeferredConfirmWithContext(ch.opt.ctx, "", key, true, false, msg)
+	if err != nil {
+		return err
+	}
+	if deferred == nil {
+		return errRetryUnconfirmed
+	}
+
+	select {
+	case <-ch.opt.ctx.Done():
+		return ch.opt.ctx.Err()
+	case <-time.After(retryConfirmTimeout):
+		return errRetryUnconfirmed
+	case <-deferred.Done():
+		if !deferred.Acked() {
+			return errRetryUnconfirmed
+		}
+	}
+
+	select {
+	case ret, ok := <-returned:
+		if ok {
+			return fmt.Errorf("republished message returned: %s", ret.ReplyText)
+		}
+	default:
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package grabbit
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// newRetryConsumer consumes the queue with the handler and the retry policy.
+func newRetryConsumer(t *testing.T, conn *Connection, queue string, policy RetryPolicy, handler DeliveryHandler) *Consumer {
+	t.Helper()
+
+	opt := DefaultConsumerOptions()
+	opt.WithQueue(queue).WithHandler(handler).WithRetry(policy)
+	consumer := NewConsumer(conn, opt, WithChannelDelay(testDelay), WithChannelTopology(testQueue(queue)))
+	t.Cleanup(func() { consumer.Close() })
+
+	return consumer
+}
+
+func TestRetryParksExhaustedMessage(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	var handled atomic.Int32
+	newRetryConsumer(t, conn, "work", RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     []time.Duration{20 * time.Millisecond},
+		ParkingLot:  "work.parked",
+	}, func(ctx context.Context, msg Delivery) Result {
+		handled.Add(1)
+		return Retry
+	})
+
+	eventually(t, 5*time.Second, func() bool {
+		_, ok := srv.Queue("work.parked")
+		return ok
+	}, "retry topology not declared")
+	srv.Publish("", "work", amqp.Publishing{Body: []byte("msg")})
+
+	eventually(t, 5*time.Second, func() bool {
+		info, _ := srv.Queue("work.parked")
+		return info.Ready == 1
+	}, "message not parked")
+	if n := handled.Load(); n != 3 {
+		t.Fatalf("handled %d times", n)
+	}
+	if info, _ := srv.Queue("work"); info.Ready+info.Unacked != 0 {
+		t.Fatalf("consumer queue not settled: %+v", info)
+	}
+}
+
+func TestRetryRequeuesUnroutableRepublishing(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	var handled atomic.Int32
+	consumer := newRetryConsumer(t, conn, "unroutable", RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     []time.Duration{time.Minute},
+	}, func(ctx context.Context, msg Delivery) Result {
+		if handled.Add(1) == 1 {
+			return Retry
+		}
+		return Ack
+	})
+	events, cancel := consumer.Channel().Subscribe(EventsOfKind(EventMessagePublished))
+	defer cancel()
+
+	delayQueue := "unroutable.retry.1m0s"
+	eventually(t, 5*time.Second, func() bool {
+		_, ok := srv.Queue(delayQueue)
+		return ok
+	}, "retry topology not declared")
+	srv.DeleteQueue(delayQueue)
+	srv.Publish("", "unroutable", amqp.Publishing{Body: []byte("msg")})
+
+	select {
+	case event := <-events:
+		if event.TargetName != delayQueue {
+			t.Fatalf("event for %q", event.TargetName)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("returned republishing not reported")
+	}
+	// kept in the consumer queue rather than lost
+	eventually(t, 5*time.Second, func() bool { return handled.Load() == 2 }, "message not redelivered")
+}
+
+func TestRetryServerNamedQueueReported(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	events, cancel := conn.Subscribe(EventsOfKind(EventCannotEstablish))
+	defer cancel()
+
+	opt := DefaultConsumerOptions()
+	opt.WithHandler(func(ctx context.Context, msg Delivery) Result { return Ack }).
+		WithRetry(RetryPolicy{MaxAttempts: 2})
+	consumer := NewConsumer(conn, opt, WithChannelDelay(testDelay),
+		WithChannelTopology([]*TopologyOptions{{Declare: true, Exclusive: true, IsDestination: true}}))
+	defer consumer.Close()
+
+	select {
+	case event := <-events:
+		if event.Err.Error() != errRetryUnnamedQueue.Error() {
+			t.Fatalf("event error %v", event.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ignored retry policy not reported")
+	}
+}
+
+func TestRetryDelaysExpiringMessage(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	const delay = 300 * time.Millisecond
+	attempts := make(chan Delivery, 2)
+	newRetryConsumer(t, conn, "expiring", RetryPolicy{
+		MaxAttempts: 2,
+		Backoff:     []time.Duration{delay},
+	}, func(ctx context.Context, msg Delivery) Result {
+		attempts <- msg
+		return Retry
+	})
+	eventually(t, 5*time.Second, func() bool {
+		info, _ := srv.Queue("expiring")
+		return info.Consumers == 1
+	}, "consumer not started")
+
+	// expiring well ahead of the retry delay
+	srv.Publish("", "expiring", amqp.Publishing{Expiration: "50", Body: []byte("msg")})
+	var first Delivery
+	var started time.Time
+	select {
+	case first = <-attempts:
+		started = time.Now()
+	case <-time.After(5 * time.Second):
+		t.Fatal("not delivered")
+	}
+	if first.Expiration != "50" {
+		t.Fatalf("expiration %q", first.Expiration)
+	}
+
+	select {
+	case retried := <-attempts:
+		if elapsed := time.Since(started); elapsed < delay-50*time.Millisecond {
+			t.Fatalf("retried after %v", elapsed)
+		}
+		if retried.Expiration != "" || retried.Headers[RetryExpirationHeader] != "50" {
+			t.Fatalf("retried with expiration %q, headers %v", retried.Expiration, retried.Headers)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("not retried")
+	}
+}
@@ -0,0 +1,67 @@
+package grabbit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+func TestLagInspectsAside(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	release := make(chan struct{})
+	defer close(release)
+	opt := DefaultConsumerOptions()
+	opt.WithQueue("lagging").WithHandler(func(ctx context.Context, msg Delivery) Result {
+		<-release
+		return Ack
+	})
+	consumer := NewConsumer(conn, opt, WithChannelDelay(testDelay), WithChannelTopology(testQueue("lagging")))
+	defer consumer.Close()
+
+	eventually(t, 5*time.Second, func() bool {
+		info, _ := srv.Queue("lagging")
+		return info.Consumers == 1
+	}, "consumer not started")
+	downs, cancel := consumer.Channel().Subscribe(EventsOfKind(EventDown))
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		srv.Publish("", "lagging", amqp.Publishing{Body: []byte("msg")})
+	}
+	// one message prefetched by the stuck handler
+	eventually(t, 5*time.Second, func() bool {
+		lag, err := consumer.Lag()
+		return err == nil && lag == 4
+	}, "lag not reported")
+
+	select {
+	case event := <-downs:
+		t.Fatalf("consumer channel disturbed: %v", event)
+	default:
+	}
+}
+
+func TestLagUnknownServerNamedQueue(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	opt := DefaultConsumerOptions()
+	consumer := NewConsumer(conn, opt, WithChannelCtx(canceledContext()))
+	defer consumer.Close()
+
+	if _, err := consumer.Lag(); !errors.Is(err, ErrUnknownQueue) {
+		t.Fatalf("error %v", err)
+	}
+}
+
+// canceledContext keeps a channel from ever being established.
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
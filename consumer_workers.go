@@ -0,0 +1,152 @@
+package grabbit
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// OrderingKey extracts from a delivery the key whose messages must be handled
+// sequentially when consuming with several workers (see [ConsumerOptions.WithWorkers]).
+// Deliveries sharing a key are handled one after another, in reception order.
+type OrderingKey func(msg Delivery) string
+
+// ByRoutingKey keeps sequential the deliveries published with the same routing key.
+func ByRoutingKey() OrderingKey {
+	return func(msg Delivery) string {
+		return msg.RoutingKey
+	}
+}
+
+// ByHeader keeps sequential the deliveries carrying the same value of the named header.
+// Deliveries without the header share the empty key.
+func ByHeader(name string) OrderingKey {
+	return func(msg Delivery) string {
+		if value, ok := msg.Headers[name]; ok {
+			return fmt.Sprint(value)
+		}
+		return ""
+	}
+}
+
+// workDone carries the outcome of a delivery handled by a worker.
+type workDone struct {
+	tag    uint64
+	result Result
+	span   Span
+}
+
+// serveConcurrently runs the per message consumer function over a pool of workers.
+//
+// Deliveries are fanned out to the workers (by key when ordering is requested) while
+// this routine keeps the acknowledgements bookkeeping: outcomes completed out of order
+// are held back until all the preceding deliveries are handled, then settled by
+// contiguous runs.
+func (ch *Channel) serveConcurrently(consumer <-chan amqp.Delivery) {
+	params := ch.opt.implParams
+	mustAck := !params.ConsumerAutoAck
+	window := max(params.PrefetchCount, 1)
+	tracker := newAckTracker()
+	defer tracker.abandon()
+
+	done := make(chan struct{})
+	completions := make(chan workDone)
+	queues := make([]chan amqp.Delivery, params.Workers)
+
+	work := func(queue chan amqp.Delivery) {
+		for msg := range queue {
+			select {
+			case <-done: // consumer gone, cannot settle anymore
+				return
+			case <-ch.opt.ctx.Done(): // closing, the delivery returns to the queue
+				return
+			default:
+			}
+
+			result, span := ch.handle(&msg)
+			select {
+			case completions <- workDone{tag: msg.DeliveryTag, result: result, span: span}:
+			case <-done:
+				if span != nil {
+					span.End(amqp.ErrClosed)
+				}
+				return
+			}
+		}
+	}
+
+	if params.OrderBy == nil {
+		shared := make(chan amqp.Delivery)
+		for i := range queues {
+			queues[i] = shared
+			go work(shared)
+		}
+		defer close(shared)
+	} else {
+		for i := range queues {
+			queues[i] = make(chan amqp.Delivery, window)
+			go work(queues[i])
+			defer close(queues[i])
+		}
+	}
+	defer close(done)
+
+	settle := func(c workDone) {
+		if mustAck {
+			tracker.done(c.tag, c.result, c.span)
+		} else {
+			endDelivery(c.span, c.result)
+		}
+	}
+
+	for {
+		select {
+		case <-ch.opt.ctx.Done(): // main chan and notifiers.Consumers should also be gone
+			ch.Cancel(params.ConsumerName, true)
+			return
+		case c := <-completions:
+			settle(c)
+			// gather the readily available outcomes before settling
+			for more := true; more; {
+				select {
+				case c := <-completions:
+					settle(c)
+				default:
+					more = false
+				}
+			}
+			tracker.flush(ch)
+		case msg, ok := <-consumer:
+			if !ok {
+				// conn/chan are gone, cannot ACK/NAK anyways
+				ch.Cancel(params.ConsumerName, true)
+				return
+			}
+
+			if mustAck {
+				tracker.add(msg.DeliveryTag)
+			}
+
+			queue := queues[0]
+			if params.OrderBy != nil {
+				hash := fnv.New32a()
+				hash.Write([]byte(params.OrderBy(deliveryFrom(&msg))))
+				queue = queues[hash.Sum32()%uint32(len(queues))]
+			}
+
+			// keep collecting outcomes while the workers are busy
+			for sent := false; !sent; {
+				select {
+				case queue <- msg:
+					sent = true
+				case c := <-completions:
+					settle(c)
+				case <-ch.opt.ctx.Done():
+					ch.Cancel(params.ConsumerName, true)
+					return
+				}
+			}
+		}
+	}
+}
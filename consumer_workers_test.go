@@ -0,0 +1,139 @@
+package grabbit
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+func TestWorkersKeepKeyOrder(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	const keys, perKey = 5, 40
+	var (
+		mu              sync.Mutex
+		seen            = make(map[string][]int)
+		active, busiest atomic.Int32
+	)
+	opt := DefaultConsumerOptions()
+	opt.WithQueue("ordered").WithPrefetchCount(20).WithWorkers(4).WithOrdering(ByHeader("key")).
+		WithHandler(func(ctx context.Context, msg Delivery) Result {
+			n := active.Add(1)
+			defer active.Add(-1)
+			for old := busiest.Load(); n > old && !busiest.CompareAndSwap(old, n); old = busiest.Load() {
+			}
+			time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			key := fmt.Sprint(msg.Headers["key"])
+			seen[key] = append(seen[key], int(msg.Headers["seq"].(int32)))
+			return Ack
+		})
+	consumer := NewConsumer(conn, opt, WithChannelDelay(testDelay), WithChannelTopology(testQueue("ordered")))
+	defer consumer.Close()
+	eventually(t, 5*time.Second, func() bool {
+		info, _ := srv.Queue("ordered")
+		return info.Consumers == 1
+	}, "consumer not started")
+	downs, cancel := consumer.Channel().Subscribe(EventsOfKind(EventDown))
+	defer cancel()
+
+	for seq := 0; seq < perKey; seq++ {
+		for key := 0; key < keys; key++ {
+			srv.Publish("", "ordered", amqp.Publishing{Headers: amqp.Table{"key": fmt.Sprint("k", key), "seq": int32(seq)}})
+		}
+	}
+	// the outcomes completed out of order are all settled
+	eventually(t, 10*time.Second, func() bool {
+		info, _ := srv.Queue("ordered")
+		return info.Ready == 0 && info.Unacked == 0
+	}, "deliveries not all settled")
+
+	mu.Lock()
+	defer mu.Unlock()
+	for key, sequence := range seen {
+		for i, seq := range sequence {
+			if seq != i {
+				t.Fatalf("key %s handled out of order: %v", key, sequence)
+			}
+		}
+		if len(sequence) != perKey {
+			t.Fatalf("key %s handled %d times", key, len(sequence))
+		}
+	}
+	if len(seen) != keys {
+		t.Fatalf("%d keys handled", len(seen))
+	}
+	if busiest.Load() < 2 {
+		t.Fatal("deliveries not handled concurrently")
+	}
+	select {
+	case event := <-downs:
+		t.Fatalf("channel closed by the broker: %v", event.Err)
+	default:
+	}
+}
+
+func TestWorkersDrainOnCancel(t *testing.T) {
+	for _, ordering := range []OrderingKey{nil, ByRoutingKey()} {
+		srv, faults := newTestBroker(t)
+		conn := newTestConnection(t, srv, faults)
+
+		var started, active, handled atomic.Int32
+		ctx, cancelCtx := context.WithCancel(context.Background())
+		opt := DefaultConsumerOptions()
+		opt.WithQueue("drained").WithPrefetchCount(10).WithWorkers(3).WithOrdering(ordering).
+			WithHandler(func(ctx context.Context, msg Delivery) Result {
+				started.Add(1)
+				active.Add(1)
+				defer active.Add(-1)
+				<-ctx.Done()
+				handled.Add(1)
+				return Ack
+			})
+		consumer := NewConsumer(conn, opt, WithChannelDelay(testDelay), WithChannelCtx(ctx),
+			WithChannelTopology(testQueue("drained")))
+		eventually(t, 5*time.Second, func() bool {
+			info, _ := srv.Queue("drained")
+			return info.Consumers == 1
+		}, "consumer not started")
+
+		for i := 0; i < 10; i++ {
+			srv.Publish("", "drained", amqp.Publishing{Body: []byte("msg")})
+		}
+		// with ordering the deliveries all share a key and a worker
+		busy := int32(3)
+		if ordering != nil {
+			busy = 1
+		}
+		eventually(t, 5*time.Second, func() bool { return active.Load() == busy }, "workers not busy")
+
+		cancelCtx()
+		eventually(t, 5*time.Second, func() bool { return active.Load() == 0 }, "workers not drained")
+		eventually(t, 5*time.Second, func() bool {
+			info, _ := srv.Queue("drained")
+			return info.Consumers == 0
+		}, "consumer not cancelled")
+
+		// the queued deliveries are not handed over anymore
+		n := started.Load()
+		time.Sleep(100 * time.Millisecond)
+		if started.Load() != n || n != busy {
+			t.Fatalf("%d deliveries started, %d expected", started.Load(), busy)
+		}
+		// all but the handled ones, which may have been settled, return to the queue
+		consumer.Close()
+		eventually(t, 5*time.Second, func() bool {
+			info, _ := srv.Queue("drained")
+			return info.Unacked == 0 && info.Ready >= 10-int(handled.Load())
+		}, "deliveries lost")
+	}
+}
@@ -41,6 +41,8 @@ const (
 	EventDefineTopology
 	EventDataExhausted
 	EventDataPartial
+	EventBreaker
+	EventQueueRenamed
 )
 
 // Event defines a simple body structure for the alerts received
@@ -50,19 +52,15 @@ type Event struct {
 	SourceType ClientType    // origin type
 	SourceName string        // origin tag
 	TargetName string        // affected tag
+	OldName    string        // former TargetName, ex: server-assigned name of a redeclared queue
 	Kind       EventType     // type of event
 	Err        OptionalError // low level error
 }
 
 // raise pushes an event type from a particular connection or channel
-// over the provided notification channel. If the notification channel does not have enough capacity,
-// it ignores the event and does nothing.
+// to the subscribers of the provided bus, according to their overflow policies.
 //
-// See WithChannelNotification and WithConnectionEvent
-func (event Event) raise(ch chan Event) {
-	select {
-	case ch <- event:
-	default:
-		// chan has not enough capacity, dump this alert
-	}
+// See Connection.Subscribe, WithChannelNotification and WithConnectionEvent
+func (event Event) raise(bus *eventBus) {
+	bus.publish(event)
 }
@@ -0,0 +1,279 @@
+package grabbit
+
+import (
+	"sync"
+)
+
+// OverflowPolicy defines what happens to the events raised while the buffer
+// of a subscriber (see [Connection.Subscribe]) is full.
+type OverflowPolicy int
+
+const (
+	OverflowDropNewest OverflowPolicy = iota // discard the event being raised
+	OverflowDropOldest                       // discard the oldest buffered event to make room
+	OverflowBlock                            // wait for room; stalls the raising routine
+)
+
+// EventFilter selects the events delivered to a subscriber. Nil selects all events.
+type EventFilter func(event Event) bool
+
+// EventsOfKind selects the events of the given kinds.
+func EventsOfKind(kinds ...EventType) EventFilter {
+	return func(event Event) bool {
+		for _, kind := range kinds {
+			if event.Kind == kind {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// EventsFrom selects the events raised by the named connections or channels.
+func EventsFrom(names ...string) EventFilter {
+	return func(event Event) bool {
+		for _, name := range names {
+			if event.SourceName == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SubscriptionOptions defines the delivery of events to a subscriber.
+type SubscriptionOptions struct {
+	Name     string         // tag reported by [Connection.Subscriptions]
+	Buffer   int            // capacity of the subscriber channel
+	Overflow OverflowPolicy // behavior when the buffer is full
+}
+
+// DefaultSubscriptionOptions buffers up to 64 events, then waits for room: no event is lost,
+// hence the subscriber must keep receiving or unsubscribe. Dropping is opt-in, see [WithSubscriptionOverflow].
+func DefaultSubscriptionOptions() SubscriptionOptions {
+	return SubscriptionOptions{
+		Buffer:   64,
+		Overflow: OverflowBlock,
+	}
+}
+
+// WithSubscriptionName sets the tag of the subscriber.
+func WithSubscriptionName(name string) func(options *SubscriptionOptions) {
+	return func(options *SubscriptionOptions) {
+		options.Name = name
+	}
+}
+
+// WithSubscriptionBuffer sets the capacity of the subscriber channel.
+func WithSubscriptionBuffer(size int) func(options *SubscriptionOptions) {
+	return func(options *SubscriptionOptions) {
+		options.Buffer = size
+	}
+}
+
+// WithSubscriptionOverflow sets the policy applied when the subscriber lags behind.
+func WithSubscriptionOverflow(policy OverflowPolicy) func(options *SubscriptionOptions) {
+	return func(options *SubscriptionOptions) {
+		options.Overflow = policy
+	}
+}
+
+// SubscriptionStats reports the state of a subscriber.
+type SubscriptionStats struct {
+	Name     string // subscriber tag
+	Buffered int    // events waiting to be received
+	Dropped  uint64 // events lost because of overflowing
+}
+
+// subscriber is a registered receiver of events.
+type subscriber struct {
+	mu      sync.Mutex
+	opt     SubscriptionOptions
+	filter  EventFilter
+	events  chan Event
+	owned   bool           // the channel is closed on unsubscribing
+	done    chan struct{}  // closed on unsubscribing, releases the blocked senders
+	senders sync.WaitGroup // blocked senders, waited for before closing the channel
+	closed  bool
+	dropped uint64
+}
+
+// eventBus dispatches the events raised by a connection and its channels
+// to all the interested subscribers.
+type eventBus struct {
+	mu          sync.RWMutex
+	subscribers []*subscriber
+	closed      bool
+}
+
+// newEventBus creates a bus without subscribers.
+func newEventBus() *eventBus {
+	return &eventBus{}
+}
+
+// subscribe registers a subscriber owning a new channel.
+func (bus *eventBus) subscribe(filter EventFilter, opt SubscriptionOptions) (<-chan Event, func()) {
+	sub := &subscriber{
+		opt:    opt,
+		filter: filter,
+		events: make(chan Event, max(opt.Buffer, 0)),
+		owned:  true,
+		done:   make(chan struct{}),
+	}
+
+	bus.mu.Lock()
+	if bus.closed {
+		sub.close()
+	} else {
+		bus.subscribers = append(bus.subscribers, sub)
+	}
+	bus.mu.Unlock()
+
+	return sub.events, func() { bus.unsubscribe(sub) }
+}
+
+// attach registers an application provided channel, never closed by the bus.
+// Events not fitting into it are dropped.
+func (bus *eventBus) attach(events chan Event) {
+	sub := &subscriber{
+		opt:    SubscriptionOptions{Name: "notification", Overflow: OverflowDropNewest},
+		events: events,
+		done:   make(chan struct{}),
+	}
+
+	bus.mu.Lock()
+	bus.subscribers = append(bus.subscribers, sub)
+	bus.mu.Unlock()
+}
+
+// unsubscribe removes and closes a subscriber; safe to call repeatedly.
+func (bus *eventBus) unsubscribe(sub *subscriber) {
+	bus.mu.Lock()
+	for i, s := range bus.subscribers {
+		if s == sub {
+			bus.subscribers = append(bus.subscribers[:i:i], bus.subscribers[i+1:]...)
+			break
+		}
+	}
+	bus.mu.Unlock()
+
+	sub.close()
+}
+
+// close ends the dispatching and closes the subscribers channels.
+func (bus *eventBus) close() {
+	bus.mu.Lock()
+	subscribers := bus.subscribers
+	bus.subscribers = nil
+	bus.closed = true
+	bus.mu.Unlock()
+
+	for _, sub := range subscribers {
+		sub.close()
+	}
+}
+
+// publish delivers the event to the interested subscribers.
+func (bus *eventBus) publish(event Event) {
+	if bus == nil {
+		return
+	}
+
+	bus.mu.RLock()
+	subscribers := bus.subscribers
+	bus.mu.RUnlock()
+
+	for _, sub := range subscribers {
+		if sub.filter == nil || sub.filter(event) {
+			sub.deliver(event)
+		}
+	}
+}
+
+// stats reports the state of the subscribers.
+func (bus *eventBus) stats() []SubscriptionStats {
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+
+	stats := make([]SubscriptionStats, 0, len(bus.subscribers))
+	for _, sub := range bus.subscribers {
+		sub.mu.Lock()
+		stats = append(stats, SubscriptionStats{
+			Name:     sub.opt.Name,
+			Buffered: len(sub.events),
+			Dropped:  sub.dropped,
+		})
+		sub.mu.Unlock()
+	}
+
+	return stats
+}
+
+// deliver pushes the event according to the overflow policy.
+func (sub *subscriber) deliver(event Event) {
+	if sub.opt.Overflow == OverflowBlock {
+		sub.deliverBlocking(event)
+		return
+	}
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if sub.closed {
+		return
+	}
+	for {
+		select {
+		case sub.events <- event:
+			return
+		default:
+		}
+
+		if sub.opt.Overflow == OverflowDropNewest || cap(sub.events) == 0 {
+			sub.dropped++
+			return
+		}
+		// make room, unless the receiver just did
+		select {
+		case <-sub.events:
+			sub.dropped++
+		default:
+		}
+	}
+}
+
+// deliverBlocking waits for room in the subscriber channel or for unsubscribing.
+// The lock is not held while waiting, so unsubscribing is never stalled.
+func (sub *subscriber) deliverBlocking(event Event) {
+	sub.mu.Lock()
+	if sub.closed {
+		sub.mu.Unlock()
+		return
+	}
+	sub.senders.Add(1)
+	sub.mu.Unlock()
+	defer sub.senders.Done()
+
+	select {
+	case sub.events <- event:
+	case <-sub.done:
+	}
+}
+
+// close marks the subscriber closed and, when owned, closes its channel.
+func (sub *subscriber) close() {
+	sub.mu.Lock()
+	if sub.closed {
+		sub.mu.Unlock()
+		return
+	}
+	sub.closed = true
+	close(sub.done)
+	sub.mu.Unlock()
+
+	if sub.owned {
+		// the blocked senders are released by done
+		sub.senders.Wait()
+		close(sub.events)
+	}
+}
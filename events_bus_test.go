@@ -0,0 +1,123 @@
+package grabbit
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// raiseTestEvents raises the count of events in order, closing the returned channel once done.
+func raiseTestEvents(conn *Connection, count int) <-chan struct{} {
+	raised := make(chan struct{})
+	go func() {
+		defer close(raised)
+		for i := 0; i < count; i++ {
+			Event{SourceType: CliConnection, SourceName: "test", TargetName: strconv.Itoa(i), Kind: EventDown}.raise(conn.opt.notifier)
+		}
+	}()
+	return raised
+}
+
+// onEventStats returns the state of the OnEvent subscription.
+func onEventStats(t *testing.T, conn *Connection) SubscriptionStats {
+	t.Helper()
+
+	for _, stats := range conn.Subscriptions() {
+		if stats.Name == "OnEvent" {
+			return stats
+		}
+	}
+	t.Fatal("OnEvent subscription not reported")
+	return SubscriptionStats{}
+}
+
+func TestOnEventLosesNothingWhileLagging(t *testing.T) {
+	srv, faults := newTestBroker(t)
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var received []string
+	conn := newTestConnection(t, srv, faults, OnEvent(func(event Event) {
+		<-release
+		if event.SourceName == "test" {
+			mu.Lock()
+			received = append(received, event.TargetName)
+			mu.Unlock()
+		}
+	}))
+
+	raised := raiseTestEvents(conn, 200)
+	select {
+	case <-raised:
+		t.Fatal("events raised beyond the buffer of the lagging callback")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-raised:
+	case <-time.After(5 * time.Second):
+		t.Fatal("raising stalled after the callback caught up")
+	}
+	eventually(t, 5*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 200
+	}, "events lost")
+	for i, target := range received {
+		if target != strconv.Itoa(i) {
+			t.Fatalf("event %d received as %s", i, target)
+		}
+	}
+	if stats := onEventStats(t, conn); stats.Dropped != 0 {
+		t.Fatalf("%d events dropped", stats.Dropped)
+	}
+}
+
+func TestOnEventDropsWhenOptedIn(t *testing.T) {
+	srv, faults := newTestBroker(t)
+
+	release := make(chan struct{})
+	defer close(release)
+	conn := newTestConnection(t, srv, faults, OnEvent(func(event Event) { <-release },
+		WithSubscriptionOverflow(OverflowDropNewest)))
+
+	select {
+	case <-raiseTestEvents(conn, 200):
+	case <-time.After(5 * time.Second):
+		t.Fatal("raising stalled by the lagging callback")
+	}
+	if stats := onEventStats(t, conn); stats.Dropped == 0 {
+		t.Fatal("no event dropped")
+	}
+}
+
+func TestChannelNotificationEndsWithChannel(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	notifications := make(chan Event, 16)
+	ch := NewChannel(conn, WithChannelDelay(testDelay), WithChannelNotification(notifications))
+	events, _ := ch.Subscribe(nil)
+
+	eventually(t, 5*time.Second, func() bool { return !ch.IsClosed() }, "channel not up")
+	ch.Close()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				// the application channel is left open
+				select {
+				case notifications <- Event{}:
+				default:
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel subscription not closed along the channel")
+		}
+	}
+}
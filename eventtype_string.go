@@ -23,11 +23,13 @@ func _() {
 	_ = x[EventDefineTopology-12]
 	_ = x[EventDataExhausted-13]
 	_ = x[EventDataPartial-14]
+	_ = x[EventBreaker-15]
+	_ = x[EventQueueRenamed-16]
 }
 
-const _EventType_name = "UpDownCannotEstablishBlockedUnBlockedClosedMessageReceivedMessagePublishedMessageReturnedConfirmQosConsumeDefineTopologyDataExhaustedDataPartial"
+const _EventType_name = "UpDownCannotEstablishBlockedUnBlockedClosedMessageReceivedMessagePublishedMessageReturnedConfirmQosConsumeDefineTopologyDataExhaustedDataPartialBreakerQueueRenamed"
 
-var _EventType_index = [...]uint8{0, 2, 6, 21, 28, 37, 43, 58, 74, 89, 96, 99, 106, 120, 133, 144}
+var _EventType_index = [...]uint8{0, 2, 6, 21, 28, 37, 43, 58, 74, 89, 96, 99, 106, 120, 133, 144, 151, 163}
 
 func (i EventType) String() string {
 	if i < 0 || i >= EventType(len(_EventType_index)-1) {
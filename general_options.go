@@ -16,8 +16,8 @@ type SecretProvider interface {
 
 // DelayProvider allows passing a bespoke method for providing the
 // delay policy for waiting between reconnection attempts.
-// See [WithConnectionDelay], [WithChannelDelay]. TIP:
-// one could pass en exponential delayer derived from the 'retry' counter.
+// See [WithConnectionDelay], [WithChannelDelay] and the implementations
+// [ExponentialDelayer], [DecorrelatedJitterDelayer], [MaxAttemptsDelayer], [CircuitBreaker].
 type DelayProvider interface {
 	Delay(retry int) time.Duration
 }
@@ -87,15 +87,17 @@ type DeliveryPayload []byte
 
 // DeliveryData isolates the data part of each specific delivered message
 type DeliveryData struct {
-	Body        DeliveryPayload // actual data payload
-	DeliveryTag uint64          // sequential number of this message
-	Redelivered bool            // message has been re-enqueued
-	Expiration  string          // message expiration spec
-	MessageId   string          // message identifier
-	Timestamp   time.Time       // message timestamp
-	Type        string          // message type name
-	UserId      string          // user of the publishing connection
-	AppId       string          // application id
+	Body          DeliveryPayload // actual data payload
+	DeliveryTag   uint64          // sequential number of this message
+	Redelivered   bool            // message has been re-enqueued
+	Expiration    string          // message expiration spec
+	MessageId     string          // message identifier
+	Timestamp     time.Time       // message timestamp
+	Type          string          // message type name
+	UserId        string          // user of the publishing connection
+	AppId         string          // application id
+	CorrelationId string          // application use - correlation identifier
+	ReplyTo       string          // application use - address to reply to (ex: RPC)
 }
 
 // DeliveryDataFrom creates a DeliveryData object from an amqp.Delivery object.
@@ -103,15 +105,17 @@ type DeliveryData struct {
 // It takes a pointer to an amqp.Delivery object as its parameter and returns a DeliveryData object.
 func DeliveryDataFrom(d *amqp.Delivery) (data DeliveryData) {
 	return DeliveryData{
-		Body:        d.Body,
-		DeliveryTag: d.DeliveryTag,
-		Redelivered: d.Redelivered,
-		Expiration:  d.Expiration,
-		MessageId:   d.MessageId,
-		Timestamp:   d.Timestamp,
-		Type:        d.Type,
-		UserId:      d.UserId,
-		AppId:       d.AppId,
+		Body:          d.Body,
+		DeliveryTag:   d.DeliveryTag,
+		Redelivered:   d.Redelivered,
+		Expiration:    d.Expiration,
+		MessageId:     d.MessageId,
+		Timestamp:     d.Timestamp,
+		Type:          d.Type,
+		UserId:        d.UserId,
+		AppId:         d.AppId,
+		CorrelationId: d.CorrelationId,
+		ReplyTo:       d.ReplyTo,
 	}
 }
 
@@ -153,11 +157,20 @@ func callbackDoUp(want bool, cb CallbackWhenUp, name string) {
 // delayerCompleted waits for the provided (WithConnectionDelay, WithChannelDelay)
 // or default (DefaultDelayer) timing-out policy to complete as part of the recovery loop
 // (see chanReconnectLoop, connReconnectLoop).
+// A negative delay (see [AbortRecovery]) gives up recovering.
 func delayerCompleted(ctx context.Context, delayer DelayProvider, attempt int) bool {
+	delay := delayer.Delay(attempt)
+	if delay < 0 {
+		return false
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
 	select {
 	case <-ctx.Done():
 		return false
-	case <-time.After(delayer.Delay(attempt)):
+	case <-timer.C:
 	}
 
 	return true
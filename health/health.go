@@ -0,0 +1,368 @@
+// Package health reports the state of grabbit connections, channels, publishers and
+// consumers via liveness (/livez) and readiness (/readyz) HTTP handlers, suitable for
+// Kubernetes probes.
+//
+// Example Usage:
+//
+//	checker := health.NewChecker(
+//	  health.WithReadyGrace(10*time.Second),
+//	  health.WithLiveLimit(5*time.Minute),
+//	)
+//	defer checker.Close()
+//	checker.AddConnection(conn)
+//	checker.AddPublisher(publisher)
+//	checker.AddConsumer(consumer)
+//	http.Handle("/", checker.Handler())
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	grabbit "github.com/oarkflow/amqp"
+)
+
+// Kind tells the type of a tracked component.
+type Kind string
+
+const (
+	KindConnection Kind = "connection"
+	KindChannel    Kind = "channel"
+	KindPublisher  Kind = "publisher"
+	KindConsumer   Kind = "consumer"
+)
+
+// Report statuses and component states.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	StateUp    = "up"
+	StateDown  = "down"
+)
+
+// Options defines the thresholds of the probes.
+type Options struct {
+	ReadyGrace      time.Duration // downtime tolerated by the readiness probe
+	LiveLimit       time.Duration // downtime failing the liveness probe; zero never fails it
+	BlockedNotReady bool          // a connection blocked by the broker fails the readiness probe
+	PausedNotReady  bool          // a publisher paused by the broker flow control fails the readiness probe
+	InspectLag      bool          // query the broker for the consumers lag
+	MaxLag          int           // consumer lag failing the readiness probe; zero never fails it
+}
+
+// DefaultOptions fails the readiness as soon as a component is down or blocked
+// and never fails the liveness.
+func DefaultOptions() Options {
+	return Options{
+		BlockedNotReady: true,
+	}
+}
+
+// WithReadyGrace tolerates components being down (ex: recovering) for the given duration
+// before failing the readiness probe.
+func WithReadyGrace(grace time.Duration) func(options *Options) {
+	return func(options *Options) {
+		options.ReadyGrace = grace
+	}
+}
+
+// WithLiveLimit fails the liveness probe when a component is down for longer than limit.
+func WithLiveLimit(limit time.Duration) func(options *Options) {
+	return func(options *Options) {
+		options.LiveLimit = limit
+	}
+}
+
+// WithBlockedNotReady sets whether blocked connections fail the readiness probe.
+func WithBlockedNotReady(notReady bool) func(options *Options) {
+	return func(options *Options) {
+		options.BlockedNotReady = notReady
+	}
+}
+
+// WithPausedNotReady sets whether paused publishers fail the readiness probe.
+func WithPausedNotReady(notReady bool) func(options *Options) {
+	return func(options *Options) {
+		options.PausedNotReady = notReady
+	}
+}
+
+// WithConsumerLag reports the lag of the consumers, queried on each probe,
+// and fails the readiness probe when it exceeds max (zero for never).
+func WithConsumerLag(max int) func(options *Options) {
+	return func(options *Options) {
+		options.InspectLag = true
+		options.MaxLag = max
+	}
+}
+
+// ComponentStatus details the state of a tracked component.
+type ComponentStatus struct {
+	Name        string     `json:"name"`                    // connection or channel tag
+	Kind        Kind       `json:"kind"`                    // type of component
+	State       string     `json:"state"`                   // StateUp or StateDown
+	Healthy     bool       `json:"healthy"`                 // passes the probe
+	Reason      string     `json:"reason,omitempty"`        // why the probe failed
+	SinceLastUp float64    `json:"since_last_up_seconds"`   // time down, since going down; zero while up
+	Blocked     bool       `json:"blocked"`                 // connection blocked by the broker
+	Paused      bool       `json:"paused"`                  // publishing paused by the broker flow control
+	LastError   string     `json:"last_error,omitempty"`    // latest error event
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"` // time of the latest error event
+	Lag         *int       `json:"lag,omitempty"`           // messages ready in the consumed queue, when known
+}
+
+// Report is the outcome of a probe.
+type Report struct {
+	Status     string            `json:"status"` // StatusOK or StatusFail
+	Components []ComponentStatus `json:"components"`
+}
+
+// Healthy reports whether the probe passed.
+func (r Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+// component is a tracked connection or channel based object.
+type component struct {
+	mu          sync.Mutex
+	name        string
+	kind        Kind
+	state       func() (up, blocked, paused bool)
+	lag         func() (int, error) // nil when unknown
+	downSince   time.Time           // time going down (EventDown or observed down), zero while up
+	lastErr     error
+	lastErrAt   time.Time
+	unsubscribe func()
+}
+
+// track records the errors and the up and down transitions of the component events
+// till the subscription ends.
+func (c *component) track(events <-chan grabbit.Event) {
+	for event := range events {
+		c.mu.Lock()
+		if err := event.Err.Or(nil); err != nil {
+			c.lastErr, c.lastErrAt = err, time.Now()
+		}
+		switch event.Kind {
+		case grabbit.EventUp:
+			c.downSince = time.Time{}
+		case grabbit.EventDown:
+			c.wentDown(time.Now())
+		}
+		c.mu.Unlock()
+	}
+}
+
+// wentDown records the time going down, unless already down.
+func (c *component) wentDown(at time.Time) {
+	if c.downSince.IsZero() {
+		c.downSince = at
+	}
+}
+
+// Checker tracks a set of components and evaluates the probes.
+// Create one by calling [NewChecker].
+type Checker struct {
+	mu         sync.RWMutex
+	opt        Options
+	components []*component
+}
+
+// NewChecker creates a checker without components, with [DefaultOptions]
+// altered by the option functions.
+func NewChecker(optionFuncs ...func(*Options)) *Checker {
+	opt := DefaultOptions()
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&opt)
+	}
+
+	return &Checker{opt: opt}
+}
+
+// AddConnection tracks a connection.
+func (c *Checker) AddConnection(conn *grabbit.Connection) {
+	events, unsubscribe := conn.Subscribe(grabbit.EventsFrom(conn.Name()))
+	c.add(&component{
+		name: conn.Name(),
+		kind: KindConnection,
+		state: func() (bool, bool, bool) {
+			return !conn.IsClosed(), conn.IsBlocked(), false
+		},
+		unsubscribe: unsubscribe,
+	}, events)
+}
+
+// AddChannel tracks a channel.
+func (c *Checker) AddChannel(ch *grabbit.Channel) {
+	c.addChannel(ch, KindChannel, nil)
+}
+
+// AddPublisher tracks a publisher through its channel.
+func (c *Checker) AddPublisher(p *grabbit.Publisher) {
+	c.addChannel(p.Channel(), KindPublisher, nil)
+}
+
+// AddConsumer tracks a consumer through its channel.
+func (c *Checker) AddConsumer(consumer *grabbit.Consumer) {
+	c.addChannel(consumer.Channel(), KindConsumer, consumer.Lag)
+}
+
+// addChannel tracks a channel based component.
+func (c *Checker) addChannel(ch *grabbit.Channel, kind Kind, lag func() (int, error)) {
+	events, unsubscribe := ch.Subscribe(grabbit.EventsFrom(ch.Name()))
+	c.add(&component{
+		name: ch.Name(),
+		kind: kind,
+		state: func() (bool, bool, bool) {
+			return !ch.IsClosed(), ch.Connection().IsBlocked(), ch.IsPaused()
+		},
+		lag:         lag,
+		unsubscribe: unsubscribe,
+	}, events)
+}
+
+// add registers the component and starts tracking its events.
+func (c *Checker) add(comp *component, events <-chan grabbit.Event) {
+	if up, _, _ := comp.state(); !up {
+		comp.downSince = time.Now()
+	}
+	go comp.track(events)
+
+	c.mu.Lock()
+	c.components = append(c.components, comp)
+	c.mu.Unlock()
+}
+
+// Close stops tracking the events of all components. The probes
+// keep reporting their state, without the latest errors.
+func (c *Checker) Close() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, comp := range c.components {
+		comp.unsubscribe()
+	}
+}
+
+// Liveness evaluates the liveness probe: it fails only when a component is down
+// for longer than [Options.LiveLimit].
+func (c *Checker) Liveness() Report {
+	return c.evaluate(false)
+}
+
+// Readiness evaluates the readiness probe: it fails when a component is down for longer
+// than [Options.ReadyGrace], blocked or paused (as configured) or lagging more than [Options.MaxLag].
+func (c *Checker) Readiness() Report {
+	return c.evaluate(true)
+}
+
+// evaluate builds the report of the readiness or liveness probe.
+func (c *Checker) evaluate(readiness bool) Report {
+	c.mu.RLock()
+	components := c.components
+	c.mu.RUnlock()
+
+	report := Report{
+		Status:     StatusOK,
+		Components: make([]ComponentStatus, 0, len(components)),
+	}
+	for _, comp := range components {
+		status := c.inspect(comp, readiness)
+		if !status.Healthy {
+			report.Status = StatusFail
+		}
+		report.Components = append(report.Components, status)
+	}
+
+	return report
+}
+
+// inspect reports the state of a component against the probe thresholds.
+func (c *Checker) inspect(comp *component, readiness bool) ComponentStatus {
+	up, blocked, paused := comp.state()
+	now := time.Now()
+
+	comp.mu.Lock()
+	if up {
+		comp.downSince = time.Time{}
+	} else {
+		// the down event may still be on its way
+		comp.wentDown(now)
+	}
+	status := ComponentStatus{
+		Name:    comp.name,
+		Kind:    comp.kind,
+		State:   StateUp,
+		Healthy: true,
+		Blocked: blocked,
+		Paused:  paused,
+	}
+	downFor := now.Sub(comp.downSince)
+	if comp.lastErr != nil {
+		at := comp.lastErrAt
+		status.LastError, status.LastErrorAt = comp.lastErr.Error(), &at
+	}
+	comp.mu.Unlock()
+
+	if !up {
+		status.State = StateDown
+		status.SinceLastUp = downFor.Seconds()
+	}
+	if up && comp.lag != nil && c.opt.InspectLag {
+		if lag, err := comp.lag(); err == nil {
+			status.Lag = &lag
+		}
+	}
+
+	switch {
+	case !readiness:
+		if !up && c.opt.LiveLimit > 0 && downFor > c.opt.LiveLimit {
+			status.Healthy, status.Reason = false, fmt.Sprintf("down for %s", downFor.Round(time.Second))
+		}
+	case !up && downFor >= c.opt.ReadyGrace:
+		status.Healthy, status.Reason = false, fmt.Sprintf("down for %s", downFor.Round(time.Second))
+	case blocked && c.opt.BlockedNotReady:
+		status.Healthy, status.Reason = false, "connection blocked"
+	case paused && c.opt.PausedNotReady:
+		status.Healthy, status.Reason = false, "publishing paused"
+	case status.Lag != nil && c.opt.MaxLag > 0 && *status.Lag > c.opt.MaxLag:
+		status.Healthy, status.Reason = false, fmt.Sprintf("lag %d exceeds %d", *status.Lag, c.opt.MaxLag)
+	}
+
+	return status
+}
+
+// LivezHandler serves the liveness report as JSON, with status 503 when failing.
+func (c *Checker) LivezHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serve(w, c.Liveness())
+	})
+}
+
+// ReadyzHandler serves the readiness report as JSON, with status 503 when failing.
+func (c *Checker) ReadyzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serve(w, c.Readiness())
+	})
+}
+
+// Handler routes /livez and /readyz to their handlers.
+func (c *Checker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/livez", c.LivezHandler())
+	mux.Handle("/readyz", c.ReadyzHandler())
+	return mux
+}
+
+// serve writes the report.
+func serve(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !report.Healthy() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
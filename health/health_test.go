@@ -0,0 +1,200 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	grabbit "github.com/oarkflow/amqp"
+	amqp "github.com/oarkflow/amqp/amqp091"
+	"github.com/oarkflow/amqp/amqp091/amqptest"
+)
+
+// newTestConnection connects to a new in-process broker through a fault injecting dialer.
+func newTestConnection(t *testing.T) (*grabbit.Connection, *amqptest.Server, *amqptest.FaultDialer) {
+	t.Helper()
+
+	srv := amqptest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+	faults := amqptest.NewFaultDialer(srv.Dial)
+
+	conn := grabbit.NewConnection(srv.URL(), amqp.Config{Dial: faults.Dial},
+		grabbit.WithConnectionName("conn"),
+		grabbit.WithConnectionDelay(grabbit.DefaultDelayer{Value: 20 * time.Millisecond}))
+	t.Cleanup(func() { conn.Close() })
+	eventually(t, func() bool { return !conn.IsClosed() }, "connection not established")
+
+	return conn, srv, faults
+}
+
+// newTestChecker tracks the connection, closing the checker with the test.
+func newTestChecker(t *testing.T, conn *grabbit.Connection, optionFuncs ...func(*Options)) *Checker {
+	t.Helper()
+
+	checker := NewChecker(optionFuncs...)
+	t.Cleanup(checker.Close)
+	checker.AddConnection(conn)
+
+	return checker
+}
+
+// eventually polls the condition for up to 5s.
+func eventually(t *testing.T, condition func() bool, message string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// probe requests the path of the checker handler, decoding the report.
+func probe(t *testing.T, checker *Checker, path string) (int, Report) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	checker.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return rec.Code, report
+}
+
+func TestReportShape(t *testing.T) {
+	conn, _, _ := newTestConnection(t)
+	checker := newTestChecker(t, conn)
+
+	rec := httptest.NewRecorder()
+	checker.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body["status"] != StatusOK {
+		t.Fatalf("status %v", body["status"])
+	}
+	components, _ := body["components"].([]any)
+	if len(components) != 1 {
+		t.Fatalf("components %v", body["components"])
+	}
+	component, _ := components[0].(map[string]any)
+	for _, key := range []string{"name", "kind", "state", "healthy", "since_last_up_seconds", "blocked", "paused"} {
+		if _, found := component[key]; !found {
+			t.Errorf("component without %s: %v", key, component)
+		}
+	}
+	if component["name"] != "conn" || component["kind"] != string(KindConnection) || component["state"] != StateUp {
+		t.Fatalf("component %v", component)
+	}
+}
+
+func TestDowntimeThresholds(t *testing.T) {
+	conn, _, faults := newTestConnection(t)
+	checker := newTestChecker(t, conn,
+		WithReadyGrace(200*time.Millisecond),
+		WithLiveLimit(400*time.Millisecond))
+
+	// probed up long before going down: the downtime starts with going down
+	if code, _ := probe(t, checker, "/readyz"); code != http.StatusOK {
+		t.Fatalf("readyz %d", code)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	faults.RejectDials(-1)
+	faults.Sever()
+	eventually(t, conn.IsClosed, "connection not down")
+
+	code, report := probe(t, checker, "/readyz")
+	if code != http.StatusOK || report.Components[0].State != StateDown {
+		t.Fatalf("readyz %d within the grace: %+v", code, report)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	code, report = probe(t, checker, "/readyz")
+	if code != http.StatusServiceUnavailable || report.Status != StatusFail || report.Components[0].Reason == "" {
+		t.Fatalf("readyz %d past the grace: %+v", code, report)
+	}
+	if down := report.Components[0].SinceLastUp; down < 0.2 || down > 0.4 {
+		t.Fatalf("down for %vs", down)
+	}
+	if code, _ := probe(t, checker, "/livez"); code != http.StatusOK {
+		t.Fatalf("livez %d within the limit", code)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if code, _ := probe(t, checker, "/livez"); code != http.StatusServiceUnavailable {
+		t.Fatalf("livez %d past the limit", code)
+	}
+
+	faults.Heal()
+	eventually(t, func() bool { return !conn.IsClosed() }, "connection not recovered")
+	code, report = probe(t, checker, "/livez")
+	if code != http.StatusOK || report.Components[0].SinceLastUp != 0 {
+		t.Fatalf("livez %d once recovered: %+v", code, report)
+	}
+}
+
+func TestBlockedConnection(t *testing.T) {
+	conn, srv, _ := newTestConnection(t)
+	checker := newTestChecker(t, conn)
+	tolerant := newTestChecker(t, conn, WithBlockedNotReady(false))
+
+	srv.Block("low on memory")
+	eventually(t, conn.IsBlocked, "connection not blocked")
+
+	code, report := probe(t, checker, "/readyz")
+	if code != http.StatusServiceUnavailable || !report.Components[0].Blocked {
+		t.Fatalf("readyz %d: %+v", code, report)
+	}
+	if code, _ := probe(t, checker, "/livez"); code != http.StatusOK {
+		t.Fatalf("livez %d", code)
+	}
+	code, report = probe(t, tolerant, "/readyz")
+	if code != http.StatusOK || !report.Components[0].Blocked {
+		t.Fatalf("tolerant readyz %d: %+v", code, report)
+	}
+
+	srv.Unblock()
+	eventually(t, func() bool { return !conn.IsBlocked() }, "connection not unblocked")
+	if code, _ := probe(t, checker, "/readyz"); code != http.StatusOK {
+		t.Fatalf("readyz %d once unblocked", code)
+	}
+}
+
+func TestPausedPublisher(t *testing.T) {
+	conn, srv, _ := newTestConnection(t)
+	publisher := grabbit.NewPublisher(conn, grabbit.DefaultPublisherOptions(),
+		grabbit.WithChannelName("pub"),
+		grabbit.WithChannelDelay(grabbit.DefaultDelayer{Value: 20 * time.Millisecond}))
+	t.Cleanup(func() { publisher.Close() })
+	eventually(t, func() bool { return !publisher.Channel().IsClosed() }, "publisher not established")
+
+	checker := NewChecker(WithPausedNotReady(true))
+	t.Cleanup(checker.Close)
+	checker.AddPublisher(publisher)
+	tolerant := NewChecker()
+	t.Cleanup(tolerant.Close)
+	tolerant.AddPublisher(publisher)
+
+	srv.Flow(false)
+	eventually(t, publisher.Channel().IsPaused, "publisher not paused")
+
+	code, report := probe(t, checker, "/readyz")
+	if code != http.StatusServiceUnavailable || !report.Components[0].Paused || report.Components[0].Kind != KindPublisher {
+		t.Fatalf("readyz %d: %+v", code, report)
+	}
+	if code, report := probe(t, tolerant, "/readyz"); code != http.StatusOK || !report.Components[0].Paused {
+		t.Fatalf("tolerant readyz %d: %+v", code, report)
+	}
+}
@@ -0,0 +1,69 @@
+package grabbit
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+	"github.com/oarkflow/amqp/amqp091/amqptest"
+)
+
+// testDelay keeps the recoveries of the tests short.
+var testDelay = DefaultDelayer{Value: 50 * time.Millisecond}
+
+// newTestBroker starts an in-process broker behind a fault injecting dialer.
+func newTestBroker(t *testing.T) (*amqptest.Server, *amqptest.FaultDialer) {
+	t.Helper()
+
+	srv := amqptest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	return srv, amqptest.NewFaultDialer(srv.Dial)
+}
+
+// newTestConnection connects to the broker through the dialer, closing the connection with the test.
+func newTestConnection(t *testing.T, srv *amqptest.Server, faults *amqptest.FaultDialer, optionFuncs ...func(*ConnectionOptions)) *Connection {
+	t.Helper()
+
+	conn := NewConnection(srv.URL(), amqp.Config{Dial: faults.Dial},
+		append([]func(*ConnectionOptions){WithConnectionDelay(testDelay)}, optionFuncs...)...)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// testQueue is a topology declaring a durable queue for the publisher or consumer.
+func testQueue(name string) []*TopologyOptions {
+	return []*TopologyOptions{{Name: name, Durable: true, Declare: true, IsDestination: true}}
+}
+
+// eventually polls the condition till it holds or the timeout expires.
+func eventually(t *testing.T, timeout time.Duration, condition func() bool, format string, args ...any) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf(format, args...)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newTestPublisher opens a publisher and waits for its channel being in confirm mode.
+func newTestPublisher(t *testing.T, conn *Connection, opt PublisherOptions, optionFuncs ...func(*ChannelOptions)) *Publisher {
+	t.Helper()
+
+	pub := NewPublisher(conn, opt, append([]func(*ChannelOptions){WithChannelDelay(testDelay)}, optionFuncs...)...)
+	t.Cleanup(func() { pub.Close() })
+
+	// unroutable probe, dropped by the broker once confirmed
+	probe := opt
+	probe.WithExchange("").WithKey("")
+	eventually(t, 5*time.Second, func() bool {
+		confirmation, err := pub.PublishDeferredConfirmWithOptions(probe, amqp.Publishing{})
+		return err == nil && pub.AwaitDeferredConfirmation(confirmation, time.Second).Outcome == ConfirmationACK
+	}, "publisher not in confirm mode")
+
+	return pub
+}
@@ -0,0 +1,80 @@
+package grabbit
+
+import (
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// PublishFunc defines a function type sending one message with the given options.
+// The confirmation is nil for the publishing methods not returning one
+// (ex: [Publisher.Publish]) and for messages dropped by an interceptor.
+type PublishFunc func(opt PublisherOptions, msg amqp.Publishing) (*DeferredConfirmation, error)
+
+// PublishInterceptor wraps the publishing of messages: it may transform the message
+// (ex: [CompressionInterceptor]), observe the outcome or abort by returning an
+// error without calling next. Register them via [PublisherOptions.WithPublishInterceptors].
+type PublishInterceptor func(next PublishFunc) PublishFunc
+
+// chainPublish composes the interceptors around the final publishing function;
+// the first interceptor is the outermost one.
+func chainPublish(interceptors []PublishInterceptor, final PublishFunc) PublishFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		final = interceptors[i](final)
+	}
+	return final
+}
+
+// ConsumeInterceptor wraps the processing of received messages, either per message
+// (see [ConsumerOptions.WithHandler]) or per batch (see [WithChannelProcessor]).
+// Register them via [WithConsumeInterceptors] or [ConsumerOptions.WithConsumeInterceptors].
+// Use [HandlerInterceptor] or [ProcessorInterceptor] for wrapping a single mode.
+type ConsumeInterceptor interface {
+	InterceptHandler(next DeliveryHandler) DeliveryHandler
+	InterceptProcessor(next CallbackProcessMessages) CallbackProcessMessages
+}
+
+// HandlerInterceptor adapts a function to a [ConsumeInterceptor] wrapping only the per message handlers.
+type HandlerInterceptor func(next DeliveryHandler) DeliveryHandler
+
+// InterceptHandler implements the [ConsumeInterceptor] interface.
+func (f HandlerInterceptor) InterceptHandler(next DeliveryHandler) DeliveryHandler {
+	return f(next)
+}
+
+// InterceptProcessor implements the [ConsumeInterceptor] interface, leaving the processor unchanged.
+func (f HandlerInterceptor) InterceptProcessor(next CallbackProcessMessages) CallbackProcessMessages {
+	return next
+}
+
+// ProcessorInterceptor adapts a function to a [ConsumeInterceptor] wrapping only the batch processors.
+type ProcessorInterceptor func(next CallbackProcessMessages) CallbackProcessMessages
+
+// InterceptHandler implements the [ConsumeInterceptor] interface, leaving the handler unchanged.
+func (f ProcessorInterceptor) InterceptHandler(next DeliveryHandler) DeliveryHandler {
+	return next
+}
+
+// InterceptProcessor implements the [ConsumeInterceptor] interface.
+func (f ProcessorInterceptor) InterceptProcessor(next CallbackProcessMessages) CallbackProcessMessages {
+	return f(next)
+}
+
+// WithConsumeInterceptors appends interceptors wrapping the message processing of a
+// consumer channel; the first one is the outermost. They compose around the final
+// processor or handler regardless of the order of the option functions.
+func WithConsumeInterceptors(interceptors ...ConsumeInterceptor) func(options *ChannelOptions) {
+	return func(options *ChannelOptions) {
+		options.consumeInterceptors = append(options.consumeInterceptors, interceptors...)
+	}
+}
+
+// chainConsume composes the interceptors around the message processing function in use
+// (the handler when set, the processor otherwise); the first interceptor is the outermost one.
+func chainConsume(opt *ChannelOptions) {
+	for i := len(opt.consumeInterceptors) - 1; i >= 0; i-- {
+		if opt.handler != nil {
+			opt.handler = opt.consumeInterceptors[i].InterceptHandler(opt.handler)
+		} else {
+			opt.cbProcessMessages = opt.consumeInterceptors[i].InterceptProcessor(opt.cbProcessMessages)
+		}
+	}
+}
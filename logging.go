@@ -0,0 +1,143 @@
+package grabbit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// LogLevels sets the level of the records of each logging category. Lowering a
+// category below the level enabled by the logger handler silences it,
+// ex: Recovery set to [slog.LevelDebug] mutes the failed attempts of the reconnect loops.
+type LogLevels struct {
+	Lifecycle slog.Level // connections and channels going up, down, closed, blocked or unblocked
+	Recovery  slog.Level // failed attempts of (re)establishing connections and channels
+	Topology  slog.Level // exchanges and queues declared
+	Confirms  slog.Level // positive publishing confirmations and returned messages
+	Errors    slog.Level // protocol errors: qos, consume, confirm mode, topology failures, negative confirmations
+}
+
+// DefaultLogLevels returns the levels used unless overridden via
+// [WithConnectionLogLevels] or [WithChannelLogLevels].
+func DefaultLogLevels() LogLevels {
+	return LogLevels{
+		Lifecycle: slog.LevelInfo,
+		Recovery:  slog.LevelWarn,
+		Topology:  slog.LevelDebug,
+		Confirms:  slog.LevelDebug,
+		Errors:    slog.LevelError,
+	}
+}
+
+// WithConnectionLogger enables structured logging of the connection and, unless
+// overridden by [WithChannelLogger], of its channels. The records carry the
+// "connection" name and "vhost" attributes. Logging is disabled by default.
+// The logger also receives the protocol level records (see [amqp.Config].Logger),
+// unless the configuration passed to [NewConnection] sets its own.
+func WithConnectionLogger(logger *slog.Logger) func(options *ConnectionOptions) {
+	return func(options *ConnectionOptions) {
+		options.logger = logger
+	}
+}
+
+// WithConnectionLogLevels sets the levels of the logging categories
+// for the connection and its channels.
+func WithConnectionLogLevels(levels LogLevels) func(options *ConnectionOptions) {
+	return func(options *ConnectionOptions) {
+		options.logLevels = levels
+	}
+}
+
+// WithChannelLogger sets the structured logger of the channel. The records
+// carry the "channel" name and "channel_id" attributes.
+func WithChannelLogger(logger *slog.Logger) func(options *ChannelOptions) {
+	return func(options *ChannelOptions) {
+		options.logger = logger
+	}
+}
+
+// WithChannelLogLevels sets the levels of the logging categories for the channel.
+func WithChannelLogLevels(levels LogLevels) func(options *ChannelOptions) {
+	return func(options *ChannelOptions) {
+		options.logLevels = levels
+	}
+}
+
+// connectionLogger returns the logger annotated with the connection
+// attributes or nil when logging is disabled.
+func connectionLogger(logger *slog.Logger, name, address string, config amqp.Config) *slog.Logger {
+	if logger == nil {
+		return nil
+	}
+
+	vhost := config.Vhost
+	if vhost == "" {
+		if uri, err := amqp.ParseURI(address); err == nil {
+			vhost = uri.Vhost
+		}
+	}
+
+	return logger.With(slog.String("connection", name), slog.String("vhost", vhost))
+}
+
+// logAttrs emits a record when logging is enabled for its level, with the error
+// (and its reply code, if sent by the server) as attributes.
+func logAttrs(logger *slog.Logger, level slog.Level, msg string, err error, attrs ...slog.Attr) {
+	if logger == nil || !logger.Enabled(context.Background(), level) {
+		return
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+
+		var amqpErr *amqp.Error
+		if errors.As(err, &amqpErr) {
+			attrs = append(attrs,
+				slog.Int("reply_code", amqpErr.Code),
+				slog.Bool("server", amqpErr.Server))
+		}
+	}
+
+	logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+// log emits a record of the connection.
+func (conn *Connection) log(level slog.Level, msg string, err error, attrs ...slog.Attr) {
+	logAttrs(conn.opt.logger, level, msg, err, attrs...)
+}
+
+// log emits a record of the channel, carrying the current base channel number.
+func (ch *Channel) log(level slog.Level, msg string, err error, attrs ...slog.Attr) {
+	if ch.opt.logger == nil {
+		return
+	}
+
+	attrs = append(attrs, slog.Int("channel_id", int(ch.id.Load())))
+	logAttrs(ch.opt.logger, level, msg, err, attrs...)
+}
+
+// logConfirm emits a record of a publishing confirmation, negative ones as errors.
+func (ch *Channel) logConfirm(confirm amqp.Confirmation) {
+	if confirm.Ack {
+		ch.log(ch.opt.logLevels.Confirms, "publishing confirmed", nil,
+			slog.Uint64("delivery_tag", confirm.DeliveryTag))
+		return
+	}
+	ch.log(ch.opt.logLevels.Errors, "publishing not acknowledged", nil,
+		slog.Uint64("delivery_tag", confirm.DeliveryTag))
+}
+
+// logTopology emits a record of an exchange or queue declaration, failures as errors.
+func (ch *Channel) logTopology(t *TopologyOptions, name string, err error) {
+	kind := "queue"
+	if t.IsExchange {
+		kind = "exchange"
+	}
+	if err != nil {
+		ch.log(ch.opt.logLevels.Errors, kind+" declaration failed", err, slog.String(kind, t.Name))
+		return
+	}
+	ch.log(ch.opt.logLevels.Topology, kind+" declared", nil, slog.String(kind, name))
+}
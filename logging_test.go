@@ -0,0 +1,54 @@
+package grabbit
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// logBuffer collects the log records written concurrently.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestProtocolLoggingPerConnection(t *testing.T) {
+	srv, faults := newTestBroker(t)
+
+	var first, second logBuffer
+	newTestConnection(t, srv, faults, WithConnectionName("first"),
+		WithConnectionLogger(slog.New(slog.NewTextHandler(&first, nil))))
+	newTestConnection(t, srv, faults, WithConnectionName("second"),
+		WithConnectionLogger(slog.New(slog.NewTextHandler(&second, nil))))
+	eventually(t, 5*time.Second, func() bool { return srv.Connections() == 2 }, "connections not established")
+
+	srv.CloseConnections(amqp.ConnectionForced, "maintenance")
+
+	for name, logs := range map[string]*logBuffer{"first": &first, "second": &second} {
+		eventually(t, 5*time.Second, func() bool {
+			return strings.Contains(logs.String(), "connection closed by server")
+		}, "%s: protocol record not logged", name)
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			if !strings.Contains(line, "connection="+name) {
+				t.Fatalf("%s: record of another connection: %s", name, line)
+			}
+		}
+	}
+}
@@ -0,0 +1,427 @@
+package grabbit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics is a registry of counters, gauges and histograms about the connections and
+// channels it is passed to (see [WithConnectionMetrics], [WithChannelMetrics]).
+// Unlike the notification events, nothing gets dropped. It exposes the Prometheus
+// text format via [Metrics.Handler] and has no dependency. Create it with [NewMetrics];
+// the same registry can be shared by several connections.
+type Metrics struct {
+	mu       sync.Mutex
+	families []*metricFamily                   // in exposition order
+	byName   map[string]*metricFamily          // lookup
+	pending  map[*Channel]map[uint64]time.Time // publishing times by channel and sequence number
+	early    map[*Channel]map[uint64]time.Time // confirmation times received ahead of recording their publishing
+}
+
+// metricFamily groups the series of a metric.
+type metricFamily struct {
+	name    string
+	help    string
+	kind    string    // counter, gauge or histogram
+	labels  []string  // label names
+	buckets []float64 // histogram upper bounds, ascending
+	series  map[string]*metricSeries
+}
+
+// metricSeries holds the values of one combination of labels.
+type metricSeries struct {
+	labels []string // label values
+	value  float64  // counter or gauge value
+	counts []uint64 // histogram bucket counts (not cumulated)
+	sum    float64  // histogram sum of the observations
+	count  uint64   // histogram number of the observations
+}
+
+// maxPendingConfirms bounds the publishing times kept per channel for
+// measuring the confirmation latency.
+const maxPendingConfirms = 1 << 16
+
+// Names of the metrics exposed by the registry.
+const (
+	metricAttempts      = "grabbit_reconnect_attempts_total"
+	metricUp            = "grabbit_up"
+	metricUps           = "grabbit_up_transitions_total"
+	metricDowns         = "grabbit_down_transitions_total"
+	metricFailures      = "grabbit_establish_failures_total"
+	metricBlocked       = "grabbit_blocked_total"
+	metricPublished     = "grabbit_published_total"
+	metricConfirms      = "grabbit_confirms_total"
+	metricConfirmTime   = "grabbit_confirm_latency_seconds"
+	metricReturned      = "grabbit_returned_total"
+	metricBatchSize     = "grabbit_consumer_batch_size"
+	metricProcessTime   = "grabbit_processing_seconds"
+	metricDataPartial   = "grabbit_data_partial_total"
+	metricDataExhausted = "grabbit_data_exhausted_total"
+)
+
+// NewMetrics creates an empty registry.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		byName:  make(map[string]*metricFamily),
+		pending: make(map[*Channel]map[uint64]time.Time),
+		early:   make(map[*Channel]map[uint64]time.Time),
+	}
+
+	latency := []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	sizes := []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+	m.define(metricAttempts, "counter", "Connection or channel (re)establishing attempts.", nil, "source", "name")
+	m.define(metricUp, "gauge", "Whether the connection or channel is up (1) or down (0).", nil, "source", "name")
+	m.define(metricUps, "counter", "Transitions to the up state.", nil, "source", "name")
+	m.define(metricDowns, "counter", "Transitions to the down state.", nil, "source", "name")
+	m.define(metricFailures, "counter", "Failed (re)establishing attempts.", nil, "source", "name")
+	m.define(metricBlocked, "counter", "Flow control activations (connection blocked, channel paused).", nil, "source", "name")
+	m.define(metricPublished, "counter", "Messages passed to the broker.", nil, "channel")
+	m.define(metricConfirms, "counter", "Publisher confirmations received, by outcome (ack, nack).", nil, "channel", "outcome")
+	m.define(metricConfirmTime, "histogram", "Delay between publishing and its confirmation.", latency, "channel")
+	m.define(metricReturned, "counter", "Unroutable messages returned by the broker.", nil, "channel")
+	m.define(metricBatchSize, "histogram", "Deliveries per batch passed to the consumer processor.", sizes, "channel")
+	m.define(metricProcessTime, "histogram", "Duration of the consumer processing, per batch or per message.", latency, "channel")
+	m.define(metricDataPartial, "counter", "Consumer batches processed before being full (EventDataPartial).", nil, "channel")
+	m.define(metricDataExhausted, "counter", "Consumer prefetch timeouts without data (EventDataExhausted).", nil, "channel")
+
+	return m
+}
+
+// define registers a metric family.
+func (m *Metrics) define(name, kind, help string, buckets []float64, labels ...string) {
+	family := &metricFamily{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*metricSeries),
+	}
+	m.families = append(m.families, family)
+	m.byName[name] = family
+}
+
+// seriesOf returns (creating it) the series of the family for the label values.
+// Must be called with the lock held.
+func (m *Metrics) seriesOf(name string, labels ...string) *metricSeries {
+	family := m.byName[name]
+	key := strings.Join(labels, "\xff")
+
+	series, ok := family.series[key]
+	if !ok {
+		series = &metricSeries{labels: labels}
+		if family.kind == "histogram" {
+			series.counts = make([]uint64, len(family.buckets))
+		}
+		family.series[key] = series
+	}
+
+	return series
+}
+
+// add increments a counter.
+func (m *Metrics) add(name string, delta float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.seriesOf(name, labels...).value += delta
+}
+
+// set assigns a gauge.
+func (m *Metrics) set(name string, value float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.seriesOf(name, labels...).value = value
+}
+
+// observe records a histogram observation.
+func (m *Metrics) observe(name string, value float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.observeLocked(name, value, labels...)
+}
+
+// observeLocked records a histogram observation with the lock held.
+func (m *Metrics) observeLocked(name string, value float64, labels ...string) {
+	series := m.seriesOf(name, labels...)
+	buckets := m.byName[name].buckets
+	if i := sort.SearchFloat64s(buckets, value); i < len(buckets) {
+		series.counts[i]++
+	}
+	series.sum += value
+	series.count++
+}
+
+// attempt counts a (re)establishing attempt.
+func (m *Metrics) attempt(source ClientType, name string) {
+	if m != nil {
+		m.add(metricAttempts, 1, source.String(), name)
+	}
+}
+
+// transition records an up or down transition, or a failed establishing attempt.
+func (m *Metrics) transition(source ClientType, name string, kind EventType) {
+	if m == nil {
+		return
+	}
+
+	switch kind {
+	case EventUp:
+		m.add(metricUps, 1, source.String(), name)
+		m.set(metricUp, 1, source.String(), name)
+	case EventDown:
+		m.add(metricDowns, 1, source.String(), name)
+		m.set(metricUp, 0, source.String(), name)
+	case EventCannotEstablish:
+		m.add(metricFailures, 1, source.String(), name)
+	}
+}
+
+// blocked counts the flow control activations.
+func (m *Metrics) blocked(source ClientType, name string) {
+	if m != nil {
+		m.add(metricBlocked, 1, source.String(), name)
+	}
+}
+
+// published counts a message and, for the confirming channels, remembers its
+// publishing time for the confirmation latency. The tag (delivery tag of the
+// deferred confirmation) is 0 when the channel is not in confirm mode.
+// The times are kept by channel instance, as several channels may share a name.
+func (m *Metrics) published(ch *Channel, tag uint64, sent time.Time, confirming bool) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.seriesOf(metricPublished, ch.opt.name).value++
+	if !confirming || tag == 0 {
+		return
+	}
+	if received, ok := m.early[ch][tag]; ok {
+		delete(m.early[ch], tag)
+		m.observeLocked(metricConfirmTime, received.Sub(sent).Seconds(), ch.opt.name)
+		return
+	}
+	remember(m.pending, ch, tag, sent)
+}
+
+// remember records the time of a tag, within the bounds of maxPendingConfirms.
+func remember(times map[*Channel]map[uint64]time.Time, ch *Channel, tag uint64, at time.Time) {
+	byTag, ok := times[ch]
+	if !ok {
+		byTag = make(map[uint64]time.Time)
+		times[ch] = byTag
+	}
+	if len(byTag) < maxPendingConfirms {
+		byTag[tag] = at
+	}
+}
+
+// confirmed counts a publisher confirmation and observes its latency.
+func (m *Metrics) confirmed(ch *Channel, tag uint64, ack bool) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	outcome := "nack"
+	if ack {
+		outcome = "ack"
+	}
+	m.seriesOf(metricConfirms, ch.opt.name, outcome).value++
+
+	if sent, ok := m.pending[ch][tag]; ok {
+		delete(m.pending[ch], tag)
+		m.observeLocked(metricConfirmTime, time.Since(sent).Seconds(), ch.opt.name)
+	} else {
+		// confirmed before the publisher got to record it
+		remember(m.early, ch, tag, time.Now())
+	}
+}
+
+// rebased forgets the publishing and early confirmation times of a channel
+// whose sequence numbers restart, or which closed for good.
+func (m *Metrics) rebased(ch *Channel) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.pending, ch)
+	delete(m.early, ch)
+}
+
+// returned counts an unroutable message.
+func (m *Metrics) returned(channel string) {
+	if m != nil {
+		m.add(metricReturned, 1, channel)
+	}
+}
+
+// processed observes a consumer batch (size > 0) or a single delivery (size 0).
+func (m *Metrics) processed(channel string, size int, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+
+	if size > 0 {
+		m.observe(metricBatchSize, float64(size), channel)
+	}
+	m.observe(metricProcessTime, elapsed.Seconds(), channel)
+}
+
+// prefetched counts the consumer prefetch timeouts, with or without pending data.
+func (m *Metrics) prefetched(channel string, kind EventType) {
+	if m == nil {
+		return
+	}
+
+	if kind == EventDataPartial {
+		m.add(metricDataPartial, 1, channel)
+	} else {
+		m.add(metricDataExhausted, 1, channel)
+	}
+}
+
+// WriteTo writes all the metrics in the Prometheus text exposition format (version 0.0.4).
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, family := range m.families {
+		if len(family.series) == 0 {
+			continue
+		}
+
+		cw.printf("# HELP %s %s\n# TYPE %s %s\n", family.name, family.help, family.name, family.kind)
+
+		keys := make([]string, 0, len(family.series))
+		for key := range family.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		bucketLabels := append(slices.Clip(family.labels), "le")
+		for _, key := range keys {
+			series := family.series[key]
+			labels := formatLabels(family.labels, series.labels)
+
+			if family.kind != "histogram" {
+				cw.printf("%s%s %s\n", family.name, labels, formatFloat(series.value))
+				continue
+			}
+
+			var cumulated uint64
+			for i, bound := range family.buckets {
+				cumulated += series.counts[i]
+				cw.printf("%s_bucket%s %d\n", family.name,
+					formatLabels(bucketLabels, append(slices.Clip(series.labels), formatFloat(bound))), cumulated)
+			}
+			cw.printf("%s_bucket%s %d\n", family.name,
+				formatLabels(bucketLabels, append(slices.Clip(series.labels), "+Inf")), series.count)
+			cw.printf("%s_sum%s %s\n", family.name, labels, formatFloat(series.sum))
+			cw.printf("%s_count%s %d\n", family.name, labels, series.count)
+		}
+	}
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// Handler returns an http.Handler serving the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = m.WriteTo(w)
+	})
+}
+
+// countingWriter remembers the written bytes and the first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+// printf writes unless a previous write failed.
+func (cw *countingWriter) printf(format string, args ...any) {
+	if cw.err != nil {
+		return
+	}
+	var n int
+	n, cw.err = io.WriteString(cw.w, fmt.Sprintf(format, args...))
+	cw.n += int64(n)
+}
+
+// formatLabels renders the label set, escaped as the exposition format requires.
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteString(`="`)
+		sb.WriteString(labelEscaper.Replace(values[i]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+
+	return sb.String()
+}
+
+// labelEscaper escapes the label values.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatFloat renders a sample value.
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// WithConnectionMetrics feeds the registry from the connection and, unless overridden
+// with [WithChannelMetrics], from the channels created on it.
+func WithConnectionMetrics(m *Metrics) func(options *ConnectionOptions) {
+	return func(options *ConnectionOptions) {
+		options.metrics = m
+	}
+}
+
+// WithChannelMetrics feeds the registry from the channel; nil disables its metrics.
+func WithChannelMetrics(m *Metrics) func(options *ChannelOptions) {
+	return func(options *ChannelOptions) {
+		options.metrics = m
+	}
+}
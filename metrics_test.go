@@ -0,0 +1,126 @@
+package grabbit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// scrape returns the exposition of the metrics handler.
+func scrape(t *testing.T, metrics *Metrics) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("content type %q", ct)
+	}
+
+	return rec.Body.String()
+}
+
+func TestMetricsHandlerSharedChannelName(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	metrics := NewMetrics()
+	conn := newTestConnection(t, srv, faults, WithConnectionName("metered"), WithConnectionMetrics(metrics))
+
+	// both channels keep the default name, their sequence numbers overlap
+	opt := DefaultPublisherOptions()
+	opt.WithKey("metered")
+	publishers := []*Publisher{
+		newTestPublisher(t, conn, opt, WithChannelTopology(testQueue("metered"))),
+		newTestPublisher(t, conn, opt),
+	}
+	for i := 0; i < 50; i++ {
+		confirmations := make([]*DeferredConfirmation, len(publishers))
+		for j, pub := range publishers {
+			var err error
+			if confirmations[j], err = pub.PublishDeferredConfirm(amqp.Publishing{Body: []byte("msg")}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for j, pub := range publishers {
+			if outcome := pub.AwaitDeferredConfirmation(confirmations[j], 5*time.Second).Outcome; outcome != ConfirmationACK {
+				t.Fatalf("outcome %v", outcome)
+			}
+		}
+	}
+
+	var published, measured float64
+	eventually(t, 5*time.Second, func() bool {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+
+		published = metrics.seriesOf(metricPublished, "default").value
+		measured = float64(metrics.seriesOf(metricConfirmTime, "default").count)
+		return published == measured
+	}, "%v publishings, %v latencies measured", published, measured)
+
+	exposition := scrape(t, metrics)
+	for _, line := range []string{
+		"# TYPE grabbit_up gauge",
+		`grabbit_up{source="Connection",name="metered"} 1`,
+		`grabbit_up{source="Channel",name="default"} 1`,
+		`grabbit_up_transitions_total{source="Channel",name="default"} 2`,
+		"# TYPE grabbit_published_total counter",
+		`grabbit_published_total{channel="default"} ` + formatFloat(published),
+		`grabbit_confirms_total{channel="default",outcome="ack"} ` + formatFloat(published),
+		"# TYPE grabbit_confirm_latency_seconds histogram",
+		`grabbit_confirm_latency_seconds_bucket{channel="default",le="+Inf"} ` + formatFloat(published),
+		`grabbit_confirm_latency_seconds_count{channel="default"} ` + formatFloat(published),
+	} {
+		if !strings.Contains(exposition, line+"\n") {
+			t.Errorf("no %q in\n%s", line, exposition)
+		}
+	}
+	if strings.Contains(exposition, "grabbit_returned_total") {
+		t.Error("families without series exposed")
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	for _, pub := range publishers {
+		if pending, early := len(metrics.pending[pub.Channel()]), len(metrics.early[pub.Channel()]); pending+early != 0 {
+			t.Errorf("%d pending, %d early confirmation times left", pending, early)
+		}
+	}
+}
+
+func TestMetricsForgetTimesOnRebase(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	metrics := NewMetrics()
+	conn := newTestConnection(t, srv, faults, WithConnectionMetrics(metrics))
+	pub := newTestPublisher(t, conn, DefaultPublisherOptions())
+	ch := pub.Channel()
+
+	// times left behind by the previous base channel
+	metrics.mu.Lock()
+	remember(metrics.pending, ch, 1000, time.Now())
+	remember(metrics.early, ch, 1001, time.Now())
+	metrics.mu.Unlock()
+
+	faults.Sever()
+	eventually(t, 5*time.Second, func() bool {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+
+		return metrics.seriesOf(metricUps, CliChannel.String(), ch.Name()).value == 2
+	}, "channel not recovered")
+
+	metrics.mu.Lock()
+	_, pending := metrics.pending[ch]
+	_, early := metrics.early[ch]
+	metrics.mu.Unlock()
+	if pending || early {
+		t.Fatalf("times kept over the rebase: pending %v, early %v", pending, early)
+	}
+
+	exposition := scrape(t, metrics)
+	if !strings.Contains(exposition, `grabbit_down_transitions_total{source="Channel",name="default"} 1`+"\n") {
+		t.Fatalf("down transition not exposed in\n%s", exposition)
+	}
+}
@@ -0,0 +1,57 @@
+package grabbit
+
+import (
+	"context"
+)
+
+// PoolOptions defines the connections owned by a [Pool].
+//
+// Attributes can be set via optionFuncs parameters of [NewPool]
+// via WithPool<Fct> family, ex: [WithPoolSize], [WithPoolConnectionOptions].
+type PoolOptions struct {
+	name        string                             // prefix of the connection names
+	size        int                                // connections per traffic type
+	ctx         context.Context                    // cancellation context
+	connOptions []func(options *ConnectionOptions) // applied to every connection
+}
+
+// DefaultPoolOptions creates one connection for publishing and one for consuming.
+func DefaultPoolOptions() PoolOptions {
+	return PoolOptions{
+		name: "pool",
+		size: 1,
+		ctx:  context.Background(),
+	}
+}
+
+// WithPoolName sets the prefix of the connection names, followed by the traffic
+// type and index, ex: "pool.publish.0", "pool.consume.0".
+func WithPoolName(name string) func(options *PoolOptions) {
+	return func(options *PoolOptions) {
+		options.name = name
+	}
+}
+
+// WithPoolSize sets the number of connections for each traffic type, i.e.
+// the pool owns size connections for publishing and size for consuming.
+func WithPoolSize(size int) func(options *PoolOptions) {
+	return func(options *PoolOptions) {
+		options.size = max(size, 1)
+	}
+}
+
+// WithPoolCtx sets the parent context of the connections.
+func WithPoolCtx(ctx context.Context) func(options *PoolOptions) {
+	return func(options *PoolOptions) {
+		options.ctx = ctx
+	}
+}
+
+// WithPoolConnectionOptions passes options to every connection of the pool,
+// ex: [WithConnectionDelay], [WithConnectionMetrics]. The names and contexts are
+// set by the pool.
+func WithPoolConnectionOptions(optionFuncs ...func(*ConnectionOptions)) func(options *PoolOptions) {
+	return func(options *PoolOptions) {
+		options.connOptions = append(options.connOptions, optionFuncs...)
+	}
+}
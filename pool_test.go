@@ -0,0 +1,195 @@
+package grabbit
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+	"github.com/oarkflow/amqp/amqp091/amqptest"
+)
+
+// newTestPool creates a pool of two connections per traffic type; the first
+// dialed one, and any redial, goes through the returned dialer, the others through faults.
+func newTestPool(t *testing.T, srv *amqptest.Server, faults *amqptest.FaultDialer) (*Pool, *amqptest.FaultDialer) {
+	t.Helper()
+
+	first := amqptest.NewFaultDialer(srv.Dial)
+	var dials atomic.Int32
+	dial := func(network, addr string) (net.Conn, error) {
+		if n := dials.Add(1); n == 1 || n > 4 {
+			return first.Dial(network, addr)
+		}
+		return faults.Dial(network, addr)
+	}
+
+	pool := NewPool(srv.URL(), amqp.Config{Dial: dial}, WithPoolName("pool"), WithPoolSize(2),
+		WithPoolConnectionOptions(WithConnectionDelay(testDelay)))
+	t.Cleanup(func() { pool.Close() })
+	eventually(t, 5*time.Second, func() bool {
+		for _, stats := range pool.Stats() {
+			if !stats.Up {
+				return false
+			}
+		}
+		return true
+	}, "pool not established")
+
+	return pool, first
+}
+
+// isolate takes down the connection of the first dialer, returning it.
+func isolate(t *testing.T, pool *Pool, first *amqptest.FaultDialer) *Connection {
+	t.Helper()
+
+	first.RejectDials(-1)
+	first.Sever()
+
+	var down *Connection
+	eventually(t, 5*time.Second, func() bool {
+		for _, traffic := range []Traffic{TrafficPublish, TrafficConsume} {
+			for _, conn := range pool.Connections(traffic) {
+				if conn.IsClosed() {
+					down = conn
+					return true
+				}
+			}
+		}
+		return false
+	}, "connection not down")
+
+	return down
+}
+
+// poolChannel creates a channel of the traffic type of conn on the pool.
+func poolChannel(pool *Pool, conn *Connection, optionFuncs ...func(*ChannelOptions)) *Channel {
+	for _, publishing := range pool.Connections(TrafficPublish) {
+		if publishing == conn {
+			return pool.NewPublisher(DefaultPublisherOptions(), optionFuncs...).Channel()
+		}
+	}
+	return pool.NewConsumer(DefaultConsumerOptions(), optionFuncs...).Channel()
+}
+
+func TestPoolRebalanceRoutesEvents(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	pool, first := newTestPool(t, srv, faults)
+	down := isolate(t, pool, first)
+
+	// all placed on the other connection of the traffic type
+	var channels []*Channel
+	for _, name := range []string{"a", "b", "c", "d"} {
+		channels = append(channels, poolChannel(pool, down, WithChannelName(name), WithChannelDelay(testDelay),
+			WithChannelTopology(testQueue("pooled"))))
+	}
+	owner := channels[0].Connection()
+	for _, ch := range channels {
+		eventually(t, 5*time.Second, func() bool { return !ch.IsClosed() }, "channel %s not up", ch.Name())
+		if ch.Connection() != owner {
+			t.Fatalf("channel %s placed on the down connection", ch.Name())
+		}
+	}
+	// the transitions only, not drained while waiting for the rebalance
+	names, kinds := EventsFrom("a", "b", "c", "d"), EventsOfKind(EventUp, EventDown, EventBlocked)
+	transitions := func(event Event) bool { return names(event) && kinds(event) }
+	ownerEvents, cancelOwner := owner.Subscribe(transitions, WithSubscriptionBuffer(100))
+	defer cancelOwner()
+	movedEvents, cancelMoved := down.Subscribe(transitions, WithSubscriptionBuffer(100))
+	defer cancelMoved()
+
+	first.Heal()
+	eventually(t, 5*time.Second, func() bool {
+		var placed []int
+		for _, stats := range pool.Stats() {
+			if stats.Name == down.Name() || stats.Name == owner.Name() {
+				placed = append(placed, stats.Channels)
+			}
+		}
+		return len(placed) == 2 && placed[0] == 2 && placed[1] == 2
+	}, "channels not rebalanced: %+v", pool.Stats())
+
+	// the moved channels come up again, raising on the bus of their new connection
+	var moved []*Channel
+	for _, ch := range channels {
+		if ch.Connection() == down {
+			moved = append(moved, ch)
+		}
+	}
+	ups := make(map[string]bool)
+	for len(ups) < len(moved) {
+		select {
+		case event := <-movedEvents:
+			if event.Kind == EventUp {
+				ups[event.SourceName] = true
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("moved channels up: %v", ups)
+		}
+	}
+	for _, ch := range moved {
+		if !ups[ch.Name()] {
+			t.Fatalf("channel %s not up on its new connection", ch.Name())
+		}
+	}
+
+	moved[0].Pause()
+	for blocked := false; !blocked; {
+		select {
+		case event := <-movedEvents:
+			blocked = event.Kind == EventBlocked && event.SourceName == moved[0].Name()
+		case <-time.After(5 * time.Second):
+			t.Fatal("event not raised on the new connection")
+		}
+	}
+	// the former connection saw the migration only
+	for drained := false; !drained; {
+		select {
+		case event := <-ownerEvents:
+			if event.Kind == EventBlocked || event.Kind == EventUp && event.SourceName == moved[0].Name() {
+				t.Fatalf("event %v raised on the former connection", event)
+			}
+		default:
+			drained = true
+		}
+	}
+}
+
+func TestPoolKeepsExclusiveQueues(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	pool, first := newTestPool(t, srv, faults)
+	down := isolate(t, pool, first)
+
+	exclusive := []*TopologyOptions{{IsDestination: true, Exclusive: true, AutoDelete: true, Declare: true}}
+	var channels []*Channel
+	for i := 0; i < 3; i++ {
+		channels = append(channels, poolChannel(pool, down, WithChannelDelay(testDelay), WithChannelTopology(exclusive)))
+	}
+	for _, ch := range channels {
+		eventually(t, 5*time.Second, func() bool { return !ch.IsClosed() && ch.Queue() != "" }, "channel not up")
+	}
+	owner := channels[0].Connection()
+	queue := channels[2].Queue()
+	downs, cancel := owner.Subscribe(EventsOfKind(EventDown))
+	defer cancel()
+
+	first.Heal()
+	eventually(t, 5*time.Second, func() bool { return !down.IsClosed() }, "connection not recovered")
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancelCtx()
+	select {
+	case event := <-downs:
+		t.Fatalf("channel moved: %v", event)
+	case <-ctx.Done():
+	}
+	for _, ch := range channels {
+		if ch.Connection() != owner {
+			t.Fatal("channel owning an exclusive queue moved")
+		}
+	}
+	if info, ok := srv.Queue(queue); !ok || !info.Exclusive {
+		t.Fatalf("exclusive queue %s lost: %+v", queue, info)
+	}
+}
@@ -2,6 +2,7 @@ package grabbit
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	amqp "github.com/oarkflow/amqp/amqp091"
@@ -17,6 +18,7 @@ const (
 	ConfirmationPrevious                            // lower sequence number than expected
 	ConfirmationACK                                 // ACK (publish confirmed)
 	ConfirmationNAK                                 // NAK (publish negative acknowledgement)
+	ConfirmationSpooled                             // stored on disk for later publishing
 )
 
 // DeferredConfirmation wraps [amqp.DeferredConfirmation] with additional data.
@@ -24,9 +26,20 @@ const (
 type DeferredConfirmation struct {
 	*amqp.DeferredConfirmation                     // wrapped low level confirmation
 	Outcome                    ConfirmationOutcome // acknowledgment received stats
-	RequestSequence            uint64              // sequence of the original request (GetNextPublishSeqNo)
+	RequestSequence            uint64              // sequence of the original request (its delivery tag)
 	ChannelName                string              // channel name of the publisher
 	Queue                      string              // queue name of the publisher
+	entry                      *outboxEntry        // outbox tracking, survives recoveries
+}
+
+// deliveryTag returns the sequence number assigned to a publishing, 0 when the channel
+// is not in confirm mode. Unlike GetNextPublishSeqNo, it is read from the publishing
+// itself, not racing the concurrent ones nor contending with the confirmations.
+func deliveryTag(deferred *amqp.DeferredConfirmation) uint64 {
+	if deferred == nil {
+		return 0
+	}
+	return deferred.DeliveryTag
 }
 
 // Publisher implements an object allowing calling applications
@@ -35,6 +48,11 @@ type DeferredConfirmation struct {
 type Publisher struct {
 	channel *Channel         // assigned channel
 	opt     PublisherOptions // specific options
+	outbox  *outbox          // unconfirmed messages, when enabled
+	spool   *spool           // on-disk buffer, when enabled
+
+	publish         PublishFunc // interceptors chain of the unconfirmed publishing
+	publishDeferred PublishFunc // interceptors chain of the confirmed publishing
 }
 
 // defaultNotifyPublish provides a base implementation of [CallbackNotifyPublish] which can be
@@ -50,7 +68,7 @@ func defaultNotifyPublish(confirm amqp.Confirmation, ch *Channel) {
 			Err: SomeErrFromString(
 				fmt.Sprintf("delivery tag %d unconfirmed", confirm.DeliveryTag),
 			),
-		}.raise(ch.opt.notifier)
+		}.raise(ch.notifier())
 	}
 }
 
@@ -66,7 +84,7 @@ func defaultNotifyReturn(msg amqp.Return, ch *Channel) {
 		Err: SomeErrFromString(
 			fmt.Sprintf("message %s returned", msg.MessageId),
 		),
-	}.raise(ch.opt.notifier)
+	}.raise(ch.notifier())
 }
 
 // Channel returns the managed [Channel] which can be further used to extract [SafeBaseChan]
@@ -82,10 +100,50 @@ func NewPublisher(conn *Connection, opt PublisherOptions, optionFuncs ...func(*C
 	}
 	chanOpt := append(optionFuncs, WithChannelUsageParams(useParams))
 
-	return &Publisher{
+	var ob *outbox
+	if opt.Outbox > 0 {
+		ob = newOutbox(opt.Outbox)
+		chanOpt = append(chanOpt, withChannelOutbox(ob))
+	}
+
+	var sp *spool
+	var spErr error
+	if opt.Spool.Dir != "" {
+		if sp, spErr = openSpool(opt.Spool); spErr == nil {
+			chanOpt = append(chanOpt, withChannelSpool(sp))
+		}
+	}
+
+	p := &Publisher{
 		channel: NewChannel(conn, chanOpt...),
 		opt:     opt,
+		outbox:  ob,
+		spool:   sp,
 	}
+	plain, deferred := opt.Interceptors, opt.Interceptors
+	if opt.Tracer != nil {
+		// innermost: the trace context survives the interceptors replacing the headers
+		plain = append(slices.Clip(opt.Interceptors), p.tracing(false))
+		deferred = append(slices.Clip(opt.Interceptors), p.tracing(true))
+	}
+	p.publish = chainPublish(plain, p.send)
+	p.publishDeferred = chainPublish(deferred, p.sendDeferred)
+
+	if spErr != nil {
+		Event{
+			SourceType: CliChannel,
+			SourceName: p.channel.Name(),
+			TargetName: opt.Spool.Dir,
+			Kind:       EventCannotEstablish,
+			Err:        SomeErrFromError(spErr, true),
+		}.raise(p.channel.notifier())
+	}
+	if sp != nil {
+		conn.spools.Store(sp, struct{}{})
+		go p.drainSpool()
+	}
+
+	return p
 }
 
 // AwaitDeferredConfirmation waits for the confirmation of a deferred action and updates its outcome.
@@ -94,9 +152,14 @@ func NewPublisher(conn *Connection, opt PublisherOptions, optionFuncs ...func(*C
 // It returns the updated deferred confirmation object.
 func (p *Publisher) AwaitDeferredConfirmation(d *DeferredConfirmation, tmr time.Duration) *DeferredConfirmation {
 	if d.DeferredConfirmation == nil {
-		d.Outcome = ConfirmationDisabled
+		if d.Outcome != ConfirmationSpooled {
+			d.Outcome = ConfirmationDisabled
+		}
 		return d
 	}
+	if d.entry != nil {
+		return p.awaitOutboxConfirmation(d, tmr)
+	}
 
 	select {
 	case <-time.After(tmr):
@@ -117,73 +180,145 @@ func (p *Publisher) AwaitDeferredConfirmation(d *DeferredConfirmation, tmr time.
 	return d
 }
 
-// Publish wraps the amqp.PublishWithContext using the internal [PublisherOptions]
-// cached when the publisher was created.
-func (p *Publisher) Publish(msg amqp.Publishing) error {
-
-	if p.channel.IsClosed() {
-		return amqp.ErrClosed
+// awaitOutboxConfirmation waits for the final outcome of a message kept by the outbox,
+// possibly confirmed over a recovered channel. On completion the embedded low level
+// confirmation and the RequestSequence reflect the latest (remapped) delivery tag.
+func (p *Publisher) awaitOutboxConfirmation(d *DeferredConfirmation, tmr time.Duration) *DeferredConfirmation {
+	select {
+	case <-time.After(tmr):
+		d.Outcome = ConfirmationTimeOut
+	case <-p.opt.Context.Done():
+		d.Outcome = ConfirmationClosed
+	case <-d.entry.done:
+		if d.entry.deferred != nil {
+			d.DeferredConfirmation = d.entry.deferred
+			d.RequestSequence = d.entry.deferred.DeliveryTag
+		}
+		if d.entry.ack {
+			d.Outcome = ConfirmationACK
+		} else {
+			d.Outcome = ConfirmationNAK
+		}
 	}
 
-	return p.channel.PublishWithContext(
-		p.opt.Context, p.opt.Exchange, p.opt.Key, p.opt.Mandatory, p.opt.Immediate,
-		msg)
+	return d
 }
 
-// PublishDeferredConfirm wraps the amqp.PublishWithDeferredConfirmWithContext using the internal [PublisherOptions]
-// cached when the publisher was created.
-func (p *Publisher) PublishDeferredConfirm(msg amqp.Publishing) (*DeferredConfirmation, error) {
-	if p.channel.IsClosed() {
-		return nil, amqp.ErrClosed
+// Unconfirmed returns the number of messages held by the outbox
+// (see [PublisherOptions.WithOutbox]) while waiting for their confirmation.
+func (p *Publisher) Unconfirmed() int {
+	if p.outbox == nil {
+		return 0
 	}
+	return p.outbox.size()
+}
 
-	var err error
-	confirmation := &DeferredConfirmation{
-		Outcome:         ConfirmationClosed,
-		ChannelName:     p.channel.Name(),
-		Queue:           p.channel.Queue(),
-		RequestSequence: p.channel.GetNextPublishSeqNo(),
+// Spooled returns the amount of bytes held by the on-disk spool
+// (see [PublisherOptions.WithSpool]) while waiting for being published.
+func (p *Publisher) Spooled() int64 {
+	if p.spool == nil {
+		return 0
 	}
-	confirmation.DeferredConfirmation, err = p.channel.PublishWithDeferredConfirmWithContext(
-		p.opt.Context, p.opt.Exchange, p.opt.Key, p.opt.Mandatory, p.opt.Immediate, msg)
+	return p.spool.len()
+}
 
-	return confirmation, err
+// spooledConfirmation is returned by the deferred publishing methods for messages taken over by the spool.
+func (p *Publisher) spooledConfirmation() *DeferredConfirmation {
+	return &DeferredConfirmation{
+		Outcome:     ConfirmationSpooled,
+		ChannelName: p.channel.Name(),
+		Queue:       p.channel.Queue(),
+	}
+}
+
+// Publish wraps the amqp.PublishWithContext using the internal [PublisherOptions]
+// cached when the publisher was created.
+func (p *Publisher) Publish(msg amqp.Publishing) error {
+	_, err := p.publish(p.opt, msg)
+	return err
+}
+
+// PublishDeferredConfirm wraps the amqp.PublishWithDeferredConfirmWithContext using the internal [PublisherOptions]
+// cached when the publisher was created.
+func (p *Publisher) PublishDeferredConfirm(msg amqp.Publishing) (*DeferredConfirmation, error) {
+	return p.publishDeferred(p.opt, msg)
 }
 
 // PublishWithOptions wraps the amqp.PublishWithContext using the passed options.
 func (p *Publisher) PublishWithOptions(opt PublisherOptions, msg amqp.Publishing) error {
+	_, err := p.publish(opt, msg)
+	return err
+}
 
-	if p.channel.IsClosed() {
-		return amqp.ErrClosed
+// PublishDeferredConfirmWithOptions wraps the amqp.PublishWithDeferredConfirmWithContext using the passed options.
+func (p *Publisher) PublishDeferredConfirmWithOptions(opt PublisherOptions, msg amqp.Publishing) (*DeferredConfirmation, error) {
+	return p.publishDeferred(opt, msg)
+}
+
+// send is the innermost [PublishFunc] of the publishing methods not waiting for confirmations.
+func (p *Publisher) send(opt PublisherOptions, msg amqp.Publishing) (*DeferredConfirmation, error) {
+	if spooled, err := p.spooled(opt, msg); spooled || err != nil {
+		return nil, err
 	}
 
-	return p.channel.PublishWithContext(
-		opt.Context, opt.Exchange, opt.Key, opt.Mandatory, opt.Immediate,
-		msg)
+	_, err := p.attempt(opt, func() (*DeferredConfirmation, error) {
+		if p.outbox != nil {
+			_, err := p.outbox.publish(p.channel, opt, msg)
+			return nil, err
+		}
+
+		if p.channel.IsClosed() {
+			return nil, amqp.ErrClosed
+		}
+
+		return nil, p.channel.PublishWithContext(
+			opt.Context, opt.Exchange, opt.Key, opt.Mandatory, opt.Immediate,
+			msg)
+	})
+	_, err = p.respooled(opt, msg, err)
+
+	return nil, err
 }
 
-// PublishDeferredConfirmWithOptions wraps the amqp.PublishWithDeferredConfirmWithContext using the passed options.
-func (p *Publisher) PublishDeferredConfirmWithOptions(opt PublisherOptions, msg amqp.Publishing) (*DeferredConfirmation, error) {
-	if p.channel.IsClosed() {
-		return nil, amqp.ErrClosed
+// sendDeferred is the innermost [PublishFunc] of the publishing methods returning confirmations.
+func (p *Publisher) sendDeferred(opt PublisherOptions, msg amqp.Publishing) (*DeferredConfirmation, error) {
+	if spooled, err := p.spooled(opt, msg); err != nil {
+		return nil, err
+	} else if spooled {
+		return p.spooledConfirmation(), nil
 	}
 
-	var err error
-	confirmation := &DeferredConfirmation{
-		Outcome:         ConfirmationClosed,
-		ChannelName:     p.channel.Name(),
-		Queue:           p.channel.Queue(),
-		RequestSequence: p.channel.GetNextPublishSeqNo(),
+	confirmation, err := p.attempt(opt, func() (*DeferredConfirmation, error) {
+		if p.outbox != nil {
+			return p.outbox.publish(p.channel, opt, msg)
+		}
+
+		if p.channel.IsClosed() {
+			return nil, amqp.ErrClosed
+		}
+
+		var err error
+		confirmation := &DeferredConfirmation{
+			Outcome:     ConfirmationClosed,
+			ChannelName: p.channel.Name(),
+			Queue:       p.channel.Queue(),
+		}
+		confirmation.DeferredConfirmation, err = p.channel.PublishWithDeferredConfirmWithContext(
+			opt.Context, opt.Exchange, opt.Key, opt.Mandatory, opt.Immediate, msg)
+		confirmation.RequestSequence = deliveryTag(confirmation.DeferredConfirmation)
+
+		return confirmation, err
+	})
+	if spooled, spoolErr := p.respooled(opt, msg, err); spooled {
+		return p.spooledConfirmation(), spoolErr
 	}
-	confirmation.DeferredConfirmation, err = p.channel.PublishWithDeferredConfirmWithContext(
-		opt.Context, opt.Exchange, opt.Key, opt.Mandatory, opt.Immediate, msg)
 
 	return confirmation, err
 }
 
 // Available returns the status of both the underlying connection and channel.
 func (p *Publisher) Available() (bool, bool) {
-	return !p.channel.conn.IsClosed(), !p.channel.IsClosed()
+	return !p.channel.Connection().IsClosed(), !p.channel.IsClosed()
 }
 
 // AwaitAvailable waits till the publisher infrastructure is ready or timeout expires.
@@ -201,17 +336,14 @@ func (p *Publisher) AwaitAvailable(timeout, pollFreq time.Duration) bool {
 	// status polling
 	ticker := time.NewTicker(pollFreq)
 	defer ticker.Stop()
-	done := make(chan bool)
 
 	// session timeout
-	go func() {
-		time.Sleep(timeout)
-		done <- true
-	}()
+	expired := time.NewTimer(timeout)
+	defer expired.Stop()
 
 	for {
 		select {
-		case <-done:
+		case <-expired.C:
 			return false
 		case <-ticker.C:
 			if connUp, chanUp := p.Available(); connUp && chanUp {
@@ -0,0 +1,96 @@
+package grabbit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// Errors returned by the blocking publishing (see [PublisherOptions.WithBlocking]).
+// ErrTimeout wraps the state the publisher was waiting on, ex: errors.Is(err, ErrBlocked) holds
+// for a message refused after waiting for the broker flow control till the deadline.
+var (
+	ErrBlocked    = errors.New("publishing paused by the broker flow control")
+	ErrRecovering = errors.New("publisher channel is recovering")
+	ErrTimeout    = errors.New("publishing timed out")
+)
+
+// blockingPollInterval is the state polling of a publisher waiting to send.
+const blockingPollInterval = 50 * time.Millisecond
+
+// unavailable tells why messages cannot be sent right now, nil when they can.
+func (p *Publisher) unavailable() error {
+	switch {
+	case p.channel.Context().Err() != nil:
+		return amqp.ErrClosed
+	case p.channel.IsClosed() || p.channel.Connection().IsClosed():
+		return ErrRecovering
+	case p.channel.IsPaused() || p.channel.Connection().IsBlocked():
+		return ErrBlocked
+	}
+	return nil
+}
+
+// awaitPublishable waits for the channel to be up and unblocked, till ctx is done.
+func (p *Publisher) awaitPublishable(ctx context.Context) error {
+	cause := p.unavailable()
+	if cause == nil || errors.Is(cause, amqp.ErrClosed) {
+		return cause
+	}
+
+	for {
+		if err := p.pause(ctx, cause); err != nil {
+			return err
+		}
+		if cause = p.unavailable(); cause == nil || errors.Is(cause, amqp.ErrClosed) {
+			return cause
+		}
+	}
+}
+
+// pause waits for a polling interval, cut short by the channel ending. A ctx done
+// first fails the publishing with the cause it was waiting on.
+func (p *Publisher) pause(ctx context.Context, cause error) error {
+	timer := time.NewTimer(blockingPollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %w", ErrTimeout, cause)
+		}
+		return fmt.Errorf("%w: %w", cause, ctx.Err())
+	case <-p.channel.Context().Done():
+	case <-timer.C:
+	}
+	return nil
+}
+
+// attempt runs the publishing, first waiting for the channel to be publishable in blocking mode.
+// A message refused by a channel closing meanwhile is attempted again once recovered,
+// polling as the closing may not show yet.
+func (p *Publisher) attempt(opt PublisherOptions, publish func() (*DeferredConfirmation, error)) (*DeferredConfirmation, error) {
+	if !opt.Blocking {
+		return publish()
+	}
+
+	ctx := opt.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for {
+		if err := p.awaitPublishable(ctx); err != nil {
+			return nil, err
+		}
+		confirmation, err := publish()
+		if !errors.Is(err, amqp.ErrClosed) || p.channel.Context().Err() != nil {
+			return confirmation, err
+		}
+		if err := p.pause(ctx, ErrRecovering); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -21,6 +21,12 @@ type PublisherOptions struct {
 	Key       string          // routing key (usually queue name)
 	Mandatory bool            // delivery is mandatory
 	Immediate bool            // delivery is immediate
+	Outbox    int             // capacity of unconfirmed messages kept for republishing; 0 disables
+	Spool     SpoolOptions    // on-disk buffer while the broker is not reachable; disabled when Dir is empty
+	Blocking  bool            // wait for the flow control and recoveries till the Context deadline
+
+	Interceptors []PublishInterceptor // wrappers of the publishing methods, outermost first
+	Tracer       Tracer               // publishing spans and trace context propagation (optional)
 }
 
 // DefaultPublisherOptions creates some sane defaults for publishing messages.
@@ -108,3 +114,63 @@ func (opt *PublisherOptions) WithConfirmationsCount(count int) *PublisherOptions
 	opt.ConfirmationCount = count
 	return opt
 }
+
+// WithOutbox enables keeping up to capacity unconfirmed messages which get
+// republished automatically, in order, once the publisher channel recovers.
+// The [DeferredConfirmation] returned by the publishing methods still resolves
+// with the outcome of the republished message. Zero disables the outbox.
+//
+// Note: this provides at-least-once semantics; consumers may see duplicates
+// for messages that were routed but not confirmed before the channel failure.
+func (opt *PublisherOptions) WithOutbox(capacity int) *PublisherOptions {
+	opt.Outbox = capacity
+	return opt
+}
+
+// WithSpool enables a durable on-disk buffer for messages published while the
+// channel is down, paused (channel.flow) or the connection is blocked. Such
+// messages are appended to segment files under spool.Dir and published in order,
+// confirmed one by one, once the channel recovers or gets unblocked.
+// The deferred publishing methods report them with [ConfirmationSpooled].
+//
+// Spooled messages survive application restarts: a publisher opened on the same
+// directory resumes draining where the previous one stopped. Use distinct
+// directories for distinct publishers.
+func (opt *PublisherOptions) WithSpool(spool SpoolOptions) *PublisherOptions {
+	opt.Spool = spool
+	return opt
+}
+
+// WithBlocking makes the publishing methods wait while the channel is paused (channel.flow),
+// the connection is blocked (connection.blocked) or either is recovering, instead of
+// publishing regardless or failing with amqp.ErrClosed. The wait lasts till the Context
+// of the options is done, returning [ErrTimeout] on its deadline; the error also wraps
+// [ErrBlocked] or [ErrRecovering] for applying backpressure upstream.
+// Spooled publishers (see [PublisherOptions.WithSpool]) never wait, the spool taking the
+// messages over.
+func (opt *PublisherOptions) WithBlocking(blocking bool) *PublisherOptions {
+	opt.Blocking = blocking
+	return opt
+}
+
+// WithPublishInterceptors appends interceptors wrapping all the publishing methods
+// (header stamping, validation, logging, metrics etc.); the first one is the outermost.
+func (opt *PublisherOptions) WithPublishInterceptors(interceptors ...PublishInterceptor) *PublisherOptions {
+	opt.Interceptors = append(opt.Interceptors, interceptors...)
+	return opt
+}
+
+// WithCompression compresses the bodies of at least threshold bytes (see [CompressionInterceptor]).
+// The compression runs after the interceptors already registered, so these see the plain body.
+func (opt *PublisherOptions) WithCompression(compressor Compressor, threshold int) *PublisherOptions {
+	opt.Interceptors = append(opt.Interceptors, CompressionInterceptor(compressor, threshold))
+	return opt
+}
+
+// WithTracer starts a span per published message and propagates its W3C trace context
+// via the message headers. The spans of the deferred publishing methods last until the
+// broker confirmation; ctx of the options carries their parent.
+func (opt *PublisherOptions) WithTracer(tracer Tracer) *PublisherOptions {
+	opt.Tracer = tracer
+	return opt
+}
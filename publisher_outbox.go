@@ -0,0 +1,229 @@
+package grabbit
+
+import (
+	"errors"
+	"sync"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// ErrOutboxFull is returned by the publishing methods when the outbox
+// (see [PublisherOptions.WithOutbox]) holds already its capacity of unconfirmed messages.
+var ErrOutboxFull = errors.New("publisher outbox is full")
+
+// outboxEntry is a publishing kept until the broker confirms it.
+type outboxEntry struct {
+	opt      PublisherOptions           // routing used for (re)publishing
+	msg      amqp.Publishing            // original message
+	deferred *amqp.DeferredConfirmation // low level confirmation of the latest attempt
+	done     chan struct{}              // closed on the final outcome
+	ack      bool                       // final outcome
+}
+
+// outbox keeps the unconfirmed messages of a publisher and replays them
+// after the supporting channel has been recovered.
+//
+// The network publishing happens outside of mu, which the channel manage loop
+// takes for settling the confirmations: a publishing stuck on a blocked
+// connection must not stall the confirmations, hence the connection reader.
+type outbox struct {
+	sending  sync.Mutex              // serializes the (re)publishing, keeping the order
+	mu       sync.Mutex              // guards the fields below
+	capacity int                     // max number of unconfirmed messages
+	pending  []*outboxEntry          // unconfirmed messages in publishing order
+	tags     map[uint64]*outboxEntry // delivery tags on the current channel
+	early    map[uint64]bool         // confirmations received before their publishing got indexed
+	stale    bool                    // channel went down, pending need replaying
+	gen      int                     // base channel generation, bumped when it goes down
+}
+
+// newOutbox creates an outbox holding up to capacity unconfirmed messages.
+func newOutbox(capacity int) *outbox {
+	return &outbox{
+		capacity: capacity,
+		tags:     make(map[uint64]*outboxEntry),
+		early:    make(map[uint64]bool),
+	}
+}
+
+// withChannelOutbox attaches the publisher's outbox to its channel, so that
+// confirmations and recoveries reach it.
+func withChannelOutbox(ob *outbox) func(options *ChannelOptions) {
+	return func(options *ChannelOptions) {
+		options.outbox = ob
+	}
+}
+
+// publish sends the message over the channel and keeps it until confirmed.
+func (ob *outbox) publish(ch *Channel, opt PublisherOptions, msg amqp.Publishing) (*DeferredConfirmation, error) {
+	ob.sending.Lock()
+	defer ob.sending.Unlock()
+
+	ob.mu.Lock()
+	// channel recovered but the pending messages were not replayed yet
+	if ob.stale || ch.IsClosed() {
+		ob.mu.Unlock()
+		return nil, amqp.ErrClosed
+	}
+	if len(ob.pending) >= ob.capacity {
+		ob.mu.Unlock()
+		return nil, ErrOutboxFull
+	}
+	gen := ob.gen
+	ob.mu.Unlock()
+
+	entry := &outboxEntry{opt: opt, msg: msg, done: make(chan struct{})}
+	deferred, err := ob.send(ch, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmation := &DeferredConfirmation{
+		DeferredConfirmation: deferred,
+		Outcome:              ConfirmationClosed,
+		ChannelName:          ch.Name(),
+		Queue:                ch.Queue(),
+	}
+	// confirm mode not (yet) enabled, nothing to track
+	if deferred == nil {
+		return confirmation, nil
+	}
+	confirmation.RequestSequence = deferred.DeliveryTag
+	confirmation.entry = entry
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	ob.pending = append(ob.pending, entry)
+	ob.track(entry, deferred, gen)
+
+	return confirmation, nil
+}
+
+// send (re)publishes an entry, not holding mu.
+func (ob *outbox) send(ch *Channel, entry *outboxEntry) (*amqp.DeferredConfirmation, error) {
+	return ch.PublishWithDeferredConfirmWithContext(
+		entry.opt.Context, entry.opt.Exchange, entry.opt.Key, entry.opt.Mandatory, entry.opt.Immediate,
+		entry.msg)
+}
+
+// track indexes the pending entry by the delivery tag of its latest publishing,
+// unless the channel went down meanwhile: the entry then waits for the replay.
+// Called with mu held.
+func (ob *outbox) track(entry *outboxEntry, deferred *amqp.DeferredConfirmation, gen int) {
+	if gen != ob.gen {
+		return
+	}
+	entry.deferred = deferred
+
+	tag := deferred.DeliveryTag
+	ack, confirmed := ob.early[tag]
+	for early := range ob.early {
+		if early <= tag {
+			delete(ob.early, early)
+		}
+	}
+	if confirmed {
+		ob.settle(entry, ack)
+		return
+	}
+	ob.tags[tag] = entry
+}
+
+// confirm settles the entry matching the broker confirmation.
+func (ob *outbox) confirm(confirm amqp.Confirmation) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	entry, ok := ob.tags[confirm.DeliveryTag]
+	if !ok {
+		// the publishing is still returning, see track
+		ob.early[confirm.DeliveryTag] = confirm.Ack
+		return
+	}
+	delete(ob.tags, confirm.DeliveryTag)
+	ob.settle(entry, confirm.Ack)
+}
+
+// settle removes the entry from the pending ones and releases its waiters.
+func (ob *outbox) settle(entry *outboxEntry, ack bool) {
+	for i, e := range ob.pending {
+		if e == entry {
+			ob.pending = append(ob.pending[:i], ob.pending[i+1:]...)
+			break
+		}
+	}
+
+	entry.ack = ack
+	close(entry.done)
+}
+
+// invalidate forgets the delivery tags of a lost channel; the pending
+// messages are kept for replaying.
+func (ob *outbox) invalidate() {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	ob.stale = true
+	ob.gen++
+	ob.tags = make(map[uint64]*outboxEntry)
+	ob.early = make(map[uint64]bool)
+}
+
+// replay republishes, in the original order, all pending messages over the
+// recovered channel. Messages whose context got cancelled meanwhile are
+// settled negatively instead. It runs on a goroutine of its own, the channel
+// manage loop draining the confirmations meanwhile.
+func (ob *outbox) replay(ch *Channel) {
+	ob.sending.Lock()
+	defer ob.sending.Unlock()
+
+	ob.mu.Lock()
+	if !ob.stale {
+		ob.mu.Unlock()
+		return
+	}
+	ob.stale = false
+	gen := ob.gen
+	entries := append([]*outboxEntry{}, ob.pending...)
+	ob.mu.Unlock()
+
+	for _, entry := range entries {
+		if entry.opt.Context != nil && entry.opt.Context.Err() != nil {
+			ob.mu.Lock()
+			ob.settle(entry, false)
+			ob.mu.Unlock()
+			continue
+		}
+
+		deferred, err := ob.send(ch, entry)
+
+		ob.mu.Lock()
+		switch {
+		case gen != ob.gen:
+			// lost again, the next recovery replays
+			ob.mu.Unlock()
+			return
+		case err != nil:
+			// lost again, try later
+			ob.stale = true
+			ob.tags = make(map[uint64]*outboxEntry)
+			ob.mu.Unlock()
+			return
+		case deferred == nil:
+			// confirm mode is gone, the outcome cannot be tracked anymore
+			ob.settle(entry, false)
+		default:
+			ob.track(entry, deferred, gen)
+		}
+		ob.mu.Unlock()
+	}
+}
+
+// size returns the number of unconfirmed messages.
+func (ob *outbox) size() int {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	return len(ob.pending)
+}
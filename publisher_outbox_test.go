@@ -0,0 +1,63 @@
+package grabbit
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+func TestOutboxReplaysManyUnconfirmed(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	opt := DefaultPublisherOptions()
+	opt.WithKey("outbox").WithOutbox(1000)
+	pub := newTestPublisher(t, conn, opt, WithChannelTopology(testQueue("outbox")))
+
+	// confirmations held back: all stay unconfirmed till the stream is cut,
+	// more than the confirmation buffer are replayed by the recovery
+	faults.DelayReads(time.Hour)
+	confirmations := make([]*DeferredConfirmation, 200)
+	for i := range confirmations {
+		var err error
+		if confirmations[i], err = pub.PublishDeferredConfirm(amqp.Publishing{Body: []byte("msg")}); err != nil {
+			t.Fatalf("publish %d: %v", i, err)
+		}
+	}
+	faults.Heal()
+	faults.Sever()
+
+	for i, confirmation := range confirmations {
+		if outcome := pub.AwaitDeferredConfirmation(confirmation, 10*time.Second).Outcome; outcome != ConfirmationACK {
+			t.Fatalf("message %d: outcome %s", i, outcome)
+		}
+	}
+	if n := pub.Unconfirmed(); n != 0 {
+		t.Fatalf("%d messages still unconfirmed", n)
+	}
+}
+
+func TestOutboxConfirmsWhilePublishingIsStuck(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	opt := DefaultPublisherOptions()
+	opt.WithKey("stuck").WithOutbox(100)
+	pub := newTestPublisher(t, conn, opt, WithChannelTopology(testQueue("stuck")))
+
+	first, err := pub.PublishDeferredConfirm(amqp.Publishing{Body: []byte("first")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the next publishing hangs in the network while the first one gets confirmed
+	faults.DelayWrites(time.Second)
+	go pub.PublishDeferredConfirm(amqp.Publishing{Body: []byte("second")})
+	time.Sleep(50 * time.Millisecond)
+
+	if outcome := pub.AwaitDeferredConfirmation(first, 500*time.Millisecond).Outcome; outcome != ConfirmationACK {
+		t.Fatalf("outcome %s while another publishing is stuck", outcome)
+	}
+	faults.Heal()
+}
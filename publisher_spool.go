@@ -0,0 +1,532 @@
+package grabbit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// ErrSpoolFull is returned by the publishing methods when the spool
+// (see [PublisherOptions.WithSpool]) reached its MaxSize.
+var ErrSpoolFull = errors.New("publisher spool is full")
+
+const (
+	spoolSegmentExt     = ".seg"             // segment file extension
+	spoolCursorFile     = "cursor"           // persisted drain position
+	spoolRecordHeader   = 8                  // length and checksum preceding every record
+	spoolSegmentSize    = 16 << 20           // default segment size
+	spoolDrainInterval  = time.Second        // polling when no recovery hint is received
+	spoolConfirmTimeout = 30 * time.Second   // max wait for the broker confirming a drained message
+	spoolMaxAttempts    = 5                  // default negative confirmations tolerated per message
+	spoolDirPerm        = os.FileMode(0o755) // spool directory permissions
+	spoolFilePerm       = os.FileMode(0o644) // segment and cursor files permissions
+)
+
+func init() {
+	// concrete types carried by the amqp.Table headers
+	gob.Register(amqp.Table{})
+	gob.Register([]interface{}{})
+	gob.Register(amqp.Decimal{})
+	gob.Register(time.Time{})
+}
+
+// SpoolOptions configures the durable on-disk buffer of a publisher.
+// Messages published while the broker is unreachable, the channel is paused
+// (channel.flow) or the connection is blocked are appended to segment files
+// and drained, in order, once publishing is possible again.
+// A message negatively confirmed MaxAttempts times is discarded, raising
+// an [EventMessagePublished] with the reason.
+type SpoolOptions struct {
+	Dir         string        // directory holding the segment files; empty disables spooling
+	SegmentSize int64         // roll over to a new segment beyond this size (bytes); 0 means 16MiB
+	MaxSize     int64         // max bytes held; further publishes fail with [ErrSpoolFull]. 0 is unlimited
+	MaxAge      time.Duration // spooled messages older than this are discarded instead of published. 0 keeps them
+	NoSync      bool          // skips fsync after each write: faster but may lose the latest records on power loss
+	MaxAttempts int           // negative confirmations (NAK) tolerated per message before discarding it; 0 means 5
+}
+
+// spoolRecord is the persisted form of a publishing.
+type spoolRecord struct {
+	Exchange  string
+	Key       string
+	Mandatory bool
+	Immediate bool
+	Spooled   time.Time
+	Msg       amqp.Publishing
+}
+
+// spool is an append-only, segmented, file based FIFO of publishings.
+// A single writer (publishing methods) and a single reader (the drain loop) are supported.
+type spool struct {
+	mu       sync.Mutex
+	opt      SpoolOptions
+	segments []uint64 // sequence numbers of the segment files, oldest first
+	writer   *os.File // last segment, open for appending
+	wsize    int64    // size of the last segment
+	reader   *os.File // first segment, open for draining
+	roff     int64    // drain offset within the first segment
+	size     int64    // bytes not drained yet
+	cursor   *os.File // persisted drain position
+	closed   bool
+	attempts int           // negative confirmations of the oldest record
+	wake     chan struct{} // hints the drain loop that publishing may be possible
+}
+
+// withChannelSpool attaches the publisher's spool to its channel, so that
+// recoveries and flow changes wake up the draining.
+func withChannelSpool(s *spool) func(options *ChannelOptions) {
+	return func(options *ChannelOptions) {
+		options.spool = s
+	}
+}
+
+// segmentPath returns the file name of the segment with the given sequence number.
+func (s *spool) segmentPath(seq uint64) string {
+	return filepath.Join(s.opt.Dir, fmt.Sprintf("%020d%s", seq, spoolSegmentExt))
+}
+
+// openSpool opens (or creates) the spool directory, resuming the draining where
+// a previous instance left it. A torn record at the end of the last segment
+// (ex: crash while writing) is truncated.
+func openSpool(opt SpoolOptions) (*spool, error) {
+	if opt.SegmentSize <= 0 {
+		opt.SegmentSize = spoolSegmentSize
+	}
+	if opt.MaxAttempts <= 0 {
+		opt.MaxAttempts = spoolMaxAttempts
+	}
+	if err := os.MkdirAll(opt.Dir, spoolDirPerm); err != nil {
+		return nil, err
+	}
+
+	s := &spool{opt: opt, wake: make(chan struct{}, 1)}
+
+	entries, err := os.ReadDir(opt.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, spoolSegmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		s.segments = append(s.segments, seq)
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i] < s.segments[j] })
+
+	if s.cursor, err = os.OpenFile(filepath.Join(opt.Dir, spoolCursorFile), os.O_RDWR|os.O_CREATE, spoolFilePerm); err != nil {
+		return nil, err
+	}
+	var pos [16]byte
+	if _, err := s.cursor.ReadAt(pos[:], 0); err == nil {
+		rseg := binary.BigEndian.Uint64(pos[:8])
+		roff := int64(binary.BigEndian.Uint64(pos[8:]))
+		// segments before the cursor were fully drained
+		for len(s.segments) > 0 && s.segments[0] < rseg {
+			os.Remove(s.segmentPath(s.segments[0]))
+			s.segments = s.segments[1:]
+		}
+		if len(s.segments) > 0 && s.segments[0] == rseg {
+			s.roff = roff
+		}
+	}
+	if len(s.segments) == 0 {
+		s.segments = []uint64{1}
+	}
+
+	last := s.segmentPath(s.segments[len(s.segments)-1])
+	if s.wsize, err = validSize(last); err != nil {
+		s.close()
+		return nil, err
+	}
+	if err := os.Truncate(last, s.wsize); err != nil && !os.IsNotExist(err) {
+		s.close()
+		return nil, err
+	}
+	if s.writer, err = os.OpenFile(last, os.O_WRONLY|os.O_APPEND|os.O_CREATE, spoolFilePerm); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	for _, seq := range s.segments {
+		if info, err := os.Stat(s.segmentPath(seq)); err == nil {
+			s.size += info.Size()
+		}
+	}
+	s.size -= s.roff
+
+	return s, nil
+}
+
+// validSize returns the length of the leading well-formed records of a segment file.
+func validSize(path string) (int64, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var off int64
+	for {
+		_, n, err := readRecord(f, off)
+		if err != nil {
+			return off, nil
+		}
+		off += n
+	}
+}
+
+// readRecord decodes the record found at offset, returning its size on disk.
+// io.EOF signals a clean end of the segment, other errors a torn or corrupted record.
+func readRecord(f *os.File, off int64) (spoolRecord, int64, error) {
+	var rec spoolRecord
+
+	var header [spoolRecordHeader]byte
+	if n, err := f.ReadAt(header[:], off); err != nil {
+		if err == io.EOF && n == 0 {
+			return rec, 0, io.EOF
+		}
+		return rec, 0, io.ErrUnexpectedEOF
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if info, err := f.Stat(); err != nil || off+spoolRecordHeader+int64(length) > info.Size() {
+		return rec, 0, io.ErrUnexpectedEOF
+	}
+	payload := make([]byte, length)
+	if _, err := f.ReadAt(payload, off+spoolRecordHeader); err != nil {
+		return rec, 0, io.ErrUnexpectedEOF
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return rec, 0, errors.New("spool record checksum mismatch")
+	}
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&rec); err != nil {
+		return rec, 0, err
+	}
+
+	return rec, spoolRecordHeader + int64(length), nil
+}
+
+// divert appends the record when the publisher cannot send it right away
+// or when older spooled messages must go first, preserving the order.
+// It reports whether the record has been taken over by the spool.
+func (s *spool) divert(ready bool, rec spoolRecord) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false, nil
+	}
+	if ready && s.emptyLocked() {
+		return false, nil
+	}
+
+	return true, s.appendLocked(rec)
+}
+
+// appendLocked writes the record at the end of the last segment, rolling
+// over to a new segment when the current one is full.
+func (s *spool) appendLocked(rec spoolRecord) error {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(rec); err != nil {
+		return err
+	}
+	frame := make([]byte, spoolRecordHeader, spoolRecordHeader+payload.Len())
+	binary.BigEndian.PutUint32(frame[:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(frame[4:], crc32.ChecksumIEEE(payload.Bytes()))
+	frame = append(frame, payload.Bytes()...)
+
+	if s.opt.MaxSize > 0 && s.size+int64(len(frame)) > s.opt.MaxSize {
+		return ErrSpoolFull
+	}
+
+	if s.wsize > 0 && s.wsize+int64(len(frame)) > s.opt.SegmentSize {
+		seq := s.segments[len(s.segments)-1] + 1
+		writer, err := os.OpenFile(s.segmentPath(seq), os.O_WRONLY|os.O_APPEND|os.O_CREATE, spoolFilePerm)
+		if err != nil {
+			return err
+		}
+		s.writer.Close()
+		s.writer, s.wsize = writer, 0
+		s.segments = append(s.segments, seq)
+	}
+
+	n, err := s.writer.Write(frame)
+	if err != nil {
+		// drop the partial write so that the following records stay readable
+		s.writer.Truncate(s.wsize)
+		return err
+	}
+	if !s.opt.NoSync {
+		if err := s.writer.Sync(); err != nil {
+			return err
+		}
+	}
+	s.wsize += int64(n)
+	s.size += int64(n)
+
+	return nil
+}
+
+// emptyLocked reports whether everything has been drained.
+func (s *spool) emptyLocked() bool {
+	return len(s.segments) == 1 && s.roff >= s.wsize
+}
+
+// peek returns the oldest record not yet drained and its size on disk,
+// discarding the exhausted segments on its way.
+func (s *spool) peek() (spoolRecord, int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for !s.closed && !s.emptyLocked() {
+		if s.reader == nil {
+			reader, err := os.Open(s.segmentPath(s.segments[0]))
+			if err != nil {
+				return spoolRecord{}, 0, false, err
+			}
+			s.reader = reader
+		}
+
+		rec, n, err := readRecord(s.reader, s.roff)
+		if err == nil {
+			return rec, n, true, nil
+		}
+		if len(s.segments) == 1 {
+			// the writer's segment; nothing complete beyond this point
+			return spoolRecord{}, 0, false, nil
+		}
+
+		// exhausted (or corrupted) segment, move on to the next one
+		if info, err := s.reader.Stat(); err == nil {
+			s.size -= info.Size() - s.roff
+		}
+		s.reader.Close()
+		s.reader = nil
+		os.Remove(s.segmentPath(s.segments[0]))
+		s.segments = s.segments[1:]
+		s.roff = 0
+		s.saveCursorLocked()
+	}
+
+	return spoolRecord{}, 0, false, nil
+}
+
+// advance marks the record returned by peek as drained.
+func (s *spool) advance(n int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.roff += n
+	s.size -= n
+	s.attempts = 0
+
+	return s.saveCursorLocked()
+}
+
+// rejected counts a negative confirmation of the oldest record and
+// reports whether it exhausted its attempts.
+func (s *spool) rejected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts++
+	return s.attempts >= s.opt.MaxAttempts
+}
+
+// saveCursorLocked persists the drain position.
+func (s *spool) saveCursorLocked() error {
+	var pos [16]byte
+	binary.BigEndian.PutUint64(pos[:8], s.segments[0])
+	binary.BigEndian.PutUint64(pos[8:], uint64(s.roff))
+
+	if _, err := s.cursor.WriteAt(pos[:], 0); err != nil {
+		return err
+	}
+	if !s.opt.NoSync {
+		return s.cursor.Sync()
+	}
+
+	return nil
+}
+
+// notify hints the drain loop that publishing may be possible again.
+func (s *spool) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// len returns the number of bytes not drained yet.
+func (s *spool) len() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.size
+}
+
+// close releases the files; the content is kept for a next instance.
+func (s *spool) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for _, f := range []*os.File{s.writer, s.reader, s.cursor} {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
+
+// ready reports whether messages can be sent right away.
+func (p *Publisher) ready() bool {
+	return !p.channel.IsClosed() && !p.channel.IsPaused() && !p.channel.Connection().IsBlocked()
+}
+
+// spooled takes over the message when publishing is not possible right now.
+func (p *Publisher) spooled(opt PublisherOptions, msg amqp.Publishing) (bool, error) {
+	if p.spool == nil {
+		return false, nil
+	}
+
+	return p.spool.divert(p.ready(), newSpoolRecord(opt, msg))
+}
+
+// respooled takes over the message refused by the channel or its stream closing after
+// [Publisher.spooled] found it ready (ex: a recovery starting meanwhile). It returns err
+// when not taken over.
+func (p *Publisher) respooled(opt PublisherOptions, msg amqp.Publishing, err error) (bool, error) {
+	if p.spool == nil || !closedError(err) || p.channel.Context().Err() != nil {
+		return false, err
+	}
+
+	spooled, spoolErr := p.spool.divert(false, newSpoolRecord(opt, msg))
+	if !spooled {
+		return false, err
+	}
+	return true, spoolErr
+}
+
+// closedError reports whether the publishing failed on a closed channel or network stream.
+func closedError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, amqp.ErrClosed) || errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, io.EOF) || errors.As(err, &netErr)
+}
+
+// newSpoolRecord returns the persisted form of the publishing.
+func newSpoolRecord(opt PublisherOptions, msg amqp.Publishing) spoolRecord {
+	return spoolRecord{
+		Exchange:  opt.Exchange,
+		Key:       opt.Key,
+		Mandatory: opt.Mandatory,
+		Immediate: opt.Immediate,
+		Spooled:   time.Now(),
+		Msg:       msg,
+	}
+}
+
+// drainSpool publishes the spooled messages, in order, for as long as the
+// channel is available. Started by [NewPublisher] when spooling is enabled,
+// it wakes up on channel recovery, resume or connection unblocking and polls otherwise.
+func (p *Publisher) drainSpool() {
+	ticker := time.NewTicker(spoolDrainInterval)
+	defer ticker.Stop()
+	defer p.spool.close()
+
+	defer func() {
+		p.channel.Connection().spools.Delete(p.spool)
+	}()
+
+	ctx := p.channel.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.spool.wake:
+		case <-ticker.C:
+		}
+
+		for p.ready() && p.drainOne() {
+		}
+	}
+}
+
+// drainOne publishes the oldest spooled message and waits for its confirmation
+// (when confirm mode is enabled). It reports whether draining can continue.
+func (p *Publisher) drainOne() bool {
+	rec, n, ok, err := p.spool.peek()
+	if err != nil || !ok {
+		return false
+	}
+
+	if p.spool.opt.MaxAge > 0 && time.Since(rec.Spooled) > p.spool.opt.MaxAge {
+		return p.discard(rec, n, "spooled message expired")
+	}
+
+	ctx := p.channel.Context()
+	deferred, err := p.channel.PublishWithDeferredConfirmWithContext(
+		ctx, rec.Exchange, rec.Key, rec.Mandatory, rec.Immediate, rec.Msg)
+	if err != nil {
+		return false
+	}
+	if deferred != nil {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(spoolConfirmTimeout):
+			return false
+		case <-deferred.Done():
+			if deferred.Acked() {
+				break
+			}
+			if p.channel.IsClosed() {
+				// channel lost: not the broker's verdict, keep it for after the recovery
+				return false
+			}
+			if p.spool.rejected() {
+				return p.discard(rec, n,
+					fmt.Sprintf("spooled message discarded after %d negative confirmations", p.spool.opt.MaxAttempts))
+			}
+			return false
+		}
+	}
+
+	return p.spool.advance(n) == nil
+}
+
+// discard drops the oldest spooled message, raising an [EventMessagePublished] with the reason.
+func (p *Publisher) discard(rec spoolRecord, n int64, reason string) bool {
+	Event{
+		SourceType: CliChannel,
+		SourceName: p.channel.Name(),
+		TargetName: rec.Msg.MessageId,
+		Kind:       EventMessagePublished,
+		Err:        SomeErrFromString(reason),
+	}.raise(p.channel.notifier())
+	p.channel.log(p.channel.opt.logLevels.Errors, reason, nil, slog.String("message_id", rec.Msg.MessageId))
+
+	return p.spool.advance(n) == nil
+}
@@ -0,0 +1,101 @@
+package grabbit
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// newSpoolPublisher opens a spooled publisher on the queue, in a directory of the test.
+func newSpoolPublisher(t *testing.T, conn *Connection, queue string, spool SpoolOptions) *Publisher {
+	t.Helper()
+
+	spool.Dir = t.TempDir()
+	opt := DefaultPublisherOptions()
+	opt.WithKey(queue).WithSpool(spool)
+
+	return newTestPublisher(t, conn, opt, WithChannelTopology(testQueue(queue)))
+}
+
+func TestSpoolDrainsOnUnblock(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	pub := newSpoolPublisher(t, conn, "unblock", SpoolOptions{})
+
+	srv.Block("low on memory")
+	eventually(t, 5*time.Second, conn.IsBlocked, "connection not blocked")
+
+	confirmation, err := pub.PublishDeferredConfirm(amqp.Publishing{Body: []byte("msg")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confirmation.Outcome != ConfirmationSpooled {
+		t.Fatalf("outcome %s while blocked", confirmation.Outcome)
+	}
+
+	// woken up by the unblocking, well before the polling
+	srv.Unblock()
+	eventually(t, spoolDrainInterval/2, func() bool {
+		info, _ := srv.Queue("unblock")
+		return info.Ready == 1
+	}, "spool not drained on unblocking")
+	if n := pub.Spooled(); n != 0 {
+		t.Fatalf("%d bytes still spooled", n)
+	}
+}
+
+func TestSpoolDiscardsRejectedMessage(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	pub := newSpoolPublisher(t, conn, "rejected", SpoolOptions{MaxAttempts: 2})
+
+	events, cancel := pub.Channel().Subscribe(EventsOfKind(EventMessagePublished))
+	defer cancel()
+
+	srv.Block("low on memory")
+	eventually(t, 5*time.Second, conn.IsBlocked, "connection not blocked")
+	for _, body := range []string{"rejected", "next"} {
+		if err := pub.Publish(amqp.Publishing{MessageId: body, Body: []byte(body)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv.NackPublishes(true)
+	srv.Unblock()
+
+	// the negative confirmations raise events as well, the discarding names the message
+	timeout := time.After(5 * time.Second)
+	for discarded := false; !discarded; {
+		select {
+		case event := <-events:
+			discarded = event.TargetName == "rejected"
+		case <-timeout:
+			t.Fatal("rejected message not discarded")
+		}
+	}
+
+	srv.NackPublishes(false)
+	pub.Channel().opt.spool.notify()
+	eventually(t, 5*time.Second, func() bool { return pub.Spooled() == 0 }, "spool not drained")
+}
+
+func TestSpoolTakesOverWhileRecovering(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	pub := newSpoolPublisher(t, conn, "recovering", SpoolOptions{})
+
+	// published right after the stream is cut, the channel possibly not knowing it yet
+	faults.Sever()
+	for i := 0; i < 20; i++ {
+		if err := pub.Publish(amqp.Publishing{Body: []byte("msg")}); err != nil {
+			t.Fatalf("publish %d while recovering: %v", i, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	eventually(t, 10*time.Second, func() bool {
+		info, _ := srv.Queue("recovering")
+		return info.Ready == 20
+	}, "messages lost over the recovery")
+}
@@ -0,0 +1,82 @@
+package grabbit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+func TestPublishMetricsMeasureEveryConfirmation(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	metrics := NewMetrics()
+	opt := DefaultPublisherOptions()
+	opt.WithKey("metered").WithConfirmationsCount(1)
+	pub := newTestPublisher(t, conn, opt, WithChannelTopology(testQueue("metered")), WithChannelMetrics(metrics))
+	name := pub.Channel().Name()
+
+	// concurrent publishers with the confirmations held up by the small buffer
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if _, err := pub.PublishDeferredConfirm(amqp.Publishing{Body: []byte("msg")}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("publishing stuck behind the confirmations")
+	}
+
+	// the probe publishings of the set up are measured as well
+	eventually(t, 5*time.Second, func() bool {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+
+		published := metrics.seriesOf(metricPublished, name).value
+		measured := metrics.seriesOf(metricConfirmTime, name).count
+		return published >= 1600 && float64(measured) == published
+	}, "confirmation latency not measured for every publishing")
+}
+
+func TestBlockingPublishPollsWhileClosing(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	pub := newTestPublisher(t, conn, DefaultPublisherOptions())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*blockingPollInterval)
+	defer cancel()
+	opt := DefaultPublisherOptions()
+	opt.WithContext(ctx).WithBlocking(true)
+
+	// refused as closed while the channel still looks up
+	attempts := 0
+	_, err := pub.attempt(opt, func() (*DeferredConfirmation, error) {
+		attempts++
+		return nil, amqp.ErrClosed
+	})
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, ErrRecovering) {
+		t.Fatalf("error %v", err)
+	}
+	if attempts > 11 {
+		t.Fatalf("%d attempts", attempts)
+	}
+}
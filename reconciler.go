@@ -0,0 +1,502 @@
+package grabbit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// ErrTopologyConflict is returned by [Reconciler.Apply] under [ConflictFail]
+// when entities exist on the broker with other attributes or arguments.
+var ErrTopologyConflict = errors.New("topology conflicts with the broker")
+
+// ReconcileAction tells the state of an entity or binding compared to the broker.
+type ReconcileAction int
+
+//go:generate stringer -type=ReconcileAction -trimprefix=Reconcile
+const (
+	ReconcileCreate     ReconcileAction = iota // entity missing, to declare
+	ReconcileMatch                             // entity or binding in place
+	ReconcileConflict                          // entity existing with other attributes or arguments, or missing passive entity
+	ReconcileBind                              // binding missing, to add
+	ReconcileUnverified                        // existing entity whose attributes a passive inspection cannot check, or binding between existing entities, added again as it cannot be inspected
+	ReconcileUnbind                            // stale binding, to remove
+)
+
+// ReconcileStep is an entity, or one of the bindings of a topology entry, in a [ReconcilePlan].
+type ReconcileStep struct {
+	Action   ReconcileAction
+	Topology *TopologyOptions // the entity, or the entry holding the binding
+	Binding  *TopologyBind    // binding of the step, nil for the entity
+	Name     string           // entity name on the broker, assigned by the server for server-named queues
+	Stale    bool             // Binding is one of the StaleBindings
+	Applied  bool             // changed by [Reconciler.Apply]
+	Err      error            // broker reply of a conflict, failure when applying
+}
+
+// String describes the step, ex: `Create queue "orders"`, `Bind exchange "events" -> queue "orders", key "order.*"`.
+func (s ReconcileStep) String() string {
+	t := s.Topology
+	if s.Binding != nil {
+		source, destination := t.RoutingOf(*s.Binding)
+		kind := "queue"
+		if t.IsExchange {
+			kind = "exchange"
+		}
+		return fmt.Sprintf("%s exchange %q -> %s %q, key %q", s.Action, source, kind, destination, s.Binding.Key)
+	}
+	if t.IsExchange {
+		return fmt.Sprintf("%s exchange %q", s.Action, t.Name)
+	}
+	return fmt.Sprintf("%s queue %q", s.Action, t.Name)
+}
+
+// ReconcilePlan lists the entities of a topology, then their bindings, as found on the broker.
+type ReconcilePlan struct {
+	Steps []ReconcileStep
+}
+
+// InSync tells whether the broker already holds the topology, unverified bindings aside.
+func (p *ReconcilePlan) InSync() bool {
+	for _, step := range p.Steps {
+		switch step.Action {
+		case ReconcileCreate, ReconcileConflict, ReconcileBind, ReconcileUnbind:
+			return false
+		}
+	}
+	return true
+}
+
+// Conflicts returns the conflicting entities.
+func (p *ReconcilePlan) Conflicts() []ReconcileStep {
+	var conflicts []ReconcileStep
+	for _, step := range p.Steps {
+		if step.Action == ReconcileConflict {
+			conflicts = append(conflicts, step)
+		}
+	}
+	return conflicts
+}
+
+// Reconciler compares a topology with the broker state and applies the differences,
+// unlike [WithChannelTopology] which declares blindly. Entities are inspected passively,
+// which tells whether they exist but not whether their attributes and arguments match.
+// [Reconciler.Apply] then redeclares the existing ones as they are desired, which the broker
+// accepts without changes when equivalent and refuses (PRECONDITION_FAILED) otherwise.
+// Create a reconciler by calling [NewReconciler].
+type Reconciler struct {
+	conn *Connection
+	opt  ReconcilerOptions
+}
+
+// NewReconciler creates a reconciler working over the managed connection.
+//
+// Example Usage:
+//
+//	reconciler := NewReconciler(conn,
+//	  WithReconcilePolicy(ConflictSkip),
+//	  WithReconcileBindings(ManagementBindings("http://localhost:15672", "guest", "guest", "/")),
+//	)
+//	plan, err := reconciler.Apply(ctx, topology)
+func NewReconciler(conn *Connection, optionFuncs ...func(*ReconcilerOptions)) *Reconciler {
+	opt := DefaultReconcilerOptions()
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&opt)
+	}
+
+	return &Reconciler{conn: conn, opt: opt}
+}
+
+// entityKey identifies an entity of the topology; server-named queues are each their own.
+type entityKey struct {
+	exchange bool
+	name     string
+	entry    *TopologyOptions // server-named queue only
+}
+
+// keyOf returns the key of the entity of a topology entry.
+func keyOf(t *TopologyOptions) entityKey {
+	if !t.IsExchange && t.Name == "" {
+		return entityKey{entry: t}
+	}
+	return entityKey{exchange: t.IsExchange, name: t.Name}
+}
+
+// Plan inspects the broker for the entities and bindings of the topology, changing nothing:
+// entities are only declared passively, the existing ones being [ReconcileUnverified].
+// Entries not meant to be declared (Declare unset) are left out.
+func (r *Reconciler) Plan(ctx context.Context, topology []*TopologyOptions) (*ReconcilePlan, error) {
+	session := &topologySession{ctx: ctx, conn: r.conn}
+	defer session.close()
+
+	plan := &ReconcilePlan{}
+	states := make(map[entityKey]ReconcileAction)
+	for _, t := range topology {
+		if !t.Declare {
+			continue
+		}
+		key := keyOf(t)
+		if _, found := states[key]; found {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return plan, err
+		}
+
+		action, err := session.inspect(t)
+		if action < 0 {
+			return plan, err
+		}
+		states[key] = action
+		plan.Steps = append(plan.Steps, ReconcileStep{Action: action, Topology: t, Name: t.Name, Err: err})
+	}
+
+	var existing []BindingDefinition
+	listed := false
+	listing := func() error {
+		if r.opt.bindings == nil || listed {
+			return nil
+		}
+		var err error
+		if existing, err = r.opt.bindings(ctx); err != nil {
+			return fmt.Errorf("listing bindings: %w", err)
+		}
+		listed = true
+		return nil
+	}
+
+	for _, t := range topology {
+		if !t.Declare {
+			continue
+		}
+
+		for _, bind := range t.Binds() {
+			bind := bind
+			source, destination := t.RoutingOf(bind)
+			action := ReconcileUnverified
+			switch {
+			case keyOf(t).entry != nil,
+				created(states, entityKey{exchange: true, name: source}),
+				created(states, entityKey{exchange: t.IsExchange, name: destination}):
+				action = ReconcileBind
+			case r.opt.bindings != nil:
+				if err := listing(); err != nil {
+					return plan, err
+				}
+				action = ReconcileBind
+				if bindingListed(existing, t, bind) {
+					action = ReconcileMatch
+				}
+			}
+			plan.Steps = append(plan.Steps, ReconcileStep{Action: action, Topology: t, Binding: &bind})
+		}
+
+		for _, bind := range t.StaleBindings {
+			bind := bind
+			action := ReconcileUnbind
+			if keyOf(t).entry != nil || created(states, keyOf(t)) {
+				action = ReconcileMatch
+			} else if r.opt.bindings != nil {
+				if err := listing(); err != nil {
+					return plan, err
+				}
+				if !bindingListed(existing, t, bind) {
+					action = ReconcileMatch
+				}
+			}
+			plan.Steps = append(plan.Steps, ReconcileStep{Action: action, Topology: t, Binding: &bind, Stale: true})
+		}
+	}
+
+	return plan, nil
+}
+
+// created tells whether the entity is planned for creation.
+func created(states map[entityKey]ReconcileAction, key entityKey) bool {
+	state, found := states[key]
+	return found && state == ReconcileCreate
+}
+
+// Apply plans (see [Reconciler.Plan]), checks the existing entities by redeclaring them, then
+// brings the broker in line with the topology, handling the conflicts as set by [WithReconcilePolicy].
+// Recreating an entity drops its messages and its bindings, the desired ones being added again.
+// Under [WithReconcileDryRun], the existing entities are left unverified.
+// The returned plan tells the applied steps and their failures, joined in the error.
+func (r *Reconciler) Apply(ctx context.Context, topology []*TopologyOptions) (*ReconcilePlan, error) {
+	plan, err := r.Plan(ctx, topology)
+	if err != nil {
+		return plan, err
+	}
+
+	session := &topologySession{ctx: ctx, conn: r.conn}
+	defer session.close()
+
+	if !r.opt.dryRun {
+		for i := range plan.Steps {
+			step := &plan.Steps[i]
+			if step.Binding != nil || step.Action != ReconcileUnverified {
+				continue
+			}
+			action, err := session.verify(step.Topology)
+			if action < 0 {
+				return plan, err
+			}
+			step.Action, step.Err = action, err
+		}
+	}
+	if conflicts := plan.Conflicts(); len(conflicts) != 0 && r.opt.policy == ConflictFail {
+		descriptions := make([]string, 0, len(conflicts))
+		for _, step := range conflicts {
+			descriptions = append(descriptions, fmt.Sprintf("%s: %v", step, step.Err))
+		}
+		return plan, fmt.Errorf("%w: %s", ErrTopologyConflict, strings.Join(descriptions, "; "))
+	}
+	if r.opt.dryRun {
+		return plan, nil
+	}
+
+	skipped := make(map[entityKey]bool)
+	names := make(map[entityKey]string) // server-named queues
+	recreated := false
+	var errs []error
+
+	for i := range plan.Steps {
+		if err := ctx.Err(); err != nil {
+			return plan, errors.Join(append(errs, err)...)
+		}
+		step := &plan.Steps[i]
+		t := step.Topology
+		key := keyOf(t)
+
+		switch {
+		case step.Binding != nil:
+			source, _ := t.RoutingOf(*step.Binding)
+			if skipped[key] || skipped[entityKey{exchange: true, name: source}] ||
+				step.Action == ReconcileMatch && (step.Stale || !recreated) {
+				continue
+			}
+			step.Err = session.bind(t, names[key], *step.Binding, !step.Stale)
+		case step.Action == ReconcileCreate:
+			names[key], step.Err = session.declare(t)
+			step.Name = names[key]
+		case step.Action == ReconcileConflict:
+			if r.opt.policy == ConflictSkip || t.Passive {
+				skipped[key] = true
+				continue
+			}
+			if step.Err = session.remove(t); step.Err == nil {
+				names[key], step.Err = session.declare(t)
+				step.Name = names[key]
+				recreated = true
+			}
+		default:
+			continue
+		}
+
+		step.Applied = step.Err == nil
+		if step.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", step, step.Err))
+		}
+		Event{
+			SourceType: CliConnection,
+			SourceName: r.conn.opt.name,
+			TargetName: t.Name,
+			Kind:       EventDefineTopology,
+			Err:        SomeErrFromError(step.Err, step.Err != nil),
+		}.raise(r.conn.opt.notifier)
+		r.conn.log(r.conn.opt.logLevels.Topology, "topology reconciled: "+step.String(), step.Err)
+	}
+
+	return plan, errors.Join(errs...)
+}
+
+// bindingListed tells whether the binding of the entry exists.
+func bindingListed(existing []BindingDefinition, t *TopologyOptions, bind TopologyBind) bool {
+	source, destination := t.RoutingOf(bind)
+	kind := "queue"
+	if t.IsExchange {
+		kind = "exchange"
+	}
+	for _, b := range existing {
+		destinationType := b.DestinationType
+		if destinationType == "" {
+			destinationType = "queue"
+		}
+		if b.Source == source && b.Destination == destination && destinationType == kind &&
+			sameBind(TopologyBind{Peer: bind.Peer, Key: b.RoutingKey, Args: b.Arguments}, bind) {
+			return true
+		}
+	}
+	return false
+}
+
+// ManagementBindings returns a [BindingLister] querying the RabbitMQ
+// management HTTP API (ex: "http://node1:15672") for the bindings of the virtual host.
+func ManagementBindings(apiURL, username, password, vhost string) BindingLister {
+	endpoint := fmt.Sprintf("%s/api/bindings/%s", strings.TrimSuffix(apiURL, "/"), url.PathEscape(vhost))
+
+	return func(ctx context.Context) ([]BindingDefinition, error) {
+		var bindings []BindingDefinition
+		if err := managementGet(ctx, endpoint, username, password, &bindings); err != nil {
+			return nil, err
+		}
+		for i := range bindings {
+			normalizeTable(bindings[i].Arguments)
+		}
+		return bindings, nil
+	}
+}
+
+// topologySession runs topology commands on a channel of its own,
+// opened again after the broker closes it on a refused command.
+type topologySession struct {
+	ctx  context.Context
+	conn *Connection
+	ch   *amqp.Channel
+}
+
+// do runs the command, dropping the channel closed by a refusal.
+// A connection being recovered is waited for till the context is done.
+func (s *topologySession) do(command func(ch *amqp.Channel) error) error {
+	if s.ch == nil || s.ch.IsClosed() {
+		ch, err := s.channel()
+		if err != nil {
+			return err
+		}
+		s.ch = ch
+	}
+
+	err := command(s.ch)
+	if isRefusal(err) {
+		s.close()
+	}
+	return err
+}
+
+// channel opens a channel, polling while the connection is down.
+func (s *topologySession) channel() (*amqp.Channel, error) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		ch, err := s.conn.Channel()
+		if err == nil {
+			return ch, nil
+		}
+		select {
+		case <-s.ctx.Done():
+			return nil, errors.Join(err, s.ctx.Err())
+		case <-s.conn.opt.ctx.Done():
+			return nil, err
+		case <-ticker.C:
+		}
+	}
+}
+
+// close releases the channel.
+func (s *topologySession) close() {
+	if s.ch != nil {
+		_ = s.ch.Close()
+		s.ch = nil
+	}
+}
+
+// isRefusal tells whether the broker refused a command (soft error closing the channel),
+// as opposed to a connection failure.
+func isRefusal(err error) bool {
+	var amqpErr *amqp.Error
+	return errors.As(err, &amqpErr) && amqpErr.Server && amqpErr.Recover
+}
+
+// isCode tells whether the broker refused a command with the reply code.
+func isCode(err error, code int) bool {
+	var amqpErr *amqp.Error
+	return errors.As(err, &amqpErr) && amqpErr.Code == code
+}
+
+// inspect returns the state of the entity on the broker, declaring it passively only,
+// a negative action along the error when the broker cannot be reached.
+func (s *topologySession) inspect(t *TopologyOptions) (ReconcileAction, error) {
+	if !t.IsExchange && t.Name == "" {
+		return ReconcileCreate, nil
+	}
+
+	err := s.do(func(ch *amqp.Channel) error {
+		if t.IsExchange {
+			return ch.ExchangeDeclarePassive(t.Name, t.Kind, t.Durable, t.AutoDelete, t.Internal, false, t.Args)
+		}
+		_, err := ch.QueueDeclarePassive(t.Name, t.Durable, t.AutoDelete, t.Exclusive, false, t.Args)
+		return err
+	})
+	switch {
+	case isCode(err, amqp.NotFound) && !t.Passive:
+		return ReconcileCreate, nil
+	case isRefusal(err):
+		return ReconcileConflict, err
+	case err != nil:
+		return -1, err
+	case t.Passive || strings.HasPrefix(t.Name, "amq."):
+		return ReconcileMatch, nil
+	}
+	return ReconcileUnverified, nil
+}
+
+// verify checks an existing entity by redeclaring it, which changes nothing when equivalent.
+// It returns a negative action along the error when the broker cannot be reached.
+func (s *topologySession) verify(t *TopologyOptions) (ReconcileAction, error) {
+	err := s.do(func(ch *amqp.Channel) error {
+		if t.IsExchange {
+			return ch.ExchangeDeclare(t.Name, t.Kind, t.Durable, t.AutoDelete, t.Internal, false, t.Args)
+		}
+		_, err := ch.QueueDeclare(t.Name, t.Durable, t.AutoDelete, t.Exclusive, false, t.Args)
+		return err
+	})
+	switch {
+	case isRefusal(err):
+		return ReconcileConflict, err
+	case err != nil:
+		return -1, err
+	}
+	return ReconcileMatch, nil
+}
+
+// declare creates the entity, returning the name of the queue.
+func (s *topologySession) declare(t *TopologyOptions) (string, error) {
+	name := t.Name
+	err := s.do(func(ch *amqp.Channel) error {
+		if t.IsExchange {
+			return ch.ExchangeDeclare(t.Name, t.Kind, t.Durable, t.AutoDelete, t.Internal, false, t.Args)
+		}
+		queue, err := ch.QueueDeclare(t.Name, t.Durable, t.AutoDelete, t.Exclusive, false, t.Args)
+		name = queue.Name
+		return err
+	})
+	return name, err
+}
+
+// remove deletes the entity.
+func (s *topologySession) remove(t *TopologyOptions) error {
+	return s.do(func(ch *amqp.Channel) error {
+		if t.IsExchange {
+			return ch.ExchangeDelete(t.Name, false, false)
+		}
+		_, err := ch.QueueDelete(t.Name, false, false, false)
+		return err
+	})
+}
+
+// bind adds or removes a binding of the entry, to the named queue when server-named.
+func (s *topologySession) bind(t *TopologyOptions, name string, bind TopologyBind, add bool) error {
+	if name == "" {
+		name = t.Name
+	}
+	bind.NoWait = false
+	return s.do(func(ch *amqp.Channel) error {
+		return applyBind(ch, t, name, bind, add)
+	})
+}
@@ -0,0 +1,59 @@
+package grabbit
+
+import (
+	"context"
+)
+
+// ConflictPolicy tells how a [Reconciler] handles the entities existing on the broker
+// with other attributes or arguments than desired.
+type ConflictPolicy int
+
+const (
+	ConflictFail     ConflictPolicy = iota // apply nothing, return ErrTopologyConflict
+	ConflictSkip                           // leave the conflicting entities and their bindings as they are
+	ConflictRecreate                       // delete the conflicting entities (and their messages!) and declare them again
+)
+
+// BindingLister returns the bindings existing on the broker, ex: [ManagementBindings].
+type BindingLister func(ctx context.Context) ([]BindingDefinition, error)
+
+// ReconcilerOptions defines the behavior of a [Reconciler].
+//
+// Attributes can be set via optionFuncs parameters of [NewReconciler]
+// via WithReconcile<Fct> family, ex: [WithReconcilePolicy], [WithReconcileDryRun].
+type ReconcilerOptions struct {
+	policy   ConflictPolicy // handling of the conflicting entities
+	dryRun   bool           // plan only
+	bindings BindingLister  // checks the bindings of the existing entities
+}
+
+// DefaultReconcilerOptions fails on conflicts and cannot verify the bindings.
+func DefaultReconcilerOptions() ReconcilerOptions {
+	return ReconcilerOptions{
+		policy: ConflictFail,
+	}
+}
+
+// WithReconcilePolicy sets the handling of the entities conflicting with the desired topology.
+func WithReconcilePolicy(policy ConflictPolicy) func(options *ReconcilerOptions) {
+	return func(options *ReconcilerOptions) {
+		options.policy = policy
+	}
+}
+
+// WithReconcileDryRun makes [Reconciler.Apply] compute the plan without changing the broker,
+// inspecting passively only: the existing entities are left [ReconcileUnverified] and the
+// conflicts found (ex: a missing passive entity) still fail under [ConflictFail].
+func WithReconcileDryRun(dryRun bool) func(options *ReconcilerOptions) {
+	return func(options *ReconcilerOptions) {
+		options.dryRun = dryRun
+	}
+}
+
+// WithReconcileBindings sets the source of the existing bindings, which AMQP cannot inspect.
+// Without it, the bindings of existing entities are planned as [ReconcileUnverified].
+func WithReconcileBindings(lister BindingLister) func(options *ReconcilerOptions) {
+	return func(options *ReconcilerOptions) {
+		options.bindings = lister
+	}
+}
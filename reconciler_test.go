@@ -0,0 +1,173 @@
+package grabbit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// ordersTopology routes an exchange into a queue.
+func ordersTopology(durable bool) []*TopologyOptions {
+	return []*TopologyOptions{
+		{Name: "orders.x", IsExchange: true, Kind: "direct", Durable: true, Declare: true},
+		{Name: "orders", Durable: durable, Declare: true, IsDestination: true,
+			Bind: TopologyBind{Enabled: true, Peer: "orders.x", Key: "order"}},
+	}
+}
+
+// actions lists the actions of the plan steps.
+func actions(plan *ReconcilePlan) []ReconcileAction {
+	var actions []ReconcileAction
+	for _, step := range plan.Steps {
+		actions = append(actions, step.Action)
+	}
+	return actions
+}
+
+func sameActions(got []ReconcileAction, want ...ReconcileAction) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReconcilerCreatesMissingTopology(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	reconciler := NewReconciler(conn)
+
+	plan, err := reconciler.Apply(context.Background(), ordersTopology(true))
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if got := actions(plan); !sameActions(got, ReconcileCreate, ReconcileCreate, ReconcileBind) {
+		t.Fatalf("plan %v", got)
+	}
+	for _, step := range plan.Steps {
+		if !step.Applied {
+			t.Fatalf("step not applied: %s", step)
+		}
+	}
+	if n := srv.Publish("orders.x", "order", amqp.Publishing{}); n != 1 {
+		t.Fatalf("routed to %d queues", n)
+	}
+
+	// existing entities and their bindings cannot be checked passively
+	plan, err = reconciler.Plan(context.Background(), ordersTopology(true))
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if got := actions(plan); !plan.InSync() || !sameActions(got, ReconcileUnverified, ReconcileUnverified, ReconcileUnverified) {
+		t.Fatalf("plan %v", got)
+	}
+
+	// applying checks the entities by redeclaring them
+	plan, err = reconciler.Apply(context.Background(), ordersTopology(true))
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if got := actions(plan); !sameActions(got, ReconcileMatch, ReconcileMatch, ReconcileUnverified) {
+		t.Fatalf("plan %v", got)
+	}
+}
+
+func TestReconcilerDryRun(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	plan, err := NewReconciler(conn, WithReconcileDryRun(true)).Apply(context.Background(), ordersTopology(true))
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if plan.InSync() {
+		t.Fatal("missing topology in sync")
+	}
+	if srv.HasExchange("orders.x") {
+		t.Fatal("exchange declared")
+	}
+	if _, found := srv.Queue("orders"); found {
+		t.Fatal("queue declared")
+	}
+}
+
+func TestReconcilerConflictPolicies(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	if _, err := NewReconciler(conn).Apply(context.Background(), ordersTopology(false)); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	srv.Publish("orders.x", "order", amqp.Publishing{})
+
+	// inspected passively only, the conflict goes unnoticed
+	plan, err := NewReconciler(conn, WithReconcileDryRun(true)).Apply(context.Background(), ordersTopology(true))
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if got := actions(plan); !sameActions(got, ReconcileUnverified, ReconcileUnverified, ReconcileUnverified) {
+		t.Fatalf("plan %v", got)
+	}
+
+	_, err = NewReconciler(conn).Apply(context.Background(), ordersTopology(true))
+	if !errors.Is(err, ErrTopologyConflict) {
+		t.Fatalf("error %v", err)
+	}
+	if info, _ := srv.Queue("orders"); info.Durable || info.Ready != 1 {
+		t.Fatalf("conflicting queue changed: %+v", info)
+	}
+
+	plan, err = NewReconciler(conn, WithReconcilePolicy(ConflictSkip)).Apply(context.Background(), ordersTopology(true))
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if conflicts := plan.Conflicts(); len(conflicts) != 1 || conflicts[0].Applied {
+		t.Fatalf("conflicts %v", conflicts)
+	}
+	if info, _ := srv.Queue("orders"); info.Durable || info.Ready != 1 {
+		t.Fatalf("skipped queue changed: %+v", info)
+	}
+
+	if _, err := NewReconciler(conn, WithReconcilePolicy(ConflictRecreate)).Apply(context.Background(), ordersTopology(true)); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if info, _ := srv.Queue("orders"); !info.Durable || info.Ready != 0 {
+		t.Fatalf("queue not recreated: %+v", info)
+	}
+	if n := srv.Publish("orders.x", "order", amqp.Publishing{}); n != 1 {
+		t.Fatalf("recreated queue routed from %d exchanges", n)
+	}
+}
+
+func TestReconcilerListedBindings(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	if _, err := NewReconciler(conn).Apply(context.Background(), ordersTopology(true)); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	topology := ordersTopology(true)
+	topology[1].Bind.Key = "order.new"
+	topology[1].StaleBindings = []TopologyBind{{Peer: "orders.x", Key: "order"}}
+	lister := func(ctx context.Context) ([]BindingDefinition, error) {
+		return []BindingDefinition{{Source: "orders.x", Destination: "orders", RoutingKey: "order"}}, nil
+	}
+
+	plan, err := NewReconciler(conn, WithReconcileBindings(lister)).Apply(context.Background(), topology)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if got := actions(plan); !sameActions(got, ReconcileMatch, ReconcileMatch, ReconcileBind, ReconcileUnbind) {
+		t.Fatalf("plan %v", got)
+	}
+	if srv.Publish("orders.x", "order", amqp.Publishing{}) != 0 || srv.Publish("orders.x", "order.new", amqp.Publishing{}) != 1 {
+		t.Fatal("bindings not reconciled")
+	}
+}
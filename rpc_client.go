@@ -0,0 +1,153 @@
+package grabbit
+
+import (
+	"context"
+	"sync"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// RPCErrorHeader carries the error returned by the [RPCHandler] of an [RPCServer].
+const RPCErrorHeader = "x-rpc-error"
+
+// RPCError is returned by [RPCClient.Call] when the remote handler failed.
+type RPCError struct {
+	Message string // remote error text
+}
+
+// Error implements the error interface.
+func (e *RPCError) Error() string {
+	return "remote procedure failed: " + e.Message
+}
+
+// RPCClient implements request/reply calls over a dedicated channel, correlating
+// the replies by their CorrelationId. Create a client instance by calling [NewRPCClient].
+//
+// The channel recovers as any other managed [Channel]; calls in flight while
+// it is down get no reply and end with their context.
+type RPCClient struct {
+	channel *Channel         // assigned channel, used for both requests and replies
+	opt     RPCClientOptions // specific options
+	mu      sync.Mutex
+	pending map[string]chan Delivery // calls waiting for a reply, by correlation id
+}
+
+// NewRPCClient creates a request/reply client with the desired options.
+// It creates and opens a new dedicated [Channel] using the passed shared connection.
+func NewRPCClient(conn *Connection, opt RPCClientOptions, optionFuncs ...func(*ChannelOptions)) *RPCClient {
+	if opt.Context == nil {
+		opt.Context = context.Background()
+	}
+	c := &RPCClient{
+		opt:     opt,
+		pending: make(map[string]chan Delivery),
+	}
+
+	useParams := ChanUsageParameters{
+		ConsumerUsageOptions: ConsumerUsageOptions{
+			IsConsumer:      true,
+			ConsumerName:    RandConsumerName(),
+			ConsumerQueue:   DirectReplyTo,
+			ConsumerAutoAck: true,
+		},
+	}
+	chanOpt := append(optionFuncs, WithChannelUsageParams(useParams), withChannelHandler(c.reply))
+	if opt.ReplyQueue {
+		useParams.ConsumerQueue = ""
+		chanOpt = append(chanOpt,
+			WithChannelUsageParams(useParams),
+			WithChannelTopology([]*TopologyOptions{
+				{IsDestination: true, Exclusive: true, AutoDelete: true, Declare: true},
+			}))
+	}
+
+	c.channel = NewChannel(conn, chanOpt...)
+
+	return c
+}
+
+// Channel returns the managed [Channel] which can be further used to extract [SafeBaseChan]
+func (c *RPCClient) Channel() *Channel {
+	return c.channel
+}
+
+// reply hands over a received reply to its waiting call.
+func (c *RPCClient) reply(ctx context.Context, msg Delivery) Result {
+	c.mu.Lock()
+	waiter, ok := c.pending[msg.CorrelationId]
+	delete(c.pending, msg.CorrelationId)
+	c.mu.Unlock()
+
+	if ok {
+		waiter <- msg // buffered
+	}
+
+	return Ack
+}
+
+// Call publishes the request and waits for its reply. The CorrelationId is generated
+// when empty and the ReplyTo gets overwritten. It returns the reply along with an
+// [RPCError] when the server reported a failure, or the context error when no
+// reply arrived in time (see [RPCClientOptions.WithTimeout]).
+func (c *RPCClient) Call(ctx context.Context, msg amqp.Publishing) (Delivery, error) {
+	if _, ok := ctx.Deadline(); !ok && c.opt.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opt.Timeout)
+		defer cancel()
+	}
+
+	msg.ReplyTo = DirectReplyTo
+	if c.opt.ReplyQueue {
+		msg.ReplyTo = c.channel.Queue()
+	}
+	if c.channel.IsClosed() || msg.ReplyTo == "" {
+		return Delivery{}, amqp.ErrClosed
+	}
+	if msg.CorrelationId == "" {
+		msg.CorrelationId = RandConsumerName()
+	}
+
+	waiter := make(chan Delivery, 1)
+	c.mu.Lock()
+	c.pending[msg.CorrelationId] = waiter
+	c.mu.Unlock()
+
+	forget := func() {
+		c.mu.Lock()
+		delete(c.pending, msg.CorrelationId)
+		c.mu.Unlock()
+	}
+
+	if err := c.channel.PublishWithContext(ctx, c.opt.Exchange, c.opt.Key, false, false, msg); err != nil {
+		forget()
+		return Delivery{}, err
+	}
+
+	select {
+	case reply := <-waiter:
+		if text, ok := reply.Headers[RPCErrorHeader].(string); ok {
+			return reply, &RPCError{Message: text}
+		}
+		return reply, nil
+	case <-ctx.Done():
+		forget()
+		return Delivery{}, ctx.Err()
+	case <-c.opt.Context.Done():
+		forget()
+		return Delivery{}, c.opt.Context.Err()
+	}
+}
+
+// Pending returns the number of calls waiting for their reply.
+func (c *RPCClient) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.pending)
+}
+
+// Close shuts down cleanly the client channel.
+func (c *RPCClient) Close() error {
+	return c.channel.Close()
+}
+
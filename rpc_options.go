@@ -0,0 +1,60 @@
+package grabbit
+
+import (
+	"context"
+	"time"
+)
+
+// DirectReplyTo is the RabbitMQ pseudo queue used by [RPCClient] for receiving
+// replies without declaring a reply queue.
+const DirectReplyTo = "amq.rabbitmq.reply-to"
+
+// RPCClientOptions defines the request/reply client parameters.
+type RPCClientOptions struct {
+	Context    context.Context // controlling environment; nil means context.Background()
+	Exchange   string          // routing exchange of the requests
+	Key        string          // routing key of the requests (usually the server queue)
+	ReplyQueue bool            // exclusive auto-delete reply queue instead of direct reply-to
+	Timeout    time.Duration   // applied to calls whose context has no deadline; 0 waits forever
+}
+
+// DefaultRPCClientOptions creates some sane defaults for calling remote procedures:
+// direct reply-to and a 30 seconds timeout.
+func DefaultRPCClientOptions() RPCClientOptions {
+	return RPCClientOptions{
+		Context: context.Background(),
+		Timeout: 30 * time.Second,
+	}
+}
+
+// WithContext sets the context for the RPCClientOptions.
+func (opt *RPCClientOptions) WithContext(ctx context.Context) *RPCClientOptions {
+	opt.Context = ctx
+	return opt
+}
+
+// WithExchange sets the exchange the requests are published to.
+func (opt *RPCClientOptions) WithExchange(exchange string) *RPCClientOptions {
+	opt.Exchange = exchange
+	return opt
+}
+
+// WithKey sets the routing key of the requests.
+func (opt *RPCClientOptions) WithKey(key string) *RPCClientOptions {
+	opt.Key = key
+	return opt
+}
+
+// WithReplyQueue selects an exclusive, auto-delete, server named reply queue
+// instead of the direct reply-to feature (see [DirectReplyTo]).
+// The queue is redeclared when the channel recovers.
+func (opt *RPCClientOptions) WithReplyQueue(replyQueue bool) *RPCClientOptions {
+	opt.ReplyQueue = replyQueue
+	return opt
+}
+
+// WithTimeout sets the default timeout of the calls whose context has no deadline.
+func (opt *RPCClientOptions) WithTimeout(timeout time.Duration) *RPCClientOptions {
+	opt.Timeout = timeout
+	return opt
+}
@@ -0,0 +1,69 @@
+package grabbit
+
+import (
+	"context"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// RPCHandler defines a function type serving one request of an [RPCServer].
+// The returned publishing is sent back to the requester; an error is
+// reported to it via the [RPCErrorHeader] header of an empty reply.
+type RPCHandler func(ctx context.Context, request Delivery) (amqp.Publishing, error)
+
+// RPCServer consumes requests from a queue and replies to their ReplyTo address,
+// preserving the CorrelationId. Create a server instance by calling [NewRPCServer].
+type RPCServer struct {
+	consumer *Consumer     // requests consumer, also used for replying
+	handler  RPCHandler    // user defined request processing
+	ready    chan struct{} // closed once the consumer is assigned
+}
+
+// NewRPCServer creates a request/reply server with the desired consumer options
+// (queue, prefetch, workers etc.) and then starts serving.
+// It creates and opens a new dedicated [Channel] using the passed shared connection.
+func NewRPCServer(conn *Connection, opt ConsumerOptions, handler RPCHandler, optionFuncs ...func(*ChannelOptions)) *RPCServer {
+	s := &RPCServer{
+		handler: handler,
+		ready:   make(chan struct{}),
+	}
+
+	opt.Handler = s.serve
+	s.consumer = NewConsumer(conn, opt, optionFuncs...)
+	close(s.ready)
+
+	return s
+}
+
+// serve handles one request and publishes the reply. Requests without a ReplyTo
+// address are handled as one-way messages.
+func (s *RPCServer) serve(ctx context.Context, request Delivery) Result {
+	<-s.ready
+
+	reply, err := s.handler(ctx, request)
+	if request.ReplyTo == "" {
+		return Ack
+	}
+
+	if err != nil {
+		reply = amqp.Publishing{Headers: amqp.Table{RPCErrorHeader: err.Error()}}
+	}
+	reply.CorrelationId = request.CorrelationId
+
+	if err := s.consumer.Channel().PublishWithContext(ctx, "", request.ReplyTo, false, false, reply); err != nil {
+		// try again on the recovered channel
+		return NackRequeue
+	}
+
+	return Ack
+}
+
+// Consumer returns the underlying requests [Consumer].
+func (s *RPCServer) Consumer() *Consumer {
+	return s.consumer
+}
+
+// Close shuts down cleanly the server channel.
+func (s *RPCServer) Close() error {
+	return s.consumer.Close()
+}
@@ -0,0 +1,144 @@
+package grabbit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// newTestRPCClient creates a client of the key, waiting for its reply consumer.
+func newTestRPCClient(t *testing.T, conn *Connection, opt RPCClientOptions) *RPCClient {
+	t.Helper()
+
+	client := NewRPCClient(conn, opt, WithChannelDelay(testDelay))
+	t.Cleanup(func() { client.Close() })
+	eventually(t, 5*time.Second, func() bool {
+		return !client.Channel().IsClosed() && (!opt.ReplyQueue || client.Channel().Queue() != "")
+	}, "client not started")
+
+	return client
+}
+
+func TestRPCCallServe(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	opt := DefaultConsumerOptions()
+	opt.WithQueue("rpc")
+	server := NewRPCServer(conn, opt, func(ctx context.Context, request Delivery) (amqp.Publishing, error) {
+		if len(request.Body) == 0 {
+			return amqp.Publishing{}, errors.New("empty request")
+		}
+		return amqp.Publishing{Body: []byte(strings.ToUpper(string(request.Body)))}, nil
+	}, WithChannelDelay(testDelay), WithChannelTopology(testQueue("rpc")))
+	defer server.Close()
+	eventually(t, 5*time.Second, func() bool {
+		info, _ := srv.Queue("rpc")
+		return info.Consumers == 1
+	}, "server not started")
+
+	for _, replyQueue := range []bool{false, true} {
+		clientOpt := DefaultRPCClientOptions()
+		clientOpt.WithKey("rpc").WithReplyQueue(replyQueue).WithTimeout(5 * time.Second)
+		client := newTestRPCClient(t, conn, clientOpt)
+
+		reply, err := client.Call(context.Background(), amqp.Publishing{CorrelationId: "c1", Body: []byte("ping")})
+		if err != nil || string(reply.Body) != "PING" || reply.CorrelationId != "c1" {
+			t.Fatalf("reply queue %v: reply %q (%s), %v", replyQueue, reply.Body, reply.CorrelationId, err)
+		}
+
+		var rpcErr *RPCError
+		if _, err := client.Call(context.Background(), amqp.Publishing{}); !errors.As(err, &rpcErr) || rpcErr.Message != "empty request" {
+			t.Fatalf("reply queue %v: error %v", replyQueue, err)
+		}
+		if n := client.Pending(); n != 0 {
+			t.Fatalf("reply queue %v: %d calls pending", replyQueue, n)
+		}
+	}
+}
+
+func TestRPCCallTimeout(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	ch := NewChannel(conn, WithChannelDelay(testDelay), WithChannelTopology(testQueue("unserved")))
+	defer ch.Close()
+	eventually(t, 5*time.Second, func() bool {
+		_, ok := srv.Queue("unserved")
+		return ok
+	}, "queue not declared")
+
+	// options built by hand: no context
+	client := newTestRPCClient(t, conn, RPCClientOptions{Key: "unserved", Timeout: 100 * time.Millisecond})
+
+	started := time.Now()
+	if _, err := client.Call(context.Background(), amqp.Publishing{Body: []byte("ping")}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error %v", err)
+	}
+	if elapsed := time.Since(started); elapsed < 100*time.Millisecond || elapsed > 2*time.Second {
+		t.Fatalf("timed out after %v", elapsed)
+	}
+
+	// the deadline of the call prevails
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	started = time.Now()
+	if _, err := client.Call(ctx, amqp.Publishing{}); !errors.Is(err, context.DeadlineExceeded) || time.Since(started) > time.Second {
+		t.Fatalf("error %v after %v", err, time.Since(started))
+	}
+
+	// as does the context of the client
+	clientCtx, cancelClient := context.WithCancel(context.Background())
+	clientOpt := DefaultRPCClientOptions()
+	clientOpt.WithKey("unserved").WithContext(clientCtx)
+	closing := newTestRPCClient(t, conn, clientOpt)
+	time.AfterFunc(20*time.Millisecond, cancelClient)
+	if _, err := closing.Call(context.Background(), amqp.Publishing{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error %v", err)
+	}
+
+	if client.Pending()+closing.Pending() != 0 {
+		t.Fatal("timed out calls left pending")
+	}
+}
+
+func TestRPCCorrelationMismatch(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	// replies first for another call, then for the request
+	var replier *Consumer
+	opt := DefaultConsumerOptions()
+	opt.WithQueue("confused").WithHandler(func(ctx context.Context, request Delivery) Result {
+		for _, id := range []string{"someone-else", request.CorrelationId} {
+			reply := amqp.Publishing{CorrelationId: id, Body: []byte(id)}
+			if err := replier.Channel().PublishWithContext(ctx, "", request.ReplyTo, false, false, reply); err != nil {
+				return NackRequeue
+			}
+		}
+		return Ack
+	})
+	replier = NewConsumer(conn, opt, WithChannelDelay(testDelay), WithChannelTopology(testQueue("confused")))
+	defer replier.Close()
+	eventually(t, 5*time.Second, func() bool {
+		info, _ := srv.Queue("confused")
+		return info.Consumers == 1
+	}, "replier not started")
+
+	clientOpt := DefaultRPCClientOptions()
+	clientOpt.WithKey("confused").WithTimeout(5 * time.Second)
+	client := newTestRPCClient(t, conn, clientOpt)
+
+	// the stray reply, correlated to no call, is dropped
+	reply, err := client.Call(context.Background(), amqp.Publishing{CorrelationId: "mine"})
+	if err != nil || reply.CorrelationId != "mine" || string(reply.Body) != "mine" {
+		t.Fatalf("reply %q (%s), %v", reply.Body, reply.CorrelationId, err)
+	}
+
+	if n := client.Pending(); n != 0 {
+		t.Fatalf("%d calls pending", n)
+	}
+}
@@ -0,0 +1,448 @@
+package grabbit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// ErrInvalidTopology is wrapped by the validation errors of [TopologyDefinitions.Options].
+var ErrInvalidTopology = errors.New("invalid topology")
+
+// TopologyDefinitions is a declarative topology, laid out as the exchanges, queues and
+// bindings sections of a RabbitMQ definitions export (other sections are ignored),
+// so that such exports load as they are. Next to the RabbitMQ attributes, entities
+// accept grabbit ones (passive, no_wait, declare, consumed) and their own lists of bindings
+// and of stale bindings to remove.
+//
+// Example document:
+//
+//	exchanges:
+//	  - name: orders
+//	    type: topic
+//	    durable: true
+//	queues:
+//	  - name: orders.billing
+//	    durable: true
+//	    consumed: true
+//	    arguments: {x-queue-type: quorum}
+//	    bindings:
+//	      - {source: orders, routing_key: order.created}
+//	      - {source: orders, routing_key: order.cancelled}
+type TopologyDefinitions struct {
+	Exchanges []ExchangeDefinition `json:"exchanges"`
+	Queues    []QueueDefinition    `json:"queues"`
+	Bindings  []BindingDefinition  `json:"bindings"`
+}
+
+// ExchangeDefinition describes an exchange of [TopologyDefinitions].
+type ExchangeDefinition struct {
+	Name       string          `json:"name"`
+	VHost      string          `json:"vhost,omitempty"`
+	Type       string          `json:"type"` // direct, topic, fanout, headers or x-* plugin types
+	Durable    bool            `json:"durable"`
+	AutoDelete bool            `json:"auto_delete"`
+	Internal   bool            `json:"internal"`
+	Arguments  amqp.Table      `json:"arguments,omitempty"`
+	Passive    bool            `json:"passive,omitempty"`        // only checked for existence
+	NoWait     bool            `json:"no_wait,omitempty"`        // no server confirmation
+	Declare    *bool           `json:"declare,omitempty"`        // true when missing
+	Bindings   []EntityBinding `json:"bindings,omitempty"`       // exchanges routing to this one
+	Stale      []EntityBinding `json:"stale_bindings,omitempty"` // bindings to remove
+}
+
+// QueueDefinition describes a queue of [TopologyDefinitions].
+type QueueDefinition struct {
+	Name       string          `json:"name"` // empty for a server-named queue
+	VHost      string          `json:"vhost,omitempty"`
+	Durable    bool            `json:"durable"`
+	AutoDelete bool            `json:"auto_delete"`
+	Exclusive  bool            `json:"exclusive,omitempty"`
+	Arguments  amqp.Table      `json:"arguments,omitempty"`
+	Passive    bool            `json:"passive,omitempty"`        // only checked for existence
+	NoWait     bool            `json:"no_wait,omitempty"`        // no server confirmation
+	Declare    *bool           `json:"declare,omitempty"`        // true when missing
+	Consumed   bool            `json:"consumed,omitempty"`       // the queue consumed by the channel, see [Channel.Queue]
+	Bindings   []EntityBinding `json:"bindings,omitempty"`       // exchanges routing to this queue
+	Stale      []EntityBinding `json:"stale_bindings,omitempty"` // bindings to remove
+}
+
+// EntityBinding routes the messages of a source exchange to the entity listing it.
+type EntityBinding struct {
+	Source     string     `json:"source"`
+	RoutingKey string     `json:"routing_key"`
+	Arguments  amqp.Table `json:"arguments,omitempty"`
+	NoWait     bool       `json:"no_wait,omitempty"`
+}
+
+// BindingDefinition routes the messages of a source exchange to a queue or exchange,
+// as in the bindings section of a RabbitMQ definitions export.
+type BindingDefinition struct {
+	Source          string     `json:"source"`
+	VHost           string     `json:"vhost,omitempty"`
+	Destination     string     `json:"destination"`
+	DestinationType string     `json:"destination_type"` // queue (when empty) or exchange
+	RoutingKey      string     `json:"routing_key"`
+	Arguments       amqp.Table `json:"arguments,omitempty"`
+	NoWait          bool       `json:"no_wait,omitempty"`
+}
+
+// LoadTopologyFile reads a YAML or JSON topology document, see [LoadTopology].
+func LoadTopologyFile(path string) ([]*TopologyOptions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadTopology(data)
+}
+
+// LoadTopology decodes and validates a YAML or JSON topology document (see
+// [TopologyDefinitions]) into the options of [WithChannelTopology].
+func LoadTopology(data []byte) ([]*TopologyOptions, error) {
+	defs, err := ParseTopology(data)
+	if err != nil {
+		return nil, err
+	}
+	return defs.Options()
+}
+
+// ParseTopology decodes a YAML or JSON topology document, the latter
+// being recognized by its leading brace. YAML documents are read as a subset:
+// block and flow collections, quoted and plain scalars and comments of a single
+// document; anchors, aliases, tags and multi-line scalars are rejected.
+func ParseTopology(data []byte) (*TopologyDefinitions, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		tree, err := parseYAML(data)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(tree); err != nil {
+			return nil, err
+		}
+	}
+
+	defs := &TopologyDefinitions{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(defs); err != nil {
+		return nil, fmt.Errorf("topology: %w", err)
+	}
+	defs.normalize()
+
+	return defs, nil
+}
+
+// normalize converts the numeric arguments to the AMQP integer or float types:
+// the broker rejects a float x-message-ttl.
+func (d *TopologyDefinitions) normalize() {
+	for i := range d.Exchanges {
+		normalizeTable(d.Exchanges[i].Arguments)
+		for _, bindings := range [][]EntityBinding{d.Exchanges[i].Bindings, d.Exchanges[i].Stale} {
+			for _, b := range bindings {
+				normalizeTable(b.Arguments)
+			}
+		}
+	}
+	for i := range d.Queues {
+		normalizeTable(d.Queues[i].Arguments)
+		for _, bindings := range [][]EntityBinding{d.Queues[i].Bindings, d.Queues[i].Stale} {
+			for _, b := range bindings {
+				normalizeTable(b.Arguments)
+			}
+		}
+	}
+	for i := range d.Bindings {
+		normalizeTable(d.Bindings[i].Arguments)
+	}
+}
+
+// normalizeTable replaces the json.Number values, nested ones included.
+func normalizeTable(table amqp.Table) {
+	for key, value := range table {
+		table[key] = normalizeValue(value)
+	}
+}
+
+// normalizeValue returns json.Number as int64 or float64, tables and arrays as AMQP ones.
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		table := amqp.Table(v)
+		normalizeTable(table)
+		return table
+	case []any:
+		for i := range v {
+			v[i] = normalizeValue(v[i])
+		}
+	}
+	return value
+}
+
+// VHost returns the definitions of the virtual host, and those not tied to any.
+func (d *TopologyDefinitions) VHost(vhost string) *TopologyDefinitions {
+	selected := &TopologyDefinitions{}
+	for _, e := range d.Exchanges {
+		if e.VHost == "" || e.VHost == vhost {
+			selected.Exchanges = append(selected.Exchanges, e)
+		}
+	}
+	for _, q := range d.Queues {
+		if q.VHost == "" || q.VHost == vhost {
+			selected.Queues = append(selected.Queues, q)
+		}
+	}
+	for _, b := range d.Bindings {
+		if b.VHost == "" || b.VHost == vhost {
+			selected.Bindings = append(selected.Bindings, b)
+		}
+	}
+	return selected
+}
+
+// Options validates the definitions and converts them to the options of [WithChannelTopology]:
+// exchanges first, each after the exchanges it is bound from, then the queues,
+// their bindings listed in Bindings.
+//
+// The errors, wrapping [ErrInvalidTopology], report the bindings to unknown entities,
+// the kind mismatches (ex: a queue as binding source, an invalid exchange type)
+// and the exchange-to-exchange binding cycles.
+func (d *TopologyDefinitions) Options() ([]*TopologyOptions, error) {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidTopology, fmt.Sprintf(format, args...)))
+	}
+
+	exchanges := make(map[string]*ExchangeDefinition, len(d.Exchanges))
+	for i := range d.Exchanges {
+		e := &d.Exchanges[i]
+		switch {
+		case e.Name == "":
+			invalid("exchange #%d has no name", i)
+		case strings.HasPrefix(e.Name, "amq.") && !e.Passive:
+			invalid("exchange %q uses the reserved prefix amq.", e.Name)
+		case exchanges[e.Name] != nil:
+			invalid("exchange %q defined twice", e.Name)
+		}
+		if !validExchangeType(e.Type) {
+			invalid("exchange %q has invalid type %q", e.Name, e.Type)
+		}
+		exchanges[e.Name] = e
+	}
+	queues := make(map[string]*QueueDefinition, len(d.Queues))
+	for i := range d.Queues {
+		q := &d.Queues[i]
+		if q.Name == "" {
+			continue // server-named, thus distinct
+		}
+		if queues[q.Name] != nil {
+			invalid("queue %q defined twice", q.Name)
+		}
+		queues[q.Name] = q
+	}
+
+	// checks the source exchange of a binding
+	isSource := func(source, destination string) bool {
+		switch {
+		case source == "":
+			invalid("binding to %q from the default exchange", destination)
+		case exchanges[source] != nil, predefinedExchange(source):
+			return true
+		case queues[source] != nil:
+			invalid("binding to %q from %q: kind mismatch, source is a queue", destination, source)
+		default:
+			invalid("binding to %q from unknown exchange %q", destination, source)
+		}
+		return false
+	}
+
+	// bindings by destination entity
+	toQueue := make(map[string][]TopologyBind)
+	toExchange := make(map[string][]TopologyBind)
+	for i := range d.Exchanges {
+		e := &d.Exchanges[i]
+		for _, b := range e.Bindings {
+			if isSource(b.Source, e.Name) {
+				toExchange[e.Name] = append(toExchange[e.Name], entityBind(b))
+			}
+		}
+	}
+	for _, b := range d.Bindings {
+		bind := TopologyBind{Enabled: true, Peer: b.Source, Key: b.RoutingKey, NoWait: b.NoWait, Args: b.Arguments}
+		switch b.DestinationType {
+		case "", "queue":
+			switch {
+			case queues[b.Destination] != nil:
+				if isSource(b.Source, b.Destination) {
+					toQueue[b.Destination] = append(toQueue[b.Destination], bind)
+				}
+			case exchanges[b.Destination] != nil:
+				invalid("binding from %q to %q: kind mismatch, destination is an exchange", b.Source, b.Destination)
+			default:
+				invalid("binding from %q to unknown queue %q", b.Source, b.Destination)
+			}
+		case "exchange":
+			switch {
+			case exchanges[b.Destination] != nil:
+				if isSource(b.Source, b.Destination) {
+					toExchange[b.Destination] = append(toExchange[b.Destination], bind)
+				}
+			case queues[b.Destination] != nil:
+				invalid("binding from %q to %q: kind mismatch, destination is a queue", b.Source, b.Destination)
+			default:
+				invalid("binding from %q to unknown exchange %q", b.Source, b.Destination)
+			}
+		default:
+			invalid("binding from %q to %q has invalid destination type %q", b.Source, b.Destination, b.DestinationType)
+		}
+	}
+
+	order, cycles := exchangeOrder(d.Exchanges, toExchange)
+	for _, cycle := range cycles {
+		invalid("exchange-to-exchange binding cycle %s", strings.Join(cycle, " -> "))
+	}
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	var topology []*TopologyOptions
+	for _, e := range order {
+		topology = append(topology, &TopologyOptions{
+			Name:          e.Name,
+			IsExchange:    true,
+			IsDestination: true, // the bindings route from the peers
+			Kind:          e.Type,
+			Durable:       e.Durable,
+			AutoDelete:    e.AutoDelete,
+			Internal:      e.Internal,
+			NoWait:        e.NoWait,
+			Passive:       e.Passive,
+			Args:          e.Arguments,
+			Declare:       e.Declare == nil || *e.Declare,
+			Bindings:      toExchange[e.Name],
+			StaleBindings: entityBinds(e.Stale),
+		})
+	}
+	for i := range d.Queues {
+		q := &d.Queues[i]
+		t := &TopologyOptions{
+			Name:          q.Name,
+			IsDestination: q.Consumed,
+			Durable:       q.Durable,
+			AutoDelete:    q.AutoDelete,
+			Exclusive:     q.Exclusive,
+			NoWait:        q.NoWait,
+			Passive:       q.Passive,
+			Args:          q.Arguments,
+			Declare:       q.Declare == nil || *q.Declare,
+			StaleBindings: entityBinds(q.Stale),
+		}
+		if q.Name != "" {
+			t.Bindings = toQueue[q.Name]
+		}
+		for _, b := range q.Bindings {
+			if isSource(b.Source, q.Name) {
+				t.Bindings = append(t.Bindings, entityBind(b))
+			}
+		}
+		topology = append(topology, t)
+	}
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return topology, nil
+}
+
+// entityBinds converts the bindings listed by an entity.
+func entityBinds(bindings []EntityBinding) []TopologyBind {
+	var binds []TopologyBind
+	for _, b := range bindings {
+		binds = append(binds, entityBind(b))
+	}
+	return binds
+}
+
+// entityBind converts the binding listed by an entity.
+func entityBind(b EntityBinding) TopologyBind {
+	return TopologyBind{Enabled: true, Peer: b.Source, Key: b.RoutingKey, NoWait: b.NoWait, Args: b.Arguments}
+}
+
+// validExchangeType tells whether the broker knows, or may know via plugins, the exchange type.
+func validExchangeType(kind string) bool {
+	switch kind {
+	case amqp.ExchangeDirect, amqp.ExchangeTopic, amqp.ExchangeFanout, amqp.ExchangeHeaders:
+		return true
+	}
+	return strings.HasPrefix(kind, "x-")
+}
+
+// predefinedExchange tells whether the exchange exists on every virtual host.
+func predefinedExchange(name string) bool {
+	switch name {
+	case "amq.direct", "amq.fanout", "amq.topic", "amq.headers", "amq.match", "amq.rabbitmq.trace":
+		return true
+	}
+	return false
+}
+
+// exchangeOrder sorts the exchanges after the exchanges they are bound from,
+// and reports the binding cycles.
+func exchangeOrder(exchanges []ExchangeDefinition, bound map[string][]TopologyBind) ([]*ExchangeDefinition, [][]string) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	index := make(map[string]int, len(exchanges))
+	for i := range exchanges {
+		index[exchanges[i].Name] = i
+	}
+
+	var order []*ExchangeDefinition
+	var cycles [][]string
+	state := make([]int, len(exchanges))
+	var path []string
+
+	var visit func(i int)
+	visit = func(i int) {
+		name := exchanges[i].Name
+		switch state[i] {
+		case visited:
+			return
+		case visiting:
+			start := len(path) - 1
+			for path[start] != name {
+				start--
+			}
+			cycles = append(cycles, append(path[start:len(path):len(path)], name))
+			return
+		}
+
+		state[i] = visiting
+		path = append(path, name)
+		for _, bind := range bound[name] {
+			if source, found := index[bind.Peer]; found {
+				visit(source)
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		order = append(order, &exchanges[i])
+	}
+	for i := range exchanges {
+		visit(i)
+	}
+
+	return order, cycles
+}
@@ -0,0 +1,229 @@
+package grabbit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// errUnnamedQueue reports a server-named queue not declared by the [Topology] manager.
+var errUnnamedQueue = errors.New("server-named queue not declared by this manager")
+
+// TopologyResult is the outcome for one entity of a topology.
+type TopologyResult struct {
+	Topology  *TopologyOptions // the entity
+	Name      string           // name on the broker, assigned by the server for server-named queues
+	Action    ReconcileAction  // state found by [Topology.Apply]
+	Messages  int              // ready messages ([Topology.Inspect]), purged or deleted along the queue
+	Consumers int              // consumers of the queue ([Topology.Inspect])
+	Err       error            // failure for the entity or for one of its bindings
+}
+
+// String describes the result, ex: `queue "orders": 12 messages, 2 consumers`.
+func (r TopologyResult) String() string {
+	kind := "queue"
+	if r.Topology.IsExchange {
+		kind = "exchange"
+	}
+	description := fmt.Sprintf("%s %q", kind, r.Name)
+	if !r.Topology.IsExchange {
+		description += fmt.Sprintf(": %d messages, %d consumers", r.Messages, r.Consumers)
+	}
+	if r.Err != nil {
+		description += " (" + r.Err.Error() + ")"
+	}
+	return description
+}
+
+// Topology provisions, inspects and tears down topologies on a managed [Connection],
+// without declaring them on channels. Unlike the channel topology, each call
+// returns the result for every entity of the topology and waits for the
+// connection being recovered, till the context is done.
+//
+// Server-named queues get their name from [Topology.Apply] and are known by
+// their entry for the subsequent calls.
+type Topology struct {
+	conn     *Connection
+	opt      ReconcilerOptions
+	mu       sync.Mutex
+	declared map[*TopologyOptions]string // server-named queues
+}
+
+// NewTopology creates a topology manager running on the connection, applying
+// the topologies as a [Reconciler] set by the optionFuncs does.
+//
+// Example:
+//
+//	manager := NewTopology(conn, WithReconcilePolicy(ConflictRecreate))
+//	results, err := manager.Apply(ctx, topology)
+//	...
+//	results, err = manager.Teardown(ctx, topology)
+func NewTopology(conn *Connection, optionFuncs ...func(*ReconcilerOptions)) *Topology {
+	opt := DefaultReconcilerOptions()
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&opt)
+	}
+
+	return &Topology{
+		conn:     conn,
+		opt:      opt,
+		declared: make(map[*TopologyOptions]string),
+	}
+}
+
+// Apply declares the entities of the topology and their bindings (see [Reconciler.Apply]).
+// The result of an entity holds the failures of its bindings as well.
+func (m *Topology) Apply(ctx context.Context, topology []*TopologyOptions) ([]TopologyResult, error) {
+	reconciler := &Reconciler{conn: m.conn, opt: m.opt}
+	plan, err := reconciler.Apply(ctx, topology)
+
+	var results []TopologyResult
+	index := make(map[entityKey]int)
+	for _, step := range plan.Steps {
+		key := keyOf(step.Topology)
+		if step.Binding == nil {
+			index[key] = len(results)
+			results = append(results, TopologyResult{
+				Topology: step.Topology,
+				Name:     step.Name,
+				Action:   step.Action,
+				Err:      step.Err,
+			})
+			if step.Applied && key.entry != nil {
+				m.mu.Lock()
+				m.declared[step.Topology] = step.Name
+				m.mu.Unlock()
+			}
+			continue
+		}
+		if i, found := index[key]; found && step.Err != nil {
+			results[i].Err = errors.Join(results[i].Err, fmt.Errorf("%s: %w", step, step.Err))
+		}
+	}
+
+	return results, err
+}
+
+// Teardown deletes the queues, then the exchanges of the topology, in reverse order.
+// Passive entries and predefined exchanges are left, as are entries not meant to be
+// declared. Entities already gone count as deleted.
+func (m *Topology) Teardown(ctx context.Context, topology []*TopologyOptions) ([]TopologyResult, error) {
+	var queues, exchanges []*TopologyOptions
+	for i := len(topology) - 1; i >= 0; i-- {
+		t := topology[i]
+		if !t.Declare || t.Passive || t.IsExchange && (t.Name == "" || predefinedExchange(t.Name)) {
+			continue
+		}
+		if t.IsExchange {
+			exchanges = append(exchanges, t)
+		} else {
+			queues = append(queues, t)
+		}
+	}
+
+	return m.run(ctx, "deleted", append(queues, exchanges...), func(ch *amqp.Channel, result *TopologyResult) error {
+		var err error
+		if result.Topology.IsExchange {
+			err = ch.ExchangeDelete(result.Name, false, false)
+		} else {
+			result.Messages, err = ch.QueueDelete(result.Name, false, false, false)
+		}
+		if isCode(err, amqp.NotFound) {
+			return nil
+		}
+		if err == nil && result.Topology.Name == "" {
+			m.mu.Lock()
+			delete(m.declared, result.Topology)
+			m.mu.Unlock()
+		}
+		return err
+	})
+}
+
+// Inspect returns the messages and consumers of the queues of the topology,
+// checking that its exchanges exist.
+func (m *Topology) Inspect(ctx context.Context, topology []*TopologyOptions) ([]TopologyResult, error) {
+	return m.run(ctx, "inspected", topology, func(ch *amqp.Channel, result *TopologyResult) error {
+		t := result.Topology
+		if t.IsExchange {
+			return ch.ExchangeDeclarePassive(result.Name, t.Kind, t.Durable, t.AutoDelete, t.Internal, false, t.Args)
+		}
+		queue, err := ch.QueueInspect(result.Name)
+		result.Messages, result.Consumers = queue.Messages, queue.Consumers
+		return err
+	})
+}
+
+// Purge removes the messages of the queues of the topology, waiting for the broker
+// to acknowledge each purge; the results tell the count of purged messages.
+func (m *Topology) Purge(ctx context.Context, topology []*TopologyOptions) ([]TopologyResult, error) {
+	var queues []*TopologyOptions
+	for _, t := range topology {
+		if !t.IsExchange {
+			queues = append(queues, t)
+		}
+	}
+
+	return m.run(ctx, "purged", queues, func(ch *amqp.Channel, result *TopologyResult) error {
+		var err error
+		result.Messages, err = ch.QueuePurge(result.Name, false)
+		return err
+	})
+}
+
+// run applies the command to each entity of the topology once, on a session of its own.
+func (m *Topology) run(ctx context.Context, verb string, topology []*TopologyOptions,
+	command func(ch *amqp.Channel, result *TopologyResult) error,
+) ([]TopologyResult, error) {
+	session := &topologySession{ctx: ctx, conn: m.conn}
+	defer session.close()
+
+	var results []TopologyResult
+	var errs []error
+	seen := make(map[entityKey]bool)
+	for _, t := range topology {
+		key := keyOf(t)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err := ctx.Err(); err != nil {
+			return results, errors.Join(append(errs, err)...)
+		}
+
+		result := TopologyResult{Topology: t, Name: m.nameOf(t)}
+		if result.Name == "" && !t.IsExchange {
+			result.Err = errUnnamedQueue
+		} else {
+			result.Err = session.do(func(ch *amqp.Channel) error {
+				return command(ch, &result)
+			})
+		}
+		results = append(results, result)
+
+		kind := "queue"
+		if t.IsExchange {
+			kind = "exchange"
+		}
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s %q: %w", kind, result.Name, result.Err))
+		}
+		m.conn.log(m.conn.opt.logLevels.Topology, fmt.Sprintf("topology %s %s %q", kind, verb, result.Name), result.Err)
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// nameOf returns the broker name of the entity.
+func (m *Topology) nameOf(t *TopologyOptions) string {
+	if t.IsExchange || t.Name != "" {
+		return t.Name
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.declared[t]
+}
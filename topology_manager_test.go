@@ -0,0 +1,135 @@
+package grabbit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// resultNames lists the broker names of the results.
+func resultNames(results []TopologyResult) []string {
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	return names
+}
+
+func TestTopologyTeardown(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	manager := NewTopology(conn)
+	ctx := context.Background()
+
+	unnamed := &TopologyOptions{Declare: true}
+	topology := append(ordersTopology(true),
+		&TopologyOptions{Name: "events.x", IsExchange: true, Kind: "fanout", Declare: true},
+		unnamed,
+		&TopologyOptions{Name: "audit", Declare: true},
+		&TopologyOptions{Name: "kept", Declare: true},
+		&TopologyOptions{Name: "manual", Declare: true},
+	)
+	results, err := manager.Apply(ctx, topology)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	serverNamed := results[3].Name
+	for i := 0; i < 3; i++ {
+		srv.Publish("orders.x", "order", amqp.Publishing{})
+	}
+	srv.DeleteQueue("audit")
+
+	// the entries left: passive, not declared and predefined
+	teardown := append(topology[:len(topology)-2:len(topology)-2],
+		&TopologyOptions{Name: "kept", Declare: true, Passive: true},
+		&TopologyOptions{Name: "manual"},
+		&TopologyOptions{Name: "amq.direct", IsExchange: true, Kind: "direct", Durable: true, Declare: true},
+	)
+	results, err = manager.Teardown(ctx, teardown)
+	if err != nil {
+		t.Fatalf("teardown: %v", err)
+	}
+
+	// queues first, in reverse order, then the exchanges
+	expected := fmt.Sprint([]string{"audit", serverNamed, "orders", "events.x", "orders.x"})
+	if names := fmt.Sprint(resultNames(results)); names != expected {
+		t.Fatalf("deleted %s, expected %s", names, expected)
+	}
+	for _, result := range results {
+		messages := 0
+		if result.Name == "orders" {
+			messages = 3
+		}
+		if result.Err != nil || result.Messages != messages {
+			t.Errorf("%s", result)
+		}
+	}
+
+	for _, queue := range []string{"orders", "audit", serverNamed} {
+		if _, ok := srv.Queue(queue); ok {
+			t.Errorf("queue %s left", queue)
+		}
+	}
+	for _, queue := range []string{"kept", "manual"} {
+		if _, ok := srv.Queue(queue); !ok {
+			t.Errorf("queue %s deleted", queue)
+		}
+	}
+	if srv.HasExchange("orders.x") || srv.HasExchange("events.x") || !srv.HasExchange("amq.direct") {
+		t.Fatal("exchanges not deleted as expected")
+	}
+
+	// the deleted server-named queue is forgotten
+	if results, err := manager.Inspect(ctx, []*TopologyOptions{unnamed}); !errors.Is(err, errUnnamedQueue) || results[0].Name != "" {
+		t.Fatalf("inspected %v, %v", results, err)
+	}
+}
+
+func TestTopologyPurge(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+	manager := NewTopology(conn)
+	ctx := context.Background()
+
+	unnamed := &TopologyOptions{Declare: true}
+	topology := append(ordersTopology(true), unnamed)
+	results, err := manager.Apply(ctx, topology)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	serverNamed := results[2].Name
+	for i := 0; i < 2; i++ {
+		srv.Publish("orders.x", "order", amqp.Publishing{})
+	}
+	srv.Publish("", serverNamed, amqp.Publishing{})
+
+	// the failures do not stop the purge of the following queues
+	purged := append(topology[:2:2],
+		&TopologyOptions{Name: "missing"},
+		&TopologyOptions{Declare: true},
+		unnamed,
+	)
+	results, err = manager.Purge(ctx, purged)
+	if !errors.Is(err, errUnnamedQueue) || !isCode(err, amqp.NotFound) {
+		t.Fatalf("purge: %v", err)
+	}
+
+	// the exchange skipped
+	expected := fmt.Sprint([]string{"orders", "missing", "", serverNamed})
+	if names := fmt.Sprint(resultNames(results)); names != expected {
+		t.Fatalf("purged %s, expected %s", names, expected)
+	}
+	for i, messages := range []int{2, 0, 0, 1} {
+		if failed := i == 1 || i == 2; results[i].Messages != messages || (results[i].Err != nil) != failed {
+			t.Errorf("%s", results[i])
+		}
+	}
+	for _, queue := range []string{"orders", serverNamed} {
+		if info, ok := srv.Queue(queue); !ok || info.Ready != 0 {
+			t.Errorf("queue %s: %+v", queue, info)
+		}
+	}
+}
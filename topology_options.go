@@ -1,6 +1,8 @@
 package grabbit
 
 import (
+	"reflect"
+
 	amqp "github.com/oarkflow/amqp/amqp091"
 )
 
@@ -16,19 +18,21 @@ type TopologyBind struct {
 // TopologyOptions defines the infrastructure topology, i.e. exchange and queues definition
 // when wanting handling automatically on recovery or one time creation
 type TopologyOptions struct {
-	Name          string       // tag of exchange or queue
-	IsDestination bool         // end target, i.e. if messages should be routed to it
-	IsExchange    bool         // indicates if this an exchange or queue
-	Bind          TopologyBind // complex routing
-	Kind          string       // empty string for default exchange or: direct, topic, fanout, headers.
-	Durable       bool         // maps the durable amqp attribute
-	AutoDelete    bool         // maps the auto-delete amqp attribute
-	Exclusive     bool         // if queue is exclusive
-	Internal      bool         //
-	NoWait        bool         // // maps the noWait amqp attribute
-	Passive       bool         // if false, it will be created on the server when missing
-	Args          amqp.Table   // wraps the amqp Table parameters
-	Declare       bool         // gets created on start and also during recovery if Durable is false
+	Name          string         // tag of exchange or queue
+	IsDestination bool           // end target, i.e. if messages should be routed to it; a queue becomes [Channel.Queue]
+	IsExchange    bool           // indicates if this an exchange or queue
+	Bind          TopologyBind   // complex routing
+	Bindings      []TopologyBind // further routings, all wanted (Enabled matters to Bind only)
+	StaleBindings []TopologyBind // routings removed when declaring, ex: a former routing key
+	Kind          string         // empty string for default exchange or: direct, topic, fanout, headers.
+	Durable       bool           // maps the durable amqp attribute
+	AutoDelete    bool           // maps the auto-delete amqp attribute
+	Exclusive     bool           // if queue is exclusive
+	Internal      bool           //
+	NoWait        bool           // // maps the noWait amqp attribute
+	Passive       bool           // if false, it will be created on the server when missing; if true, only checked for existence
+	Args          amqp.Table     // wraps the amqp Table parameters
+	Declare       bool           // gets created on start and also during recovery if Durable is false
 }
 
 // GetRouting returns the source and destination strings for the TopologyOptions struct.
@@ -45,3 +49,32 @@ func (t *TopologyOptions) GetRouting() (source, destination string) {
 		return t.Name, t.Bind.Peer
 	}
 }
+
+// Binds returns the wanted bindings: Bind when enabled, then Bindings.
+func (t *TopologyOptions) Binds() []TopologyBind {
+	binds := make([]TopologyBind, 0, len(t.Bindings)+1)
+	if t.Bind.Enabled {
+		binds = append(binds, t.Bind)
+	}
+	return append(binds, t.Bindings...)
+}
+
+// RoutingOf returns the source and destination of one of the bindings, as [GetRouting]
+// does for Bind, except that queues are always the destination.
+func (t *TopologyOptions) RoutingOf(bind TopologyBind) (source, destination string) {
+	if t.IsDestination || !t.IsExchange {
+		return bind.Peer, t.Name
+	}
+	return t.Name, bind.Peer
+}
+
+// survives tells whether the broker keeps the entity, and its bindings, when the connection is lost.
+func (t *TopologyOptions) survives() bool {
+	return t.Durable && !t.AutoDelete && !t.Exclusive && (t.IsExchange || t.Name != "")
+}
+
+// sameBind tells whether two bindings route the same peer, key and arguments.
+func sameBind(a, b TopologyBind) bool {
+	return a.Peer == b.Peer && a.Key == b.Key &&
+		(len(a.Args) == 0 && len(b.Args) == 0 || reflect.DeepEqual(a.Args, b.Args))
+}
@@ -0,0 +1,422 @@
+package grabbit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a significant line of a YAML document.
+type yamlLine struct {
+	num     int    // line number, for the error messages
+	indent  int    // leading spaces
+	content string // without indentation and comment
+}
+
+// yamlParser decodes the YAML subset used by configuration files into the values
+// produced by encoding/json: block and flow mappings and sequences (nested ones
+// included, ex: "- - a"), plain and quoted scalars, comments and a single document
+// optionally marked by "---" and "...". Anchors, tags, multi-line scalars and
+// multiple documents are not supported and reported as errors.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML decodes a YAML document into map[string]any, []any, string, bool,
+// int64, float64 or nil values.
+func parseYAML(data []byte) (any, error) {
+	p := &yamlParser{}
+	ended := false // past the document end marker
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		text := yamlStripComment(raw)
+		content := strings.TrimLeft(text, " ")
+		if content == "" || len(p.lines) == 0 && !ended && content == "---" {
+			continue
+		}
+		if content == "..." && !ended {
+			ended = true
+			continue
+		}
+		if content == "---" || ended {
+			return nil, fmt.Errorf("yaml: line %d: multiple documents not supported", i+1)
+		}
+		if strings.HasPrefix(content, "\t") {
+			return nil, fmt.Errorf("yaml: line %d: tabs cannot indent", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(text) - len(content), content: content})
+	}
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+
+	value, err := p.block(p.lines[0].indent)
+	if err == nil && p.pos < len(p.lines) {
+		err = p.errorf("unexpected indentation")
+	}
+	return value, err
+}
+
+// errorf reports an error at the current line.
+func (p *yamlParser) errorf(format string, args ...any) error {
+	line := p.lines[min(p.pos, len(p.lines)-1)]
+	return fmt.Errorf("yaml: line %d: %s", line.num, fmt.Sprintf(format, args...))
+}
+
+// block decodes the sequence or mapping starting at the current line.
+func (p *yamlParser) block(indent int) (any, error) {
+	if yamlIsItem(p.lines[p.pos].content) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+// sequence decodes the "- item" lines at the indentation.
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+	items := []any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent || !yamlIsItem(line.content) {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		rest := strings.TrimLeft(line.content[1:], " ")
+		var item any
+		var err error
+		switch {
+		case rest == "":
+			item, err = p.nested(indent)
+		case yamlIsItem(rest):
+			// sequence opened on the item line, continued below at the same column
+			p.lines[p.pos] = yamlLine{num: line.num, indent: indent + len(line.content) - len(rest), content: rest}
+			item, err = p.sequence(p.lines[p.pos].indent)
+		case yamlKeyEnd(rest) >= 0:
+			// mapping opened on the item line, continued below at the same column
+			p.lines[p.pos] = yamlLine{num: line.num, indent: indent + len(line.content) - len(rest), content: rest}
+			item, err = p.mapping(p.lines[p.pos].indent)
+		default:
+			item, err = yamlInline(rest)
+			if err != nil {
+				err = p.errorf("%v", err)
+			}
+			p.pos++
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// mapping decodes the "key: value" lines at the indentation.
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	values := map[string]any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent || yamlIsItem(line.content) {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		end := yamlKeyEnd(line.content)
+		if end < 0 {
+			return nil, p.errorf("expected a key: value pair")
+		}
+		key, err := yamlKey(line.content[:end])
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if _, found := values[key]; found {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+
+		var value any
+		if rest := strings.TrimSpace(line.content[end+1:]); rest != "" {
+			if value, err = yamlInline(rest); err != nil {
+				return nil, p.errorf("%v", err)
+			}
+			p.pos++
+		} else if value, err = p.nested(indent); err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// nested decodes the block below the current line, nil when there is none.
+// A sequence may stay at the indentation of its parent key.
+func (p *yamlParser) nested(indent int) (any, error) {
+	p.pos++
+	if p.pos == len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent || next.indent == indent && yamlIsItem(next.content) && !yamlIsItem(p.lines[p.pos-1].content) {
+		return p.block(next.indent)
+	}
+	return nil, nil
+}
+
+// yamlIsItem tells whether the content is a sequence item.
+func yamlIsItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// yamlStripComment removes the comment ending the line and the trailing spaces.
+func yamlStripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote == '\'' && c == '\'' && i+1 < len(line) && line[i+1] == '\'':
+			i++ // escaped single quote
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.ContainsRune(" \t-:[{,", rune(line[i-1])) {
+				quote = c
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return strings.TrimRight(line, " \t")
+}
+
+// yamlKeyEnd returns the position of the colon ending the key of a mapping line, -1 if none.
+func yamlKeyEnd(content string) int {
+	if content == "" || strings.ContainsRune("[{", rune(content[0])) {
+		return -1
+	}
+	var quote byte
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case i == 0 && (c == '"' || c == '\''):
+			quote = c
+		case c == ':' && (i+1 == len(content) || content[i+1] == ' '):
+			return i
+		}
+	}
+	return -1
+}
+
+// yamlKey decodes a mapping key.
+func yamlKey(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text != "" && (text[0] == '"' || text[0] == '\'') {
+		return yamlQuoted(text)
+	}
+	return text, nil
+}
+
+// yamlInline decodes the value following a key or an item marker.
+func yamlInline(text string) (any, error) {
+	switch text[0] {
+	case '[', '{':
+		flow := &yamlFlow{text: text}
+		value, err := flow.value()
+		if err == nil {
+			if flow.skipSpaces(); flow.pos < len(flow.text) {
+				err = fmt.Errorf("unexpected %q after flow collection", flow.text[flow.pos:])
+			}
+		}
+		return value, err
+	case '"', '\'':
+		return yamlQuoted(text)
+	case '|', '>':
+		return nil, fmt.Errorf("multi-line scalars not supported")
+	case '&', '*', '!':
+		return nil, fmt.Errorf("anchors, aliases and tags not supported")
+	}
+	return yamlPlain(text), nil
+}
+
+// yamlQuoted decodes a single or double quoted scalar.
+func yamlQuoted(text string) (string, error) {
+	if len(text) < 2 || text[len(text)-1] != text[0] {
+		return "", fmt.Errorf("unterminated string %s", text)
+	}
+	if text[0] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	value, err := strconv.Unquote(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", text)
+	}
+	return value, nil
+}
+
+// yamlPlain decodes an unquoted scalar, following the YAML 1.2 core schema.
+func yamlPlain(text string) any {
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i
+	}
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0o") {
+		if i, err := strconv.ParseInt(text, 0, 64); err == nil {
+			return i
+		}
+	}
+	if strings.ContainsAny(text, "0123456789") && !strings.ContainsAny(text, "_xXpP") {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+	return text
+}
+
+// yamlFlow decodes the flow collections, ex: [a, b] or {x-max-length: 10}.
+type yamlFlow struct {
+	text string
+	pos  int
+}
+
+// skipSpaces moves past the blanks.
+func (f *yamlFlow) skipSpaces() {
+	for f.pos < len(f.text) && f.text[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+// value decodes the collection or scalar at the position.
+func (f *yamlFlow) value() (any, error) {
+	f.skipSpaces()
+	if f.pos == len(f.text) {
+		return nil, fmt.Errorf("unterminated flow collection")
+	}
+
+	switch f.text[f.pos] {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	}
+	text, quoted, err := f.scalar(",]}")
+	if err != nil || quoted {
+		return text, err
+	}
+	return yamlPlain(text), nil
+}
+
+// scalar returns the quoted or plain scalar at the position, the latter ended by the stops.
+func (f *yamlFlow) scalar(stops string) (string, bool, error) {
+	start := f.pos
+	if c := f.text[start]; c == '"' || c == '\'' {
+		for f.pos++; f.pos < len(f.text); f.pos++ {
+			switch {
+			case c == '"' && f.text[f.pos] == '\\':
+				f.pos++
+			case f.text[f.pos] == c && c == '\'' && f.pos+1 < len(f.text) && f.text[f.pos+1] == '\'':
+				f.pos++
+			case f.text[f.pos] == c:
+				f.pos++
+				text, err := yamlQuoted(f.text[start:f.pos])
+				return text, true, err
+			}
+		}
+		return "", true, fmt.Errorf("unterminated string %s", f.text[start:])
+	}
+
+	for f.pos < len(f.text) && !strings.ContainsRune(stops, rune(f.text[f.pos])) {
+		f.pos++
+	}
+	return strings.TrimSpace(f.text[start:f.pos]), false, nil
+}
+
+// sequence decodes [item, ...].
+func (f *yamlFlow) sequence() ([]any, error) {
+	items := []any{}
+	f.pos++ // [
+	for {
+		f.skipSpaces()
+		if f.pos < len(f.text) && f.text[f.pos] == ']' {
+			f.pos++
+			return items, nil
+		}
+		item, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if err := f.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// mapping decodes {key: value, ...}.
+func (f *yamlFlow) mapping() (map[string]any, error) {
+	values := map[string]any{}
+	f.pos++ // {
+	for {
+		f.skipSpaces()
+		if f.pos < len(f.text) && f.text[f.pos] == '}' {
+			f.pos++
+			return values, nil
+		}
+		if f.pos == len(f.text) {
+			return nil, fmt.Errorf("unterminated flow collection")
+		}
+		key, _, err := f.scalar(":,}")
+		if err != nil {
+			return nil, err
+		}
+		if f.pos == len(f.text) || f.text[f.pos] != ':' {
+			return nil, fmt.Errorf("expected ':' after key %q", key)
+		}
+		f.pos++
+		value, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		if _, found := values[key]; found {
+			return nil, fmt.Errorf("duplicate key %q", key)
+		}
+		values[key] = value
+		if err := f.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// separator consumes the comma between entries, leaving the closing bracket.
+func (f *yamlFlow) separator(closing byte) error {
+	f.skipSpaces()
+	switch {
+	case f.pos == len(f.text):
+		return fmt.Errorf("unterminated flow collection")
+	case f.text[f.pos] == ',':
+		f.pos++
+		return nil
+	case f.text[f.pos] == closing:
+		return nil
+	}
+	return fmt.Errorf("unexpected %q in flow collection", f.text[f.pos])
+}
@@ -0,0 +1,72 @@
+package grabbit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want any
+	}{
+		{"empty", "# nothing\n", nil},
+		{"mapping", "name: orders\ndurable: true\nlimit: 10\nratio: 0.5\nnone: ~",
+			map[string]any{"name": "orders", "durable": true, "limit": int64(10), "ratio": 0.5, "none": nil}},
+		{"markers", "---\nname: orders\n...\n", map[string]any{"name": "orders"}},
+		{"end marker then comments", "name: orders\n...\n# trailing\n\n", map[string]any{"name": "orders"}},
+		{"sequence of mappings", "queues:\n  - name: a\n    durable: true\n  - name: b\n",
+			map[string]any{"queues": []any{
+				map[string]any{"name": "a", "durable": true},
+				map[string]any{"name": "b"},
+			}}},
+		{"sequence at the key indentation", "queues:\n- a\n- b\n", map[string]any{"queues": []any{"a", "b"}}},
+		{"nested sequences", "- - 1\n  - 2\n- - 3\n", []any{[]any{int64(1), int64(2)}, []any{int64(3)}}},
+		{"deeply nested sequences", "- - - a\n", []any{[]any{[]any{"a"}}}},
+		{"mapping in nested sequence", "- - a: 1\n    b: 2\n", []any{[]any{map[string]any{"a": int64(1), "b": int64(2)}}}},
+		{"flow collections", "args: {x-max-length: 10, x-queue-type: 'quorum'}\nkeys: [a, \"b c\", []]",
+			map[string]any{
+				"args": map[string]any{"x-max-length": int64(10), "x-queue-type": "quorum"},
+				"keys": []any{"a", "b c", []any{}},
+			}},
+		{"comments and quotes", "key: 'it''s # not a comment' # comment\nother: \"a\\tb\"",
+			map[string]any{"key": "it's # not a comment", "other": "a\tb"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(test.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLRejectsUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		err  string
+	}{
+		{"multiple documents", "a: 1\n---\nb: 2", "multiple documents"},
+		{"content after the end", "a: 1\n...\nb: 2", "multiple documents"},
+		{"anchor", "a: &x 1", "anchors"},
+		{"multi-line scalar", "a: |\n  text", "multi-line"},
+		{"tab indentation", "a:\n\t- 1", "tabs"},
+		{"duplicate key", "a: 1\na: 2", "duplicate key"},
+		{"bad indentation", "a: 1\n  b: 2", "indentation"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseYAML([]byte(test.doc))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
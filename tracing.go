@@ -0,0 +1,309 @@
+package grabbit
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// W3C trace context headers carried by the messages.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// Span attributes set by the library, following the OpenTelemetry messaging conventions.
+const (
+	AttrMessagingSystem = "messaging.system"                           // always "rabbitmq"
+	AttrOperation       = "messaging.operation"                        // "publish" or "process"
+	AttrDestination     = "messaging.destination.name"                 // exchange or queue
+	AttrRoutingKey      = "messaging.rabbitmq.destination.routing_key" // publishing or delivery routing key
+	AttrMessageID       = "messaging.message.id"                       // MessageId property, when set
+	AttrChannel         = "messaging.rabbitmq.channel"                 // name of the library channel
+	AttrBatchSize       = "messaging.batch.message_count"              // deliveries of a processed batch
+	AttrOutcome         = "messaging.rabbitmq.outcome"                 // confirmation (ack, nack, spooled) or settlement ([Result])
+)
+
+// errNotAcknowledged ends the spans of the publishings negatively confirmed.
+var errNotAcknowledged = errors.New("publishing not acknowledged by the broker")
+
+// SpanKind tells the role of a span, as needed by most tracing backends.
+type SpanKind int
+
+const (
+	SpanKindProducer SpanKind = iota // publishing of a message
+	SpanKindConsumer                 // processing of deliveries
+)
+
+// SpanContext holds the W3C trace context propagated along the messages.
+type SpanContext struct {
+	TraceID    [16]byte // trace identifier
+	SpanID     [8]byte  // parent span identifier
+	TraceFlags byte     // sampling flags
+	TraceState string   // vendor specific data (tracestate header), passed as is
+}
+
+// IsValid reports whether both identifiers are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Traceparent formats the context as a version 00 traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x",
+		hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), sc.TraceFlags)
+}
+
+// ParseTraceparent parses a traceparent header value along its (optional) tracestate.
+// Future versions are accepted as long as they start with the version 00 fields.
+// As required by the W3C, the hexadecimal fields are lowercase.
+func ParseTraceparent(traceparent, tracestate string) (SpanContext, error) {
+	var sc SpanContext
+
+	value := strings.TrimSpace(traceparent)
+	parts := strings.Split(value, "-")
+	if strings.ToLower(value) != value || len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		(parts[0] == "00" && len(parts) != 4) {
+		return sc, fmt.Errorf("malformed traceparent %q", traceparent)
+	}
+
+	var version, flags [1]byte
+	if _, err := hex.Decode(version[:], []byte(parts[0])); err != nil {
+		return sc, fmt.Errorf("malformed traceparent %q", traceparent)
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, fmt.Errorf("malformed traceparent %q", traceparent)
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, fmt.Errorf("malformed traceparent %q", traceparent)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, fmt.Errorf("malformed traceparent %q", traceparent)
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return sc, fmt.Errorf("malformed traceparent %q", traceparent)
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", traceparent)
+	}
+
+	sc.TraceFlags = flags[0]
+	sc.TraceState = strings.TrimSpace(tracestate)
+
+	return sc, nil
+}
+
+// Span is a unit of traced work started by a [Tracer].
+type Span interface {
+	SpanContext() SpanContext       // identifiers propagated to the published messages
+	SetAttribute(key, value string) // annotates the span
+	End(err error)                  // finishes the span, recording the failure if any
+}
+
+// Tracer starts the spans of the publishing and consuming operations. It is the
+// adaptation point for tracing backends such as OpenTelemetry, which the library
+// does not depend on. Pass it via [PublisherOptions.WithTracer] or [ConsumerOptions.WithTracer].
+type Tracer interface {
+	// Start begins a span. The parent is the remote context extracted from the
+	// received messages (zero when absent and when publishing, where the parent, if any,
+	// is carried by ctx). The returned context carries the new span.
+	Start(ctx context.Context, name string, kind SpanKind, parent SpanContext, attributes map[string]string) (context.Context, Span)
+}
+
+// spanKey is the context key of the current span.
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying the span. Tracers not relying on
+// their own context propagation can use it along [SpanFromContext].
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx, if any.
+func SpanFromContext(ctx context.Context) (Span, bool) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	return span, ok
+}
+
+// InjectTraceContext returns a copy of the headers carrying the span context.
+func InjectTraceContext(headers amqp.Table, sc SpanContext) amqp.Table {
+	injected := make(amqp.Table, len(headers)+2)
+	for k, v := range headers {
+		injected[k] = v
+	}
+
+	injected[TraceparentHeader] = sc.Traceparent()
+	if sc.TraceState != "" {
+		injected[TracestateHeader] = sc.TraceState
+	} else {
+		delete(injected, TracestateHeader)
+	}
+
+	return injected
+}
+
+// ExtractTraceContext returns the span context carried by the headers, if any and valid.
+func ExtractTraceContext(headers amqp.Table) (SpanContext, bool) {
+	sc, err := ParseTraceparent(headerString(headers, TraceparentHeader), headerString(headers, TracestateHeader))
+	return sc, err == nil
+}
+
+// headerString returns a textual header value (long string or byte array).
+func headerString(headers amqp.Table, name string) string {
+	switch value := headers[name].(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	}
+	return ""
+}
+
+// WithChannelTracer traces the message processing of a consumer channel.
+func WithChannelTracer(tracer Tracer) func(options *ChannelOptions) {
+	return func(options *ChannelOptions) {
+		options.tracer = tracer
+	}
+}
+
+// tracing returns the publisher interceptor starting a span per published message and
+// injecting its context into the headers. The spans of the deferred publishing methods
+// end with the broker confirmation; the others once the message is sent.
+func (p *Publisher) tracing(deferred bool) PublishInterceptor {
+	tracer := p.opt.Tracer
+
+	return func(next PublishFunc) PublishFunc {
+		return func(opt PublisherOptions, msg amqp.Publishing) (*DeferredConfirmation, error) {
+			attributes := map[string]string{
+				AttrMessagingSystem: "rabbitmq",
+				AttrOperation:       "publish",
+				AttrDestination:     opt.Exchange,
+				AttrRoutingKey:      opt.Key,
+				AttrChannel:         p.channel.Name(),
+			}
+			if msg.MessageId != "" {
+				attributes[AttrMessageID] = msg.MessageId
+			}
+
+			ctx := opt.Context
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			_, span := tracer.Start(ctx, publishSpanName(opt.Exchange), SpanKindProducer, SpanContext{}, attributes)
+			msg.Headers = InjectTraceContext(msg.Headers, span.SpanContext())
+
+			confirmation, err := next(opt, msg)
+			if !deferred || err != nil || confirmation == nil {
+				span.End(err)
+				return confirmation, err
+			}
+
+			go p.endOnConfirmation(span, confirmation)
+			return confirmation, err
+		}
+	}
+}
+
+// publishSpanName names the publishing spans after their exchange.
+func publishSpanName(exchange string) string {
+	if exchange == "" {
+		exchange = "(default)"
+	}
+	return exchange + " publish"
+}
+
+// endOnConfirmation ends the publishing span once its broker confirmation arrives.
+func (p *Publisher) endOnConfirmation(span Span, d *DeferredConfirmation) {
+	var done <-chan struct{}
+	var acked func() bool
+
+	switch {
+	case d.entry != nil:
+		done, acked = d.entry.done, func() bool { return d.entry.ack }
+	case d.DeferredConfirmation != nil:
+		done, acked = d.Done(), d.Acked
+	case d.Outcome == ConfirmationSpooled:
+		span.SetAttribute(AttrOutcome, "spooled")
+		span.End(nil)
+		return
+	default: // confirmations disabled
+		span.End(nil)
+		return
+	}
+
+	select {
+	case <-done:
+		if acked() {
+			span.SetAttribute(AttrOutcome, "ack")
+			span.End(nil)
+		} else {
+			span.SetAttribute(AttrOutcome, "nack")
+			span.End(errNotAcknowledged)
+		}
+	case <-p.channel.opt.ctx.Done():
+		span.End(amqp.ErrClosed)
+	}
+}
+
+// traceDelivery starts the processing span of a delivery, child of the
+// trace context it carries. Returns a nil span when tracing is disabled.
+func (ch *Channel) traceDelivery(ctx context.Context, msg *amqp.Delivery) (context.Context, Span) {
+	if ch.opt.tracer == nil {
+		return ctx, nil
+	}
+
+	parent, _ := ExtractTraceContext(msg.Headers)
+	attributes := map[string]string{
+		AttrMessagingSystem: "rabbitmq",
+		AttrOperation:       "process",
+		AttrDestination:     ch.consumedQueue(),
+		AttrRoutingKey:      msg.RoutingKey,
+		AttrChannel:         ch.opt.name,
+	}
+	if msg.MessageId != "" {
+		attributes[AttrMessageID] = msg.MessageId
+	}
+
+	return ch.opt.tracer.Start(ctx, ch.consumedQueue()+" process", SpanKindConsumer, parent, attributes)
+}
+
+// consumedQueue returns the name of the queue the channel consumes from.
+func (ch *Channel) consumedQueue() string {
+	if queue := ch.Queue(); queue != "" {
+		return queue
+	}
+	return ch.opt.implParams.ConsumerQueue
+}
+
+// endDelivery ends the processing span of a delivery with its settlement.
+func endDelivery(span Span, result Result) {
+	if span == nil {
+		return
+	}
+	span.SetAttribute(AttrOutcome, result.String())
+	span.End(nil)
+}
+
+// traceBatches wraps the batch processor with a span per batch, child of
+// the trace context of the first delivery.
+func traceBatches(next CallbackProcessMessages) CallbackProcessMessages {
+	return func(props *DeliveriesProperties, messages []DeliveryData, mustAck bool, ch *Channel) {
+		parent, _ := ExtractTraceContext(props.Headers)
+		_, span := ch.opt.tracer.Start(ch.opt.ctx, ch.consumedQueue()+" process", SpanKindConsumer, parent,
+			map[string]string{
+				AttrMessagingSystem: "rabbitmq",
+				AttrOperation:       "process",
+				AttrDestination:     ch.consumedQueue(),
+				AttrRoutingKey:      props.RoutingKey,
+				AttrChannel:         ch.opt.name,
+				AttrBatchSize:       fmt.Sprint(len(messages)),
+			})
+		defer span.End(nil)
+
+		next(props, messages, mustAck, ch)
+	}
+}
@@ -0,0 +1,254 @@
+package grabbit
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// recordedSpan is a span of the recordingTracer.
+type recordedSpan struct {
+	name       string
+	kind       SpanKind
+	parent     SpanContext
+	attributes map[string]string
+	sc         SpanContext
+
+	mu    sync.Mutex
+	ended bool
+}
+
+func (s *recordedSpan) SpanContext() SpanContext { return s.sc }
+
+func (s *recordedSpan) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attributes[key] = value
+}
+
+func (s *recordedSpan) End(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ended = true
+}
+
+// recordingTracer keeps the started spans, each in a trace of its own unless it has a parent.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (tr *recordingTracer) Start(ctx context.Context, name string, kind SpanKind, parent SpanContext, attributes map[string]string) (context.Context, Span) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	span := &recordedSpan{name: name, kind: kind, parent: parent, attributes: attributes}
+	span.sc.TraceID[0] = byte(len(tr.spans) + 1)
+	if parent.IsValid() {
+		span.sc.TraceID = parent.TraceID
+	}
+	span.sc.SpanID[7] = byte(len(tr.spans) + 1)
+	span.sc.TraceFlags = 1
+	span.sc.TraceState = "vendor=grabbit"
+	tr.spans = append(tr.spans, span)
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// started returns the spans started so far.
+func (tr *recordingTracer) started() []*recordedSpan {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	return append([]*recordedSpan(nil), tr.spans...)
+}
+
+// specTraceparent is the example of the W3C trace context specification.
+const specTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	valid := []struct {
+		name  string
+		value string
+		flags byte
+	}{
+		{"version 00", specTraceparent, 1},
+		{"surrounding spaces", " " + specTraceparent + "\t", 1},
+		{"future version", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", 1},
+		{"future version fields", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what-the-future-holds", 1},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", 0},
+	}
+	for _, test := range valid {
+		sc, err := ParseTraceparent(test.value, " congo=t61rcWkgMzE ")
+		if err != nil || hex.EncodeToString(sc.TraceID[:]) != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+			hex.EncodeToString(sc.SpanID[:]) != "00f067aa0ba902b7" || sc.TraceFlags != test.flags {
+			t.Errorf("%s: parsed %+v, %v", test.name, sc, err)
+		}
+		if sc.TraceState != "congo=t61rcWkgMzE" {
+			t.Errorf("%s: tracestate %q", test.name, sc.TraceState)
+		}
+	}
+
+	malformed := []struct {
+		name  string
+		value string
+	}{
+		{"empty", ""},
+		{"missing field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7"},
+		{"version 00 extra field", specTraceparent + "-00"},
+		{"version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{"short version", "0-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{"version not hex", "0x-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{"short trace id", "00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01"},
+		{"long span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b70-01"},
+		{"trace id not hex", "00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01"},
+		{"span id not hex", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bg-01"},
+		{"flags not hex", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0g"},
+		{"short flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1"},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01"},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{"zero span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+	}
+	for _, test := range malformed {
+		if sc, err := ParseTraceparent(test.value, "congo=t61rcWkgMzE"); err == nil {
+			t.Errorf("%s: parsed %+v, %v", test.name, sc, err)
+		}
+	}
+}
+
+func TestTraceContextRoundTrip(t *testing.T) {
+	sc, _ := ParseTraceparent(specTraceparent, "rojo=00f067aa0ba902b7")
+	if value := sc.Traceparent(); value != specTraceparent {
+		t.Fatalf("formatted %s", value)
+	}
+
+	headers := amqp.Table{"app": "orders", TracestateHeader: "stale=1"}
+	injected := InjectTraceContext(headers, sc)
+	if injected[TraceparentHeader] != specTraceparent || injected[TracestateHeader] != "rojo=00f067aa0ba902b7" || injected["app"] != "orders" {
+		t.Fatalf("injected %v", injected)
+	}
+	if len(headers) != 2 || headers[TracestateHeader] != "stale=1" {
+		t.Fatalf("headers modified: %v", headers)
+	}
+	if extracted, ok := ExtractTraceContext(injected); !ok || extracted != sc {
+		t.Fatalf("extracted %+v", extracted)
+	}
+
+	// no tracestate: the former one is not left over
+	sc.TraceState = ""
+	if injected := InjectTraceContext(headers, sc); len(injected) != 2 || injected[TracestateHeader] != nil {
+		t.Fatalf("injected %v", injected)
+	}
+	if injected := InjectTraceContext(nil, sc); len(injected) != 1 {
+		t.Fatalf("injected %v", injected)
+	}
+
+	// byte arrays as set by some clients
+	bytesHeaders := amqp.Table{TraceparentHeader: []byte(specTraceparent), TracestateHeader: []byte("rojo=1")}
+	if extracted, ok := ExtractTraceContext(bytesHeaders); !ok || extracted.Traceparent() != specTraceparent || extracted.TraceState != "rojo=1" {
+		t.Fatalf("extracted %+v", extracted)
+	}
+
+	for _, headers := range []amqp.Table{
+		nil,
+		{TracestateHeader: "rojo=1"},
+		{TraceparentHeader: int32(1)},
+		{TraceparentHeader: "00-zz"},
+	} {
+		if extracted, ok := ExtractTraceContext(headers); ok {
+			t.Errorf("%v: extracted %+v", headers, extracted)
+		}
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	srv, faults := newTestBroker(t)
+	conn := newTestConnection(t, srv, faults)
+
+	consumerTracer := &recordingTracer{}
+	handled := make(chan Span, 2)
+	opt := DefaultConsumerOptions()
+	opt.WithQueue("traced").WithTracer(consumerTracer).WithHandler(func(ctx context.Context, msg Delivery) Result {
+		span, _ := SpanFromContext(ctx)
+		handled <- span
+		return Ack
+	})
+	consumer := NewConsumer(conn, opt, WithChannelDelay(testDelay), WithChannelTopology(testQueue("traced")))
+	defer consumer.Close()
+	eventually(t, 5*time.Second, func() bool {
+		info, _ := srv.Queue("traced")
+		return info.Consumers == 1
+	}, "consumer not started")
+
+	publisherTracer := &recordingTracer{}
+	pubOpt := DefaultPublisherOptions()
+	pubOpt.WithKey("traced").WithTracer(publisherTracer)
+	pub := newTestPublisher(t, conn, pubOpt)
+	if err := pub.Publish(amqp.Publishing{MessageId: "m1"}); err != nil {
+		t.Fatal(err)
+	}
+	// a malformed context starts a trace
+	srv.Publish("", "traced", amqp.Publishing{Headers: amqp.Table{TraceparentHeader: "00-bogus"}})
+
+	var spans []Span
+	for i := 0; i < 2; i++ {
+		select {
+		case span := <-handled:
+			spans = append(spans, span)
+		case <-time.After(5 * time.Second):
+			t.Fatal("not handled")
+		}
+	}
+
+	// the probes of the publisher traced as well
+	published := publisherTracer.started()
+	processed := consumerTracer.started()
+	if len(processed) != 2 {
+		t.Fatalf("%d spans processed", len(processed))
+	}
+	// the attributes are complete once the spans ended
+	eventually(t, 5*time.Second, func() bool {
+		for _, span := range append(published, processed...) {
+			span.mu.Lock()
+			ended := span.ended
+			span.mu.Unlock()
+			if !ended {
+				return false
+			}
+		}
+		return true
+	}, "spans not ended")
+
+	var producers []*recordedSpan
+	for _, span := range published {
+		if span.attributes[AttrRoutingKey] == "traced" {
+			producers = append(producers, span)
+		}
+	}
+	if len(producers) != 1 {
+		t.Fatalf("%d spans published", len(producers))
+	}
+	producer, child, root := producers[0], processed[0], processed[1]
+	if producer.kind != SpanKindProducer || producer.attributes[AttrMessageID] != "m1" || producer.attributes[AttrRoutingKey] != "traced" {
+		t.Fatalf("producer span %+v", producer)
+	}
+	if child.kind != SpanKindConsumer || child.parent != producer.sc || child.sc.TraceID != producer.sc.TraceID {
+		t.Fatalf("consumer span %+v not child of %+v", child, producer.sc)
+	}
+	if child.name != "traced process" || child.attributes[AttrDestination] != "traced" || child.attributes[AttrOutcome] != Ack.String() {
+		t.Fatalf("consumer span %+v", child)
+	}
+	if root.parent.IsValid() {
+		t.Fatalf("malformed context as parent: %+v", root.parent)
+	}
+	// the handlers get the processing spans
+	if spans[0] != Span(child) || spans[1] != Span(root) {
+		t.Fatal("handler not given the processing span")
+	}
+}
@@ -0,0 +1,61 @@
+package grabbit
+
+import (
+	"context"
+	"fmt"
+)
+
+// TypedConsumer receives values of type T, decoded with the codec matching the
+// ContentType of each delivery. Deliveries whose Type property is set and differs from
+// the expected type name, or which cannot be decoded, go to the [PoisonHandler].
+// Create a typed consumer instance by calling [NewTypedConsumer].
+type TypedConsumer[T any] struct {
+	consumer *Consumer       // underlying per message consumer
+	opt      CodecOptions    // decoding codecs and poison handler
+	typeName string          // expected Type property
+	handler  TypedHandler[T] // user defined processing
+}
+
+// NewTypedConsumer creates a consumer of T values with the desired options and then
+// starts consuming. The handler replaces any [ConsumerOptions].Handler; the other
+// per message options (workers, ordering, retry policy) apply as usual.
+// It creates and opens a new dedicated [Channel] using the passed shared connection.
+func NewTypedConsumer[T any](conn *Connection, opt ConsumerOptions, codecOpt CodecOptions, handler TypedHandler[T], optionFuncs ...func(*ChannelOptions)) *TypedConsumer[T] {
+	c := &TypedConsumer[T]{
+		opt:      codecOpt,
+		typeName: codecOpt.TypeName,
+		handler:  handler,
+	}
+	if c.typeName == "" {
+		c.typeName = typeNameOf[T]()
+	}
+
+	opt.Handler = c.handle
+	c.consumer = NewConsumer(conn, opt, optionFuncs...)
+
+	return c
+}
+
+// handle decodes the delivery and passes it to the user handler.
+func (c *TypedConsumer[T]) handle(ctx context.Context, msg Delivery) Result {
+	if msg.Type != "" && msg.Type != c.typeName {
+		return c.opt.poison(ctx, msg, fmt.Errorf("%w: %q", ErrUnknownType, msg.Type))
+	}
+
+	value, err := decodeAs[T](&c.opt, msg)
+	if err != nil {
+		return c.opt.poison(ctx, msg, err)
+	}
+
+	return c.handler(ctx, value, msg)
+}
+
+// Consumer returns the underlying [Consumer].
+func (c *TypedConsumer[T]) Consumer() *Consumer {
+	return c.consumer
+}
+
+// Close shuts down cleanly the consumer channel.
+func (c *TypedConsumer[T]) Close() error {
+	return c.consumer.Close()
+}
@@ -0,0 +1,93 @@
+package grabbit
+
+import (
+	amqp "github.com/oarkflow/amqp/amqp091"
+)
+
+// TypedPublisher publishes values of type T, serialized by the first codec of its
+// [CodecOptions]. The messages get their ContentType set from the codec and their
+// Type set from the type name, so that [TypedConsumer] and [TypeDispatcher] can decode them.
+// Create a typed publisher instance by calling [NewTypedPublisher].
+type TypedPublisher[T any] struct {
+	publisher *Publisher // underlying publisher
+	codec     Codec      // encoding codec
+	typeName  string     // Type property of the messages
+}
+
+// NewTypedPublisher creates a publisher of T values with the desired options.
+// It creates and opens a new dedicated [Channel] using the passed shared connection.
+func NewTypedPublisher[T any](conn *Connection, opt PublisherOptions, codecOpt CodecOptions, optionFuncs ...func(*ChannelOptions)) *TypedPublisher[T] {
+	typeName := codecOpt.TypeName
+	if typeName == "" {
+		typeName = typeNameOf[T]()
+	}
+
+	return &TypedPublisher[T]{
+		publisher: NewPublisher(conn, opt, optionFuncs...),
+		codec:     codecOpt.encoder(),
+		typeName:  typeName,
+	}
+}
+
+// Publisher returns the underlying [Publisher].
+func (p *TypedPublisher[T]) Publisher() *Publisher {
+	return p.publisher
+}
+
+// encode serializes the value into a copy of the template.
+func (p *TypedPublisher[T]) encode(value T, template amqp.Publishing) (amqp.Publishing, error) {
+	body, err := p.codec.Marshal(value)
+	if err != nil {
+		return template, err
+	}
+
+	template.Body = body
+	template.ContentType = p.codec.ContentType()
+	template.Type = p.typeName
+
+	return template, nil
+}
+
+// Publish serializes and publishes the value using the internal [PublisherOptions].
+func (p *TypedPublisher[T]) Publish(value T) error {
+	return p.PublishMessage(value, amqp.Publishing{})
+}
+
+// PublishMessage is like Publish but takes the other message properties
+// (headers, persistence, correlation etc.) from the template.
+func (p *TypedPublisher[T]) PublishMessage(value T, template amqp.Publishing) error {
+	msg, err := p.encode(value, template)
+	if err != nil {
+		return err
+	}
+	return p.publisher.Publish(msg)
+}
+
+// PublishDeferredConfirm serializes and publishes the value, returning its confirmation.
+func (p *TypedPublisher[T]) PublishDeferredConfirm(value T) (*DeferredConfirmation, error) {
+	return p.PublishMessageDeferredConfirm(value, amqp.Publishing{})
+}
+
+// PublishMessageDeferredConfirm is like PublishDeferredConfirm but takes the other
+// message properties from the template.
+func (p *TypedPublisher[T]) PublishMessageDeferredConfirm(value T, template amqp.Publishing) (*DeferredConfirmation, error) {
+	msg, err := p.encode(value, template)
+	if err != nil {
+		return nil, err
+	}
+	return p.publisher.PublishDeferredConfirm(msg)
+}
+
+// PublishWithOptions serializes and publishes the value using the passed options.
+func (p *TypedPublisher[T]) PublishWithOptions(opt PublisherOptions, value T, template amqp.Publishing) error {
+	msg, err := p.encode(value, template)
+	if err != nil {
+		return err
+	}
+	return p.publisher.PublishWithOptions(opt, msg)
+}
+
+// Close shuts down cleanly the publisher channel.
+func (p *TypedPublisher[T]) Close() error {
+	return p.publisher.Close()
+}